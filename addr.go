@@ -0,0 +1,544 @@
+package ipx
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Addr is an IP address stored as a value
+// Unlike IP, Addr does not wrap a slice, so it is comparable,
+// can be used as a map key and its methods do not allocate
+// The zero Addr is not a valid IP address
+type Addr struct {
+	u    Uint128     // address as an integer, IPv4 addresses use the lowest 32 bits
+	z    *addrFamily // address family, nil for the zero Addr
+	zone string      // IPv6 zone
+}
+
+// addrFamily is an address family handle of Addr
+type addrFamily struct {
+	bits int
+}
+
+// Address family handles of Addr
+var (
+	z4 = &addrFamily{IPv4len * 8}
+	z6 = &addrFamily{IPv6len * 8}
+)
+
+// AddrFrom4 returns the IPv4 address given by the bytes in b
+func AddrFrom4(b [4]byte) Addr {
+	return Addr{u: NewUint128(uint64(binary.BigEndian.Uint32(b[:]))), z: z4}
+}
+
+// AddrFrom16 returns the IPv6 address given by the bytes in b
+// IPv4-mapped IPv6 addresses are kept as IPv6 addresses, use Unmap
+// to convert them to IPv4 addresses
+func AddrFrom16(b [16]byte) Addr {
+	return Addr{u: uint128FromBytes(b[:]), z: z6}
+}
+
+// AddrFromUint128 returns the IPv4 address held by the lowest 32 bits
+// of u if v4 is true, or the IPv6 address held by u otherwise
+func AddrFromUint128(u Uint128, v4 bool) Addr {
+	if v4 {
+		return Addr{u: NewUint128(u.Lo & 0xffffffff), z: z4}
+	}
+	return Addr{u: u, z: z6}
+}
+
+// AddrFromIP returns the Addr of ip
+// IPv4 addresses in 16-byte form are converted to IPv4 addresses
+// It returns false if ip is not a valid IP address
+func AddrFromIP(ip IP) (Addr, bool) {
+	return AddrFromNetIP(ip.IP)
+}
+
+// AddrFromNetIP returns the Addr of ip exactly as AddrFromIP does
+func AddrFromNetIP(ip net.IP) (Addr, bool) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return Addr{u: NewUint128(uint64(binary.BigEndian.Uint32(ip4))), z: z4}, true
+	}
+	if len(ip) == IPv6len {
+		return Addr{u: uint128FromBytes(ip), z: z6}, true
+	}
+	return Addr{}, false
+}
+
+// ParseAddr parses s as an IP address like "192.0.2.1", "2001:db8::68"
+// or "fe80::1%eth0"
+// IPv4-mapped IPv6 addresses like "::ffff:192.0.2.1" are parsed as
+// IPv4 addresses as ParseIP does
+// It returns ErrInvalidIP if s is not a valid IP address
+func ParseAddr(s string) (Addr, error) {
+	zone := ""
+	if i := strings.IndexByte(s, '%'); i >= 0 {
+		s, zone = s[:i], s[i+1:]
+		if zone == "" {
+			return Addr{}, ErrInvalidIP
+		}
+	}
+
+	a, ok := AddrFromNetIP(net.ParseIP(s))
+	if !ok || zone != "" && a.Is4() {
+		return Addr{}, ErrInvalidIP
+	}
+	return a.WithZone(zone), nil
+}
+
+// MustParseAddr parses s as an IP address
+// if an error ocurred, it throws a panic
+func MustParseAddr(s string) Addr {
+	a, err := ParseAddr(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// IsValid reports whether a is a valid IP address
+func (a Addr) IsValid() bool {
+	return a.z != nil
+}
+
+// Is4 reports whether a is an IPv4 address
+func (a Addr) Is4() bool {
+	return a.z == z4
+}
+
+// Is6 reports whether a is an IPv6 address
+func (a Addr) Is6() bool {
+	return a.z == z6
+}
+
+// Is4In6 reports whether a is an IPv4-mapped IPv6 address
+func (a Addr) Is4In6() bool {
+	return a.Is6() && a.u.Hi == 0 && a.u.Lo>>32 == 0xffff
+}
+
+// Unmap returns a with IPv4-mapped IPv6 addresses converted
+// to IPv4 addresses
+func (a Addr) Unmap() Addr {
+	if a.Is4In6() {
+		return AddrFromUint128(a.u, true)
+	}
+	return a
+}
+
+// Zone returns the IPv6 zone of a, or an empty string if there is none
+func (a Addr) Zone() string {
+	return a.zone
+}
+
+// WithZone returns a with the IPv6 zone zone
+// An empty zone removes the zone
+// IPv4 addresses have no zone, so it returns them unchanged
+func (a Addr) WithZone(zone string) Addr {
+	if !a.Is6() {
+		return a
+	}
+	a.zone = zone
+	return a
+}
+
+// BitLen returns 32 for IPv4, 128 for IPv6 and 0 for the zero Addr
+func (a Addr) BitLen() int {
+	if a.z == nil {
+		return 0
+	}
+	return a.z.bits
+}
+
+// Uint128 returns the integer representation of a
+func (a Addr) Uint128() Uint128 {
+	return a.u
+}
+
+// As4 returns the 4-byte representation of an IPv4 address
+// It returns zeros for IPv6 addresses
+func (a Addr) As4() [4]byte {
+	var b [4]byte
+	if a.Is4() {
+		binary.BigEndian.PutUint32(b[:], uint32(a.u.Lo))
+	}
+	return b
+}
+
+// As16 returns the 16-byte representation of a
+// IPv4 addresses are returned in IPv4-mapped IPv6 form
+func (a Addr) As16() [16]byte {
+	var b [16]byte
+	if a.Is4() {
+		b[10], b[11] = 0xff, 0xff
+		binary.BigEndian.PutUint32(b[12:], uint32(a.u.Lo))
+		return b
+	}
+	a.u.putBytes(b[:])
+	return b
+}
+
+// IP returns a as an IP without its zone
+// It returns the zero IP for the zero Addr
+func (a Addr) IP() IP {
+	return IP{a.NetIP()}
+}
+
+// NetIP returns a as a net.IP without its zone
+// IPv4 addresses are returned in 4-byte form
+// It returns nil for the zero Addr
+func (a Addr) NetIP() net.IP {
+	switch {
+	case a.z == nil:
+		return nil
+	case a.z == z4:
+		b := a.As4()
+		return net.IP(b[:])
+	}
+	b := a.As16()
+	return net.IP(b[:])
+}
+
+// IPAddr returns a as an IPAddr with its zone
+func (a Addr) IPAddr() *IPAddr {
+	return &IPAddr{IP: a.IP(), Zone: a.Zone()}
+}
+
+// String returns the textual representation of a like "192.0.2.1"
+// or "fe80::1%eth0"
+// It returns "invalid IP" for the zero Addr
+func (a Addr) String() string {
+	switch {
+	case a.z == nil:
+		return "invalid IP"
+	case a.zone == "":
+		return a.NetIP().String()
+	}
+	return a.NetIP().String() + "%" + a.zone
+}
+
+// Compare returns -1, 0 or +1 comparing a and x
+// The zero Addr comes first, then IPv4 and then IPv6 addresses
+// IPv6 addresses with the same value are ordered by their zones
+func (a Addr) Compare(x Addr) int {
+	if f, xf := a.BitLen(), x.BitLen(); f != xf {
+		if f < xf {
+			return -1
+		}
+		return 1
+	}
+	if c := a.u.Cmp(x.u); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Zone(), x.Zone())
+}
+
+// Less reports whether a comes before x
+func (a Addr) Less(x Addr) bool {
+	return a.Compare(x) < 0
+}
+
+// Next returns the next address
+// Addresses wrap around within the address family as GetNext does
+func (a Addr) Next() Addr {
+	return a.NextN(uint128One)
+}
+
+// NextN returns n'th next address
+func (a Addr) NextN(n Uint128) Addr {
+	if a.z == nil {
+		return a
+	}
+	a.u, _ = a.u.Add(n)
+	if a.z == z4 {
+		a.u.Hi, a.u.Lo = 0, a.u.Lo&0xffffffff
+	}
+	return a
+}
+
+// Prev returns the previous address
+// Addresses wrap around within the address family as GetPrevious does
+func (a Addr) Prev() Addr {
+	return a.PrevN(uint128One)
+}
+
+// PrevN returns n'th previous address
+func (a Addr) PrevN(n Uint128) Addr {
+	if a.z == nil {
+		return a
+	}
+	a.u, _ = a.u.Sub(n)
+	if a.z == z4 {
+		a.u.Hi, a.u.Lo = 0, a.u.Lo&0xffffffff
+	}
+	return a
+}
+
+// Prefix returns the network of a with given prefix length
+// It returns ErrInvalidPrefixLen if bits is out of range
+func (a Addr) Prefix(bits int) (Prefix, error) {
+	if !a.IsValid() {
+		return Prefix{}, ErrInvalidIP
+	}
+	if bits < 0 || bits > a.BitLen() {
+		return Prefix{}, ErrInvalidPrefixLen
+	}
+	return PrefixFrom(a, bits), nil
+}
+
+// hostMask returns the host bits of a network
+// of given address family and prefix length
+func hostMask(v4 bool, bits int) Uint128 {
+	return uint128Max.Rsh(uint(128 - addressBits(v4) + bits))
+}
+
+// Prefix is an IP network stored as a value
+// It is the allocation free counterpart of IPNet
+// The zero Prefix is not a valid network
+type Prefix struct {
+	addr Addr // network number without zone
+	bits int  // prefix length
+}
+
+// PrefixFrom returns the network of a with given prefix length
+// The host bits and the zone of a are cleared
+// It returns the zero Prefix if a is not valid or bits is out of range
+func PrefixFrom(a Addr, bits int) Prefix {
+	if !a.IsValid() || bits < 0 || bits > a.BitLen() {
+		return Prefix{}
+	}
+	a = a.WithZone("")
+	a.u = a.u.And(hostMask(a.Is4(), bits).Not())
+	return Prefix{a, bits}
+}
+
+// PrefixFromIPNet returns the Prefix of n
+// It returns false if n is not a valid network in CIDR notation
+func PrefixFromIPNet(n *IPNet) (Prefix, bool) {
+	if n == nil {
+		return Prefix{}, false
+	}
+	nn, m := networkNumberAndMask(n)
+	if nn == nil || m == nil {
+		return Prefix{}, false
+	}
+	ones := simpleMaskLength(m)
+	a, ok := AddrFromNetIP(nn)
+	if !ok || ones == -1 {
+		return Prefix{}, false
+	}
+	return PrefixFrom(a, ones), true
+}
+
+// ParsePrefix parses s as a CIDR notation like "192.0.2.0/24"
+// or "2001:db8::/32"
+// The host bits of the address are cleared as ParseCIDR does
+func ParsePrefix(s string) (Prefix, error) {
+	i := strings.LastIndexByte(s, '/')
+	if i < 0 {
+		return Prefix{}, &ParseError{Type: "CIDR address", Text: s}
+	}
+
+	a, err := ParseAddr(s[:i])
+	if err != nil || a.Zone() != "" {
+		return Prefix{}, &ParseError{Type: "CIDR address", Text: s}
+	}
+	bits, err := strconv.Atoi(s[i+1:])
+	if err != nil || bits < 0 || bits > a.BitLen() || s[i+1] == '+' || s[i+1] == '-' {
+		return Prefix{}, &ParseError{Type: "CIDR address", Text: s}
+	}
+	return PrefixFrom(a, bits), nil
+}
+
+// MustParsePrefix parses s as a CIDR notation
+// if an error ocurred, it throws a panic
+func MustParsePrefix(s string) Prefix {
+	p, err := ParsePrefix(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// IsValid reports whether p is a valid network
+func (p Prefix) IsValid() bool {
+	return p.addr.IsValid()
+}
+
+// Addr returns the network number of p
+func (p Prefix) Addr() Addr {
+	return p.addr
+}
+
+// Bits returns the prefix length of p
+// It returns -1 for the zero Prefix
+func (p Prefix) Bits() int {
+	if !p.IsValid() {
+		return -1
+	}
+	return p.bits
+}
+
+// LastAddr returns the last address in p
+func (p Prefix) LastAddr() Addr {
+	a := p.addr
+	if a.IsValid() {
+		a.u = a.u.Or(hostMask(a.Is4(), p.bits))
+	}
+	return a
+}
+
+// Contains reports whether the network p includes a
+// The zone of a is ignored
+func (p Prefix) Contains(a Addr) bool {
+	if !p.IsValid() || a.Is4() != p.addr.Is4() || !a.IsValid() {
+		return false
+	}
+	return a.u.And(hostMask(a.Is4(), p.bits).Not()) == p.addr.u
+}
+
+// Overlaps reports whether p and x have any address in common
+func (p Prefix) Overlaps(x Prefix) bool {
+	if !p.IsValid() || !x.IsValid() || p.addr.Is4() != x.addr.Is4() {
+		return false
+	}
+	if x.bits < p.bits {
+		return x.Contains(p.addr)
+	}
+	return p.Contains(x.addr)
+}
+
+// Range returns the AddrRange between the first and the last
+// address in p
+func (p Prefix) Range() AddrRange {
+	if !p.IsValid() {
+		return AddrRange{}
+	}
+	return AddrRange{p.addr, p.LastAddr()}
+}
+
+// IPNet returns p as an IPNet
+// It returns nil for the zero Prefix
+func (p Prefix) IPNet() *IPNet {
+	if !p.IsValid() {
+		return nil
+	}
+	return newIPNet(p.addr.u, p.bits, p.addr.Is4())
+}
+
+// String returns the CIDR notation of p like "192.0.2.0/24"
+// It returns "invalid Prefix" for the zero Prefix
+func (p Prefix) String() string {
+	if !p.IsValid() {
+		return "invalid Prefix"
+	}
+	return p.addr.String() + "/" + strconv.Itoa(p.bits)
+}
+
+// AddrRange is an inclusive range of IP addresses stored as a value
+// It is the allocation free counterpart of IPRange
+// The zero AddrRange is not a valid range
+type AddrRange struct {
+	first Addr
+	last  Addr
+}
+
+// AddrRangeFrom returns the AddrRange between x and y
+// Both boundaries are included and order is not important
+// The zones of x and y are cleared
+// It returns the zero AddrRange if x and y are not valid addresses
+// in the same address family
+func AddrRangeFrom(x, y Addr) AddrRange {
+	if !x.IsValid() || !y.IsValid() || x.Is4() != y.Is4() {
+		return AddrRange{}
+	}
+	x, y = x.WithZone(""), y.WithZone("")
+	if y.Less(x) {
+		x, y = y, x
+	}
+	return AddrRange{x, y}
+}
+
+// AddrRangeFromIPRange returns the AddrRange of r
+// It returns false if r is empty or its boundaries are not
+// in the same address family
+func AddrRangeFromIPRange(r *IPRange) (AddrRange, bool) {
+	if r == nil {
+		return AddrRange{}, false
+	}
+	first, last, v4, ok := r.bounds()
+	if !ok {
+		return AddrRange{}, false
+	}
+	return AddrRange{AddrFromUint128(first, v4), AddrFromUint128(last, v4)}, true
+}
+
+// IsValid reports whether r is a valid range
+func (r AddrRange) IsValid() bool {
+	return r.first.IsValid()
+}
+
+// First returns the first address in r
+func (r AddrRange) First() Addr {
+	return r.first
+}
+
+// Last returns the last address in r
+func (r AddrRange) Last() Addr {
+	return r.last
+}
+
+// Contains reports whether r includes a
+// The zone of a is ignored
+func (r AddrRange) Contains(a Addr) bool {
+	if !r.IsValid() || a.Is4() != r.first.Is4() || !a.IsValid() {
+		return false
+	}
+	return a.u.Cmp(r.first.u) >= 0 && a.u.Cmp(r.last.u) <= 0
+}
+
+// Overlaps reports whether r and x have any address in common
+func (r AddrRange) Overlaps(x AddrRange) bool {
+	if !r.IsValid() || !x.IsValid() || r.first.Is4() != x.first.Is4() {
+		return false
+	}
+	return r.first.u.Cmp(x.last.u) <= 0 && x.first.u.Cmp(r.last.u) <= 0
+}
+
+// Prefixes returns the minimal list of CIDR prefixes covering r
+func (r AddrRange) Prefixes() []Prefix {
+	return r.AppendPrefixes(nil)
+}
+
+// AppendPrefixes appends the minimal list of CIDR prefixes covering r
+// to dst and returns the extended slice
+// It does not allocate if dst has enough capacity
+func (r AddrRange) AppendPrefixes(dst []Prefix) []Prefix {
+	if !r.IsValid() {
+		return dst
+	}
+	v4 := r.first.Is4()
+	ipSpan{r.first.u, r.last.u}.walkPrefixes(v4, func(network Uint128, ones int) {
+		dst = append(dst, Prefix{AddrFromUint128(network, v4), ones})
+	})
+	return dst
+}
+
+// IPRange returns r as an inclusive IPRange
+// It returns nil for the zero AddrRange
+func (r AddrRange) IPRange() *IPRange {
+	if !r.IsValid() {
+		return nil
+	}
+	return NewIPRangeInclusive(r.first.IP(), r.last.IP())
+}
+
+// String returns r like "192.0.2.1-192.0.2.9"
+// It returns "invalid AddrRange" for the zero AddrRange
+func (r AddrRange) String() string {
+	if !r.IsValid() {
+		return "invalid AddrRange"
+	}
+	return r.first.String() + "-" + r.last.String()
+}
@@ -0,0 +1,337 @@
+package ipx
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+var parseAddrTests = []struct {
+	in   string
+	out  string
+	v4   bool
+	zone string
+	err  error
+}{
+	{"192.0.2.1", "192.0.2.1", true, "", nil},
+	{"::ffff:192.0.2.1", "192.0.2.1", true, "", nil},
+	{"2001:db8::68", "2001:db8::68", false, "", nil},
+	{"fe80::1%eth0", "fe80::1%eth0", false, "eth0", nil},
+	{"fe80::1%", "invalid IP", false, "", ErrInvalidIP},
+	{"192.0.2.1%eth0", "invalid IP", false, "", ErrInvalidIP},
+	{"192.0.2.256", "invalid IP", false, "", ErrInvalidIP},
+	{"", "invalid IP", false, "", ErrInvalidIP},
+}
+
+func TestParseAddr(t *testing.T) {
+	for _, tt := range parseAddrTests {
+		out, err := ParseAddr(tt.in)
+		if err != tt.err || out.String() != tt.out || out.Is4() != tt.v4 || out.Zone() != tt.zone {
+			t.Errorf("ParseAddr(%q) = %v, %v, want %v, %v", tt.in, out, err, tt.out, tt.err)
+		}
+	}
+}
+
+func TestAddrComparable(t *testing.T) {
+	m := map[Addr]int{}
+	m[MustParseAddr("fe80::1%eth0")]++
+	m[MustParseAddr("fe80::1%eth0")]++
+	m[MustParseAddr("fe80::1%eth1")]++
+	m[MustParseAddr("fe80::1")]++
+	m[MustParseAddr("10.0.0.1")]++
+	m[MustParseAddr("::ffff:10.0.0.1")]++
+	if len(m) != 4 || m[MustParseAddr("fe80::1%eth0")] != 2 || m[MustParseAddr("10.0.0.1")] != 2 {
+		t.Errorf("map[Addr] = %v, want 4 keys", m)
+	}
+
+	if a := AddrFrom16(MustParseAddr("10.0.0.1").As16()); a.Is4() || a.Unmap() != MustParseAddr("10.0.0.1") {
+		t.Errorf("AddrFrom16(10.0.0.1).Unmap() = %v, want 10.0.0.1", a.Unmap())
+	}
+}
+
+func TestAddrZone(t *testing.T) {
+	// Addrs with equal zones are equal, however many zones there are
+	seen := map[Addr]bool{}
+	for i := 0; i < 2000; i++ {
+		seen[MustParseAddr("fe80::1%eth"+strconv.Itoa(i))] = true
+	}
+	for i := 0; i < 2000; i++ {
+		zone := "eth" + strconv.Itoa(i)
+		a, b := MustParseAddr("fe80::1%"+zone), MustParseAddr("fe80::1").WithZone(zone)
+		if a != b || !seen[a] || a.Zone() != zone {
+			t.Fatalf("Addr %v != %v", a, b)
+		}
+	}
+	if len(seen) != 2000 {
+		t.Errorf("len(map[Addr]) = %d, want 2000", len(seen))
+	}
+	if a := MustParseAddr("fe80::1%eth0").WithZone(""); a != MustParseAddr("fe80::1") || a.String() != "fe80::1" {
+		t.Errorf("Addr.WithZone(\"\") = %v, want fe80::1", a)
+	}
+}
+
+var addrConversionTests = []IP{
+	IPv4(192, 0, 2, 1),
+	FromInt(0xc0000201),
+	MustParseIP("2001:db8::68"),
+	MustParseIP("::"),
+}
+
+func TestAddrConversion(t *testing.T) {
+	for _, ip := range addrConversionTests {
+		a, ok := AddrFromIP(ip)
+		if !ok || !a.IP().Equal(ip) || a.IP().IsV4() != ip.IsV4() {
+			t.Errorf("AddrFromIP(%v).IP() = %v, %v, want %v", ip, a.IP(), ok, ip)
+		}
+		u, _ := ip.toUint128()
+		if a.Uint128() != u {
+			t.Errorf("AddrFromIP(%v).Uint128() = %v, want %v", ip, a.Uint128(), u)
+		}
+	}
+
+	if a, ok := AddrFromIP(IP{}); ok || a.IsValid() || a.IP().IP != nil {
+		t.Errorf("AddrFromIP(nil) = %v, %v, want invalid", a, ok)
+	}
+}
+
+var addrNextTests = []struct {
+	in   string
+	next string
+	prev string
+}{
+	{"10.0.0.255", "10.0.1.0", "10.0.0.254"},
+	{"255.255.255.255", "0.0.0.0", "255.255.255.254"},
+	{"0.0.0.0", "0.0.0.1", "255.255.255.255"},
+	{"2001:db8::ffff:ffff:ffff:ffff", "2001:db8:0:1::", "2001:db8::ffff:ffff:ffff:fffe"},
+	{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe"},
+	{"fe80::1%eth0", "fe80::2%eth0", "fe80::%eth0"},
+}
+
+func TestAddrNext(t *testing.T) {
+	for _, tt := range addrNextTests {
+		a := MustParseAddr(tt.in)
+		if out := a.Next(); out != MustParseAddr(tt.next) {
+			t.Errorf("Addr.Next(%v) = %v, want %v", a, out, tt.next)
+		}
+		if out := a.Prev(); out != MustParseAddr(tt.prev) {
+			t.Errorf("Addr.Prev(%v) = %v, want %v", a, out, tt.prev)
+		}
+		if ip := a.IP(); a.Next().IP().String() != ip.GetNext().String() {
+			t.Errorf("Addr.Next(%v) = %v, want %v as IP.GetNext", a, a.Next(), ip.GetNext())
+		}
+	}
+}
+
+func TestAddrCompare(t *testing.T) {
+	want := []Addr{
+		{},
+		MustParseAddr("10.0.0.1"),
+		MustParseAddr("10.0.0.2"),
+		MustParseAddr("::1"),
+		MustParseAddr("fe80::1"),
+		MustParseAddr("fe80::1%eth0"),
+	}
+	for i := range want {
+		for j := range want {
+			if out := want[i].Less(want[j]); out != (i < j) {
+				t.Errorf("Addr.Less(%v, %v) = %v, want %v", want[i], want[j], out, i < j)
+			}
+		}
+	}
+}
+
+var parsePrefixTests = []struct {
+	in    string
+	out   string
+	last  string
+	valid bool
+}{
+	{"192.0.2.1/24", "192.0.2.0/24", "192.0.2.255", true},
+	{"10.0.0.0/32", "10.0.0.0/32", "10.0.0.0", true},
+	{"0.0.0.0/0", "0.0.0.0/0", "255.255.255.255", true},
+	{"2001:db8::1/32", "2001:db8::/32", "2001:db8:ffff:ffff:ffff:ffff:ffff:ffff", true},
+	{"::/0", "::/0", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", true},
+	{"10.0.0.0/33", "invalid Prefix", "invalid IP", false},
+	{"10.0.0.0/+8", "invalid Prefix", "invalid IP", false},
+	{"10.0.0.0/", "invalid Prefix", "invalid IP", false},
+	{"fe80::%eth0/64", "invalid Prefix", "invalid IP", false},
+	{"10.0.0.0", "invalid Prefix", "invalid IP", false},
+}
+
+func TestParsePrefix(t *testing.T) {
+	for _, tt := range parsePrefixTests {
+		p, err := ParsePrefix(tt.in)
+		if (err == nil) != tt.valid || p.String() != tt.out || p.LastAddr().String() != tt.last {
+			t.Errorf("ParsePrefix(%q) = %v, %v, want %v", tt.in, p, err, tt.out)
+		}
+		if !tt.valid {
+			continue
+		}
+
+		n := MustParseCIDR(tt.in)
+		if p.IPNet().String() != n.String() {
+			t.Errorf("Prefix.IPNet(%v) = %v, want %v", p, p.IPNet(), n)
+		}
+		if out, ok := PrefixFromIPNet(n); !ok || out != p {
+			t.Errorf("PrefixFromIPNet(%v) = %v, %v, want %v", n, out, ok, p)
+		}
+	}
+}
+
+var prefixContainsTests = []struct {
+	prefix string
+	addr   string
+	out    bool
+}{
+	{"10.0.0.0/8", "10.255.255.255", true},
+	{"10.0.0.0/8", "11.0.0.0", false},
+	{"0.0.0.0/0", "2001:db8::1", false},
+	{"2001:db8::/32", "2001:db8:ffff::1", true},
+	{"2001:db8::/32", "2001:db9::", false},
+	{"fe80::/64", "fe80::1%eth0", true},
+	{"::/0", "10.0.0.1", false},
+}
+
+func TestPrefixContains(t *testing.T) {
+	for _, tt := range prefixContainsTests {
+		p, a := MustParsePrefix(tt.prefix), MustParseAddr(tt.addr)
+		if out := p.Contains(a); out != tt.out {
+			t.Errorf("Prefix.Contains(%v, %v) = %v, want %v", p, a, out, tt.out)
+		}
+		if out := p.Range().Contains(a); out != tt.out {
+			t.Errorf("AddrRange.Contains(%v, %v) = %v, want %v", p.Range(), a, out, tt.out)
+		}
+		if out := MustParseCIDR(tt.prefix).Contains(a.IP()); out != tt.out && a.Zone() == "" {
+			t.Errorf("IPNet.Contains(%v, %v) = %v, want %v", p, a, out, tt.out)
+		}
+	}
+
+	if !MustParsePrefix("10.0.0.0/8").Overlaps(MustParsePrefix("10.1.0.0/16")) ||
+		MustParsePrefix("10.0.0.0/16").Overlaps(MustParsePrefix("10.1.0.0/16")) {
+		t.Errorf("Prefix.Overlaps(10.0.0.0/8, 10.1.0.0/16) is wrong")
+	}
+}
+
+var addrRangePrefixesTests = []struct {
+	x, y string
+	out  []string
+}{
+	{"10.0.0.1", "10.0.0.6", []string{"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6/32"}},
+	{"10.0.0.255", "10.0.0.0", []string{"10.0.0.0/24"}},
+	{"0.0.0.0", "255.255.255.255", []string{"0.0.0.0/0"}},
+	{"2001:db8::", "2001:db8::1:0", []string{"2001:db8::/112", "2001:db8::1:0/128"}},
+	{"10.0.0.1", "2001:db8::", nil},
+}
+
+func TestAddrRangePrefixes(t *testing.T) {
+	for _, tt := range addrRangePrefixesTests {
+		r := AddrRangeFrom(MustParseAddr(tt.x), MustParseAddr(tt.y))
+		var out []string
+		for _, p := range r.Prefixes() {
+			out = append(out, p.String())
+		}
+		if !reflect.DeepEqual(out, tt.out) {
+			t.Errorf("AddrRange.Prefixes(%v) = %v, want %v", r, out, tt.out)
+		}
+
+		if !r.IsValid() {
+			continue
+		}
+		ipRange := r.IPRange()
+		if !reflect.DeepEqual(prefixStrings(ipRange.Prefixes()), tt.out) {
+			t.Errorf("IPRange.Prefixes(%v) = %v, want %v", ipRange, prefixStrings(ipRange.Prefixes()), tt.out)
+		}
+		if out, ok := AddrRangeFromIPRange(ipRange); !ok || out != r {
+			t.Errorf("AddrRangeFromIPRange(%v) = %v, %v, want %v", ipRange, out, ok, r)
+		}
+	}
+}
+
+func TestAddrAllocs(t *testing.T) {
+	a := MustParseAddr("2001:db8::1")
+	p := MustParsePrefix("2001:db8::/32")
+	r := p.Range()
+	dst := make([]Prefix, 0, 16)
+	it := MustParseCIDR("10.0.0.0/8").Iter(IterOptions{})
+
+	allocs := testing.AllocsPerRun(100, func() {
+		a = a.Next()
+		_ = p.Contains(a)
+		_ = p.LastAddr()
+		_ = r.Contains(a)
+		_ = PrefixFrom(a, 64)
+		dst = AddrRangeFrom(a, a.NextN(NewUint128(1000))).AppendPrefixes(dst[:0])
+		it.Next()
+		_ = it.Addr()
+	})
+	if allocs != 0 {
+		t.Errorf("Addr operations allocate %v times, want 0", allocs)
+	}
+}
+
+func BenchmarkIPGetNext(b *testing.B) {
+	ip := IPv4(10, 0, 0, 0)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ip = ip.GetNext()
+	}
+}
+
+func BenchmarkAddrNext(b *testing.B) {
+	a := MustParseAddr("10.0.0.0")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		a = a.Next()
+	}
+}
+
+func BenchmarkIPNetContains(b *testing.B) {
+	n, ip := MustParseCIDR("2001:db8::/32"), MustParseIP("2001:db8::1")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		n.Contains(ip)
+	}
+}
+
+func BenchmarkPrefixContains(b *testing.B) {
+	p, a := MustParsePrefix("2001:db8::/32"), MustParseAddr("2001:db8::1")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p.Contains(a)
+	}
+}
+
+func BenchmarkIPRangeContains(b *testing.B) {
+	r, ip := MustParseIPRangeInclusive("10.0.0.0", "10.255.255.255"), IPv4(10, 1, 2, 3)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.Contains(ip)
+	}
+}
+
+func BenchmarkAddrRangeContains(b *testing.B) {
+	r := AddrRangeFrom(MustParseAddr("10.0.0.0"), MustParseAddr("10.255.255.255"))
+	a := MustParseAddr("10.1.2.3")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.Contains(a)
+	}
+}
+
+func BenchmarkIPNetIter(b *testing.B) {
+	it := MustParseCIDR("0.0.0.0/0").Iter(IterOptions{})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		it.Next()
+		_ = it.IP()
+	}
+}
+
+func BenchmarkIPNetIterAddr(b *testing.B) {
+	it := MustParseCIDR("0.0.0.0/0").Iter(IterOptions{})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		it.Next()
+		_ = it.Addr()
+	}
+}
@@ -0,0 +1,180 @@
+package ipx
+
+import "math/big"
+
+// SummarizeOptions configures the lossy summarization of SummarizeIPNets
+type SummarizeOptions struct {
+	// MaxPrefixes is the maximum number of prefixes in the summary
+	// Zero means no limit, so only lossless collapsing is done
+	MaxPrefixes int
+
+	// MinPrefixLenV4 and MinPrefixLenV6 are the shortest prefix lengths
+	// of the supernets the merges for MaxPrefixes create, e.g. 16 means
+	// no merge creates a prefix covering more than a /16 network
+	// They only limit those merges, so they have no effect without
+	// MaxPrefixes, and prefixes in nets that are shorter are kept
+	// Zero means no minimum
+	MinPrefixLenV4 int
+	MinPrefixLenV6 int
+}
+
+// CollapseIPNets returns the smallest sorted list of networks
+// that covers exactly the same addresses as nets
+// Adjacent and overlapping networks are merged into their supernets
+// It returns ErrInvalidIPSetElement if a network has a non-canonical mask
+func CollapseIPNets(nets []*IPNet) ([]*IPNet, error) {
+	s, err := ipSetFromIPNets(nets)
+	if err != nil {
+		return nil, err
+	}
+	return s.Prefixes(), nil
+}
+
+// SummarizeIPNets collapses nets exactly as CollapseIPNets does, then
+// merges neighbouring prefixes into their common supernets until the
+// summary has at most opts.MaxPrefixes prefixes
+// Merging is lossy, the supernets cover addresses that are not in nets
+// Each step picks the merge that covers the fewest extra addresses
+// It returns the summary and the number of extra addresses covered by it,
+// which is zero unless merges are needed for opts.MaxPrefixes
+// The summary can have more than opts.MaxPrefixes prefixes if
+// opts.MinPrefixLenV4 or opts.MinPrefixLenV6 does not allow further merging
+func SummarizeIPNets(nets []*IPNet, opts SummarizeOptions) ([]*IPNet, *big.Int, error) {
+	s, err := ipSetFromIPNets(nets)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var prefixes []summaryPrefix
+	for _, span := range s.v4 {
+		prefixes = appendSummaryPrefixes(prefixes, span, true)
+	}
+	for _, span := range s.v6 {
+		prefixes = appendSummaryPrefixes(prefixes, span, false)
+	}
+
+	extra := big.NewInt(0)
+	for opts.MaxPrefixes > 0 && len(prefixes) > opts.MaxPrefixes {
+		best, ok := bestSummaryMerge(prefixes, opts)
+		if !ok {
+			break
+		}
+
+		extra.Add(extra, best.cost.BigInt())
+		merged := append(prefixes[:best.lo:best.lo], best.prefix)
+		prefixes = append(merged, prefixes[best.hi+1:]...)
+	}
+
+	summary := make([]*IPNet, 0, len(prefixes))
+	for _, p := range prefixes {
+		summary = append(summary, p.ipNet())
+	}
+	return summary, extra, nil
+}
+
+// summaryPrefix represents a CIDR prefix during summarization
+type summaryPrefix struct {
+	first Uint128
+	last  Uint128
+	v4    bool
+}
+
+// summaryMerge represents merging prefixes lo to hi into prefix
+type summaryMerge struct {
+	lo     int
+	hi     int
+	prefix summaryPrefix
+	cost   Uint128 // number of extra addresses covered
+}
+
+// ipNet returns the prefix as an IPNet
+func (p summaryPrefix) ipNet() *IPNet {
+	diff, _ := p.last.Sub(p.first)
+	return newIPNet(p.first, addressBits(p.v4)-diff.BitLen(), p.v4)
+}
+
+// size returns the number of addresses in the prefix
+// It wraps to zero for ::/0
+func (p summaryPrefix) size() Uint128 {
+	diff, _ := p.last.Sub(p.first)
+	size, _ := diff.Add(uint128One)
+	return size
+}
+
+// appendSummaryPrefixes appends the minimal prefixes covering span to prefixes
+func appendSummaryPrefixes(prefixes []summaryPrefix, span ipSpan, v4 bool) []summaryPrefix {
+	for _, n := range span.prefixes(v4) {
+		network, host, _ := n.uint128()
+		prefixes = append(prefixes, summaryPrefix{network, network.Or(host), v4})
+	}
+	return prefixes
+}
+
+// commonSupernet returns the smallest prefix covering both a and b
+// and its prefix length
+func commonSupernet(a, b summaryPrefix) (summaryPrefix, int) {
+	hostBits := a.first.Or(b.last).And(a.first.And(b.last).Not()).BitLen()
+	host := uint128Max.Rsh(uint(128 - hostBits))
+	return summaryPrefix{a.first.And(host.Not()), a.first.Or(host), a.v4}, addressBits(a.v4) - hostBits
+}
+
+// coveredExtra returns the number of addresses in super
+// which are not in the prefixes covered by it
+func coveredExtra(super summaryPrefix, covered []summaryPrefix) Uint128 {
+	cost, _ := super.last.Sub(super.first)
+	for _, p := range covered {
+		cost, _ = cost.Sub(p.size())
+	}
+	cost, _ = cost.Add(uint128One)
+	return cost
+}
+
+// minPrefixLen returns the minimum prefix length of the address family
+func (opts SummarizeOptions) minPrefixLen(v4 bool) int {
+	if v4 {
+		return opts.MinPrefixLenV4
+	}
+	return opts.MinPrefixLenV6
+}
+
+// bestSummaryMerge returns the merge of two neighbouring prefixes
+// which covers the fewest extra addresses
+// ok is false if no merge is allowed by opts
+func bestSummaryMerge(prefixes []summaryPrefix, opts SummarizeOptions) (best summaryMerge, ok bool) {
+	for i := 0; i+1 < len(prefixes); i++ {
+		a, b := prefixes[i], prefixes[i+1]
+		if a.v4 != b.v4 {
+			continue
+		}
+
+		super, ones := commonSupernet(a, b)
+		if ones < opts.minPrefixLen(a.v4) {
+			continue
+		}
+
+		// find all prefixes covered by the supernet
+		lo, hi := i, i+1
+		for lo > 0 && prefixes[lo-1].v4 == a.v4 && prefixes[lo-1].first.Cmp(super.first) >= 0 {
+			lo--
+		}
+		for hi+1 < len(prefixes) && prefixes[hi+1].v4 == a.v4 && prefixes[hi+1].last.Cmp(super.last) <= 0 {
+			hi++
+		}
+
+		cost := coveredExtra(super, prefixes[lo:hi+1])
+		if !ok || cost.Cmp(best.cost) < 0 || (cost == best.cost && hi-lo > best.hi-best.lo) {
+			best = summaryMerge{lo, hi, super, cost}
+			ok = true
+		}
+	}
+	return best, ok
+}
+
+// ipSetFromIPNets returns the IPSet of nets
+func ipSetFromIPNets(nets []*IPNet) (*IPSet, error) {
+	elems := make([]interface{}, 0, len(nets))
+	for _, n := range nets {
+		elems = append(elems, n)
+	}
+	return NewIPSet(elems...)
+}
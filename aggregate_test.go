@@ -0,0 +1,121 @@
+package ipx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func parseCIDRs(s ...string) []*IPNet {
+	var nets []*IPNet
+	for _, cidr := range s {
+		nets = append(nets, MustParseCIDR(cidr))
+	}
+	return nets
+}
+
+var collapseIPNetsTests = []struct {
+	in  []*IPNet
+	out []string
+}{
+	{
+		parseCIDRs("192.0.2.0/25", "192.0.2.128/25"),
+		[]string{"192.0.2.0/24"},
+	},
+	{
+		parseCIDRs("192.0.2.0/24", "192.0.2.64/26", "192.0.3.0/24", "192.0.4.0/24"),
+		[]string{"192.0.2.0/23", "192.0.4.0/24"},
+	},
+	{
+		parseCIDRs("2001:db8::/33", "10.0.0.0/9", "2001:db8:8000::/33", "10.128.0.0/9"),
+		[]string{"10.0.0.0/8", "2001:db8::/32"},
+	},
+	{
+		parseCIDRs("10.0.0.1/32", "10.0.0.3/32"),
+		[]string{"10.0.0.1/32", "10.0.0.3/32"},
+	},
+	{
+		nil,
+		nil,
+	},
+}
+
+func TestCollapseIPNets(t *testing.T) {
+	for _, tt := range collapseIPNetsTests {
+		out, err := CollapseIPNets(tt.in)
+		if err != nil || !reflect.DeepEqual(prefixStrings(out), tt.out) {
+			t.Errorf("CollapseIPNets(%v) = %v, %v, want %v", tt.in, out, err, tt.out)
+		}
+	}
+}
+
+var summarizeIPNetsTests = []struct {
+	in    []*IPNet
+	opts  SummarizeOptions
+	out   []string
+	extra string
+}{
+	{
+		parseCIDRs("10.0.0.0/24", "10.0.2.0/24", "10.0.3.0/24"),
+		SummarizeOptions{},
+		[]string{"10.0.0.0/24", "10.0.2.0/23"},
+		"0",
+	},
+	{
+		parseCIDRs("10.0.0.0/24", "10.0.2.0/24", "10.0.3.0/24"),
+		SummarizeOptions{MaxPrefixes: 1},
+		[]string{"10.0.0.0/22"},
+		"256",
+	},
+	{
+		parseCIDRs("10.0.0.0/24", "10.0.2.0/24", "10.0.3.0/24", "10.0.16.0/24"),
+		SummarizeOptions{MaxPrefixes: 2},
+		[]string{"10.0.0.0/22", "10.0.16.0/24"},
+		"256",
+	},
+	{
+		parseCIDRs("10.0.0.0/24", "10.1.0.0/24"),
+		SummarizeOptions{MaxPrefixes: 1, MinPrefixLenV4: 16},
+		[]string{"10.0.0.0/24", "10.1.0.0/24"},
+		"0",
+	},
+	{
+		parseCIDRs("10.0.0.0/24", "10.1.0.0/24", "10.1.1.0/24", "2001:db8::/48", "2001:db8:2::/48"),
+		SummarizeOptions{MaxPrefixes: 2, MinPrefixLenV4: 16, MinPrefixLenV6: 32},
+		[]string{"10.0.0.0/24", "10.1.0.0/23", "2001:db8::/46"},
+		"2417851639229258349412352",
+	},
+	{
+		parseCIDRs("10.0.0.0/24", "10.1.0.0/24", "10.1.1.0/24", "2001:db8::/48", "2001:db8:2::/48"),
+		SummarizeOptions{MaxPrefixes: 3, MinPrefixLenV4: 8, MinPrefixLenV6: 32},
+		[]string{"10.0.0.0/15", "2001:db8::/48", "2001:db8:2::/48"},
+		"130304",
+	},
+	{
+		parseCIDRs("10.0.0.0/24", "10.0.2.0/25", "10.1.0.0/24", "10.1.0.128/25", "2001:db8::/48", "2001:db8:2::/48"),
+		SummarizeOptions{MinPrefixLenV4: 16, MinPrefixLenV6: 32},
+		[]string{"10.0.0.0/24", "10.0.2.0/25", "10.1.0.0/24", "2001:db8::/48", "2001:db8:2::/48"},
+		"0",
+	},
+	{
+		parseCIDRs("10.0.0.0/24", "10.0.2.0/25", "10.1.0.0/24", "10.1.0.128/25"),
+		SummarizeOptions{MaxPrefixes: 2, MinPrefixLenV4: 16},
+		[]string{"10.0.0.0/22", "10.1.0.0/24"},
+		"640",
+	},
+}
+
+func TestSummarizeIPNets(t *testing.T) {
+	for _, tt := range summarizeIPNetsTests {
+		out, extra, err := SummarizeIPNets(tt.in, tt.opts)
+		if err != nil || !reflect.DeepEqual(prefixStrings(out), tt.out) || extra.String() != tt.extra {
+			t.Errorf("SummarizeIPNets(%v, %+v) = %v, %v, %v, want %v, %v", tt.in, tt.opts, out, extra, err, tt.out, tt.extra)
+		}
+	}
+}
+
+func TestCollapseIPNetsError(t *testing.T) {
+	in := []*IPNet{{IP: IPv4(192, 168, 1, 0), Mask: IPv4Mask(255, 0, 255, 0)}}
+	if _, err := CollapseIPNets(in); err != ErrInvalidIPSetElement {
+		t.Errorf("CollapseIPNets(%v) = %v, want %v", in, err, ErrInvalidIPSetElement)
+	}
+}
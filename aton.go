@@ -0,0 +1,160 @@
+package ipx
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ParseMode controls how ParseIPMode treats the legacy IPv4 forms
+// that inet_aton accepts
+type ParseMode int
+
+// Parse modes
+const (
+	// ParseStrict accepts only four part dotted decimal IPv4 and IPv6
+	// addresses and returns an *AmbiguousIPError for legacy forms
+	ParseStrict ParseMode = iota
+
+	// ParseLenient accepts legacy forms and interprets them as
+	// inet_aton does
+	ParseLenient
+)
+
+// IPForm is a set of legacy forms used in the textual representation
+// of an IPv4 address
+// The zero IPForm is the canonical form
+type IPForm uint8
+
+// Legacy IPv4 forms
+const (
+	// FormShorthand has less than four parts like "127.1" or "2130706433"
+	FormShorthand IPForm = 1 << iota
+
+	// FormOctal has parts with leading zeros like "010.0.0.1"
+	// which are octal numbers
+	FormOctal
+
+	// FormHex has hexadecimal parts like "0x7f.0.0.1"
+	FormHex
+
+	// FormTrailingText has text after a whitespace like
+	// "127.0.0.1 example.com" which inet_aton ignores
+	FormTrailingText
+)
+
+// String returns the forms in f separated by "+" like "shorthand+hex"
+// It returns "canonical" for the zero IPForm
+func (f IPForm) String() string {
+	if f == 0 {
+		return "canonical"
+	}
+
+	var forms []string
+	for _, form := range []struct {
+		f    IPForm
+		name string
+	}{
+		{FormShorthand, "shorthand"},
+		{FormOctal, "octal"},
+		{FormHex, "hex"},
+		{FormTrailingText, "trailing text"},
+	} {
+		if f&form.f != 0 {
+			forms = append(forms, form.name)
+		}
+	}
+	return strings.Join(forms, "+")
+}
+
+// AmbiguousIPError declares an address in a legacy form
+// that inet_aton interprets differently than ParseIP
+type AmbiguousIPError struct {
+	Text string // input text
+	Form IPForm // legacy forms used in Text
+	IP   IP     // address inet_aton interprets Text as
+}
+
+// Error throws the error
+func (e *AmbiguousIPError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return "ambiguous ip address " + e.Text + ": " + e.Form.String() + " form is interpreted as " + e.IP.String()
+}
+
+// ParseIPMode parses s as an IP address and returns the legacy forms
+// used in s
+// In ParseStrict mode, it returns an *AmbiguousIPError if s is an IPv4
+// address in a legacy form like "0x7f.1", "127.1", "2130706433",
+// "010.0.0.1" or "127.0.0.1 example.com", which C resolvers accept,
+// so a check on the result of ParseIP can be bypassed
+// In ParseLenient mode, it returns the address inet_aton interprets
+// s as and the legacy forms used in s
+// It returns ErrInvalidIP if s is not a valid address in any form
+func ParseIPMode(s string, mode ParseMode) (IP, IPForm, error) {
+	if strings.IndexByte(s, ':') >= 0 {
+		ip, err := ParseIP(s)
+		return ip, 0, err
+	}
+
+	ip, form, ok := parseInetAton(s)
+	switch {
+	case !ok:
+		return IP{}, 0, ErrInvalidIP
+	case form != 0 && mode == ParseStrict:
+		return IP{}, form, &AmbiguousIPError{Text: s, Form: form, IP: ip}
+	}
+	return ip, form, nil
+}
+
+// parseInetAton parses s as an IPv4 address as inet_aton does
+// Each of the one to four parts of s is a decimal, an octal with a
+// leading zero or a hexadecimal number with a leading "0x"
+// The last part fills the remaining bytes of the address
+// Any text after a whitespace is ignored
+func parseInetAton(s string) (IP, IPForm, bool) {
+	var form IPForm
+	if i := strings.IndexAny(s, " \t\n\v\f\r"); i >= 0 {
+		s = s[:i]
+		form |= FormTrailingText
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > IPv4len {
+		return IP{}, 0, false
+	}
+
+	if len(parts) < IPv4len {
+		form |= FormShorthand
+	}
+
+	var val uint64
+	for n, part := range parts {
+		base := 10
+		digits := part
+		switch {
+		case len(part) > 2 && (part[:2] == "0x" || part[:2] == "0X"):
+			base, digits = 16, part[2:]
+			form |= FormHex
+		case len(part) > 1 && part[0] == '0':
+			base, digits = 8, part[1:]
+			form |= FormOctal
+		}
+		if digits == "" || strings.ContainsAny(digits, "+-_") {
+			return IP{}, 0, false
+		}
+
+		// the last part fills the remaining bytes
+		bits := 8
+		if n == len(parts)-1 {
+			bits = 8 * (IPv4len - n)
+		}
+		v, err := strconv.ParseUint(digits, base, bits)
+		if err != nil {
+			return IP{}, 0, false
+		}
+		val = val<<uint(bits) | v
+	}
+
+	return IPv4(byte(val>>24), byte(val>>16), byte(val>>8), byte(val)), form, true
+}
@@ -0,0 +1,86 @@
+package ipx
+
+import "testing"
+
+var parseIPModeTests = []struct {
+	in   string
+	out  IP
+	form IPForm
+	ok   bool
+}{
+	{"127.0.0.1", IPv4(127, 0, 0, 1), 0, true},
+	{"2001:db8::1", MustParseIP("2001:db8::1"), 0, true},
+	{"::ffff:127.0.0.1", IPv4(127, 0, 0, 1), 0, true},
+	{"127.1", IPv4(127, 0, 0, 1), FormShorthand, true},
+	{"10.1.258", IPv4(10, 1, 1, 2), FormShorthand, true},
+	{"2130706433", IPv4(127, 0, 0, 1), FormShorthand, true},
+	{"0x7f.1", IPv4(127, 0, 0, 1), FormShorthand | FormHex, true},
+	{"0x7F000001", IPv4(127, 0, 0, 1), FormShorthand | FormHex, true},
+	{"010.0.0.1", IPv4(8, 0, 0, 1), FormOctal, true},
+	{"0177.0.0.01", IPv4(127, 0, 0, 1), FormOctal, true},
+	{"0.0.0.0", IPv4(0, 0, 0, 0), 0, true},
+	{"00.0.0.0", IPv4(0, 0, 0, 0), FormOctal, true},
+	{"0xc0.0250.2.1", IPv4(192, 168, 2, 1), FormHex | FormOctal, true},
+	{"256.0.0.1", IP{}, 0, false},
+	{"1.2.3.4.5", IP{}, 0, false},
+	{"1.2.65536", IP{}, 0, false},
+	{"4294967296", IP{}, 0, false},
+	{"08.0.0.1", IP{}, 0, false},
+	{"0x.0.0.1", IP{}, 0, false},
+	{"1..2.3", IP{}, 0, false},
+	{"+1.2.3.4", IP{}, 0, false},
+	{"1.2.3.4 ", IPv4(1, 2, 3, 4), FormTrailingText, true},
+	{"127.1\tevil.example.com", IPv4(127, 0, 0, 1), FormShorthand | FormTrailingText, true},
+	{" 1.2.3.4", IP{}, 0, false},
+	{"", IP{}, 0, false},
+	{"2001:db8::x", IP{}, 0, false},
+}
+
+func TestParseIPMode(t *testing.T) {
+	for _, tt := range parseIPModeTests {
+		out, form, err := ParseIPMode(tt.in, ParseLenient)
+		if (err == nil) != tt.ok || !out.Equal(tt.out) || form != tt.form {
+			t.Errorf("ParseIPMode(%q, ParseLenient) = %v, %v, %v, want %v, %v", tt.in, out, form, err, tt.out, tt.form)
+		}
+
+		out, form, err = ParseIPMode(tt.in, ParseStrict)
+		switch {
+		case !tt.ok:
+			if err != ErrInvalidIP {
+				t.Errorf("ParseIPMode(%q, ParseStrict) = %v, want %v", tt.in, err, ErrInvalidIP)
+			}
+		case tt.form == 0:
+			if err != nil || !out.Equal(tt.out) {
+				t.Errorf("ParseIPMode(%q, ParseStrict) = %v, %v, want %v", tt.in, out, err, tt.out)
+			}
+		default:
+			ambiguous, ok := err.(*AmbiguousIPError)
+			if !ok || out.IP != nil || form != tt.form || ambiguous.Form != tt.form || !ambiguous.IP.Equal(tt.out) {
+				t.Errorf("ParseIPMode(%q, ParseStrict) = %v, %v, %v, want ambiguous %v", tt.in, out, form, err, tt.out)
+			}
+		}
+	}
+}
+
+var ipFormStringTests = []struct {
+	in  IPForm
+	out string
+}{
+	{0, "canonical"},
+	{FormShorthand, "shorthand"},
+	{FormShorthand | FormHex, "shorthand+hex"},
+	{FormOctal | FormHex, "octal+hex"},
+}
+
+func TestIPFormString(t *testing.T) {
+	for _, tt := range ipFormStringTests {
+		if out := tt.in.String(); out != tt.out {
+			t.Errorf("IPForm.String(%d) = %v, want %v", tt.in, out, tt.out)
+		}
+	}
+
+	err := &AmbiguousIPError{Text: "0x7f.1", Form: FormShorthand | FormHex, IP: IPv4(127, 0, 0, 1)}
+	if want := "ambiguous ip address 0x7f.1: shorthand+hex form is interpreted as 127.0.0.1"; err.Error() != want {
+		t.Errorf("AmbiguousIPError.Error() = %v, want %v", err.Error(), want)
+	}
+}
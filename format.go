@@ -0,0 +1,159 @@
+package ipx
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// FormatOptions configures the binary and hex representations of IP
+type FormatOptions struct {
+	// GroupSize is the number of digits in a group
+	// Zero means no grouping
+	GroupSize int
+
+	// Separator is written between the groups
+	Separator string
+
+	// Prefix writes "0b" before binary and "0x" before hex representations
+	Prefix bool
+
+	// Lowercase writes hex digits in lowercase
+	Lowercase bool
+}
+
+// Common FormatOptions
+var (
+	// DottedBinary formats binary representations per octet like
+	// "11000000.00000000.00000010.00000001"
+	DottedBinary = FormatOptions{GroupSize: 8, Separator: "."}
+
+	// ColonHex formats hex representations per hextet like
+	// "2001:0db8:0000:0000:0000:0000:0000:0001"
+	ColonHex = FormatOptions{GroupSize: 4, Separator: ":", Lowercase: true}
+)
+
+// ToBinaryFormat returns the binary representation of IP formatted by opts
+// If i is not a valid IP address, it returns an empty string
+func (i IP) ToBinaryFormat(opts FormatOptions) string {
+	b := i.bytes()
+	if b == nil {
+		return ""
+	}
+
+	var digits strings.Builder
+	for _, octet := range b {
+		s := strconv.FormatUint(uint64(octet), 2)
+		digits.WriteString(strings.Repeat("0", 8-len(s)) + s)
+	}
+	return formatDigits(digits.String(), "0b", opts)
+}
+
+// ToHexFormat returns the hex representation of IP formatted by opts
+// If i is not a valid IP address, it returns an empty string
+func (i IP) ToHexFormat(opts FormatOptions) string {
+	b := i.bytes()
+	if b == nil {
+		return ""
+	}
+
+	const hexDigits = "0123456789ABCDEF"
+	digits := make([]byte, 0, 2*len(b))
+	for _, octet := range b {
+		digits = append(digits, hexDigits[octet>>4], hexDigits[octet&0x0f])
+	}
+
+	s := string(digits)
+	if opts.Lowercase {
+		s = strings.ToLower(s)
+	}
+	return formatDigits(s, "0x", opts)
+}
+
+// ParseBinary parses s as the binary representation of an IP address
+// like ToBinary and ToBinaryFormat return
+// An optional "0b" prefix and the separators '.', ':', '-', '_' and
+// space between the digits are ignored
+// 32 digits are parsed as IPv4 and 128 digits as IPv6 address
+// It returns ErrInvalidIP if s is not a valid binary representation
+func ParseBinary(s string) (IP, error) {
+	return parseDigits(s, "0b", 1)
+}
+
+// ParseHex parses s as the hex representation of an IP address
+// like ToHex and ToHexFormat return
+// An optional "0x" prefix and the separators '.', ':', '-', '_' and
+// space between the digits are ignored
+// 8 digits are parsed as IPv4 and 32 digits as IPv6 address
+// It returns ErrInvalidIP if s is not a valid hex representation
+func ParseHex(s string) (IP, error) {
+	return parseDigits(s, "0x", 4)
+}
+
+// bytes returns the 4-byte representation of IPv4
+// and the 16-byte representation of IPv6 addresses
+func (i IP) bytes() net.IP {
+	if ip := i.IP.To4(); ip != nil {
+		return ip
+	}
+	if len(i.IP) == IPv6len {
+		return i.IP
+	}
+	return nil
+}
+
+// formatDigits groups digits and adds prefix as configured by opts
+func formatDigits(digits, prefix string, opts FormatOptions) string {
+	var b strings.Builder
+	if opts.Prefix {
+		b.WriteString(prefix)
+	}
+
+	if opts.GroupSize <= 0 {
+		b.WriteString(digits)
+		return b.String()
+	}
+
+	for i := 0; i < len(digits); i += opts.GroupSize {
+		if i > 0 {
+			b.WriteString(opts.Separator)
+		}
+		end := i + opts.GroupSize
+		if end > len(digits) {
+			end = len(digits)
+		}
+		b.WriteString(digits[i:end])
+	}
+	return b.String()
+}
+
+// parseDigits parses s as an IP address written in digits of given bit size
+func parseDigits(s, prefix string, bitSize int) (IP, error) {
+	if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		s = s[len(prefix):]
+	}
+
+	var val Uint128
+	n := 0
+	for _, c := range s {
+		switch c {
+		case '.', ':', '-', '_', ' ':
+			continue
+		}
+
+		digit, err := strconv.ParseUint(string(c), 1<<bitSize, 8)
+		if err != nil || n*bitSize >= IPv6len*8 {
+			return IP{}, ErrInvalidIP
+		}
+		val = val.Lsh(uint(bitSize)).Or(NewUint128(digit))
+		n++
+	}
+
+	switch n * bitSize {
+	case IPv4len * 8:
+		return fromUint128(val, true), nil
+	case IPv6len * 8:
+		return fromUint128(val, false), nil
+	}
+	return IP{}, ErrInvalidIP
+}
@@ -0,0 +1,82 @@
+package ipx
+
+import (
+	"strings"
+	"testing"
+)
+
+var formatTests = []struct {
+	in   IP
+	hex  bool
+	opts FormatOptions
+	out  string
+}{
+	{IPv4(192, 0, 2, 1), false, DottedBinary, "11000000.00000000.00000010.00000001"},
+	{IPv4(192, 0, 2, 1), false, FormatOptions{Prefix: true}, "0b11000000000000000000001000000001"},
+	{IPv4(192, 0, 2, 1), true, FormatOptions{GroupSize: 2, Separator: "."}, "C0.00.02.01"},
+	{IPv4(192, 0, 2, 1), true, FormatOptions{Prefix: true, Lowercase: true}, "0xc0000201"},
+	{MustParseIP("2001:db8::ff"), true, ColonHex, "2001:0db8:0000:0000:0000:0000:0000:00ff"},
+	{MustParseIP("2001:db8::ff"), true, FormatOptions{GroupSize: 8, Separator: "_"}, "20010DB8_00000000_00000000_000000FF"},
+	{MustParseIP("::1"), false, FormatOptions{GroupSize: 64, Separator: " "}, strings.Repeat("0", 64) + " " + strings.Repeat("0", 63) + "1"},
+	{IP{}, false, DottedBinary, ""},
+	{IP{}, true, ColonHex, ""},
+}
+
+func TestFormat(t *testing.T) {
+	for _, tt := range formatTests {
+		var out string
+		if tt.hex {
+			out = tt.in.ToHexFormat(tt.opts)
+		} else {
+			out = tt.in.ToBinaryFormat(tt.opts)
+		}
+		if out != tt.out {
+			t.Errorf("IP.Format(%v, %+v) = %v, want %v", tt.in, tt.opts, out, tt.out)
+		}
+	}
+}
+
+var parseDigitsTests = []struct {
+	in  string
+	hex bool
+	out IP
+	err error
+}{
+	{"11000000.00000000.00000010.00000001", false, IPv4(192, 0, 2, 1), nil},
+	{"0b11000000000000000000001000000001", false, IPv4(192, 0, 2, 1), nil},
+	{"1100000000000000000000100000000", false, IP{}, ErrInvalidIP},
+	{"11000000000000000000001000000002", false, IP{}, ErrInvalidIP},
+	{"C0000201", true, IPv4(192, 0, 2, 1), nil},
+	{"0xc0.00.02.01", true, IPv4(192, 0, 2, 1), nil},
+	{"2001:0db8:0000:0000:0000:0000:0000:00ff", true, MustParseIP("2001:db8::ff"), nil},
+	{"0X20010DB80000000000000000000000FF", true, MustParseIP("2001:db8::ff"), nil},
+	{"2001:db8::ff", true, IP{}, ErrInvalidIP},
+	{"20010DB80000000000000000000000FF00", true, IP{}, ErrInvalidIP},
+	{"0xg0000201", true, IP{}, ErrInvalidIP},
+}
+
+func TestParseBinaryHex(t *testing.T) {
+	for _, tt := range parseDigitsTests {
+		var out IP
+		var err error
+		if tt.hex {
+			out, err = ParseHex(tt.in)
+		} else {
+			out, err = ParseBinary(tt.in)
+		}
+		if err != tt.err || !out.Equal(tt.out) {
+			t.Errorf("Parse(%q) = %v, %v, want %v, %v", tt.in, out, err, tt.out, tt.err)
+		}
+	}
+}
+
+func TestParseBinaryHexRoundTrip(t *testing.T) {
+	for _, ip := range []IP{IPv4(0, 0, 0, 0), IPv4(255, 255, 255, 255), MustParseIP("::"), MustParseIP("2001:db8:85a3::8a2e:370:7334")} {
+		if out, err := ParseBinary(ip.ToBinaryFormat(DottedBinary)); err != nil || !out.Equal(ip) || out.IsV4() != ip.IsV4() {
+			t.Errorf("ParseBinary(%v) = %v, %v, want %v", ip.ToBinaryFormat(DottedBinary), out, err, ip)
+		}
+		if out, err := ParseHex(ip.ToHexFormat(ColonHex)); err != nil || !out.Equal(ip) || out.IsV4() != ip.IsV4() {
+			t.Errorf("ParseHex(%v) = %v, %v, want %v", ip.ToHexFormat(ColonHex), out, err, ip)
+		}
+	}
+}
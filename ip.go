@@ -3,7 +3,6 @@ package ipx
 import (
 	"encoding/binary"
 	"errors"
-	"fmt"
 	"math/big"
 	"math/rand"
 	"net"
@@ -67,8 +66,14 @@ func (i IP) IsV6() bool {
 }
 
 // Before checks if i is before x
+// IPv4 addresses are considered to be before IPv6 addresses
 func (i IP) Before(x IP) bool {
-	return i.ToInt() < x.ToInt()
+	iVal, iV4 := i.toUint128()
+	xVal, xV4 := x.toUint128()
+	if iV4 != xV4 {
+		return iV4
+	}
+	return iVal.Cmp(xVal) < 0
 }
 
 // ToInt returns the int reprenstation of IP
@@ -97,30 +102,19 @@ func (i IP) ToBigInt() *big.Int {
 }
 
 // ToBinary returns the binary reprenstation of IP
+// It returns 32 digits for IPv4 and 128 digits for IPv6 addresses
 func (i IP) ToBinary() string {
-	// ipv4
-	ip := i.To4()
-	if ip.IP != nil {
-		return fmt.Sprintf("%08b%08b%08b%08b", ip.IP[0], ip.IP[1], ip.IP[2], ip.IP[3])
-	}
-	// ipv6
-	// TODO: implement toBinary for ipv6
-	return ""
+	return i.ToBinaryFormat(FormatOptions{})
 }
 
 // ToHex returns the hex reprenstation of IP
+// It returns 8 digits for IPv4 and 32 digits for IPv6 addresses
 func (i IP) ToHex() string {
-	// ipv4
-	ip := i.To4()
-	if ip.IP != nil {
-		return fmt.Sprintf("%02X%02X%02X%02X", ip.IP[0], ip.IP[1], ip.IP[2], ip.IP[3])
-	}
-	// ipv6
-	// TODO: implement toHex for ipv6
-	return ""
+	return i.ToHexFormat(FormatOptions{})
 }
 
 // IsPrivate returns whether i is in a private network
+// Use SpecialPurpose for the exact IANA registry entry of i
 func (i IP) IsPrivate() bool {
 	for _, net := range privateNetworks {
 		if net.Contains(i) {
@@ -133,20 +127,34 @@ func (i IP) IsPrivate() bool {
 
 // GetNext returns the next IP
 func (i IP) GetNext() IP {
-	// TODO: implement GetNext for IPv6
 	return i.GetNextN(uint32(1))
 }
 
 // GetNextN returns the n'th next IP
+// Addresses wrap around within the address family of i,
+// so the next IP of 255.255.255.255 is 0.0.0.0 and
+// the next IP of ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff is ::
 func (i IP) GetNextN(n uint32) IP {
-	// TODO: implement GetNextN for IPv6
-	val := i.ToInt()
-	val += n
-	i = FromInt(val)
-	return i
+	return i.GetNextUint128(NewUint128(uint64(n)))
+}
+
+// GetNextUint128 returns the n'th next IP
+// It wraps around exactly as GetNextN does
+func (i IP) GetNextUint128(n Uint128) IP {
+	val, v4 := i.toUint128()
+	val, _ = val.Add(n)
+	return fromUint128(val, v4)
+}
+
+// GetNextBig returns the n'th next IP
+// It wraps around exactly as GetNextN does
+// If n is negative, it returns the |n|'th previous IP
+func (i IP) GetNextBig(n *big.Int) IP {
+	return i.GetNextUint128(Uint128FromBigInt(n))
 }
 
 // GetAllNextN returns all IP's until n'th next IP
+// Use IterNextN to walk them in constant memory
 func (i IP) GetAllNextN(n uint32) []IP {
 	var ipList []IP
 
@@ -158,23 +166,30 @@ func (i IP) GetAllNextN(n uint32) []IP {
 
 // GetPrevious returns the previous IP
 func (i IP) GetPrevious() IP {
-	// TODO: implement GetPrevious for IPv6
 	return i.GetPreviousN(uint32(1))
 }
 
-// GetPreviousN returns the n'th next IP
+// GetPreviousN returns the n'th previous IP
+// Addresses wrap around within the address family of i,
+// so the previous IP of 0.0.0.0 is 255.255.255.255 and
+// the previous IP of :: is ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff
 func (i IP) GetPreviousN(n uint32) IP {
-	// TODO: implement GetPreviousN for IPv6
-	val := i.ToInt()
+	return i.GetPreviousUint128(NewUint128(uint64(n)))
+}
 
-	if n > val {
-		n = n - val - 1
-		val = IPv4bcastInt
-	}
-	val -= n
+// GetPreviousUint128 returns the n'th previous IP
+// It wraps around exactly as GetPreviousN does
+func (i IP) GetPreviousUint128(n Uint128) IP {
+	val, v4 := i.toUint128()
+	val, _ = val.Sub(n)
+	return fromUint128(val, v4)
+}
 
-	i = FromInt(val)
-	return i
+// GetPreviousBig returns the n'th previous IP
+// It wraps around exactly as GetPreviousN does
+// If n is negative, it returns the |n|'th next IP
+func (i IP) GetPreviousBig(n *big.Int) IP {
+	return i.GetNextBig(new(big.Int).Neg(n))
 }
 
 // GetAllPreviousN returns all IP's until n'th previous IP
@@ -276,8 +291,33 @@ func FromInt(i uint32) IP {
 }
 
 // RandomIPv4 returns a random IPv4 address
+// Use a Sampler to choose the source of randomness
 func RandomIPv4() IP {
-	return FromInt(uint32(rand.Intn(int(IPv4bcastInt))))
+	return FromInt(rand.Uint32())
+}
+
+// toUint128 returns the integer representation of i
+// and whether i is an IPv4 address
+// Invalid addresses are treated as 0.0.0.0
+func (i IP) toUint128() (Uint128, bool) {
+	if ip := i.IP.To4(); ip != nil {
+		return NewUint128(uint64(binary.BigEndian.Uint32(ip))), true
+	}
+	if len(i.IP) == IPv6len {
+		return uint128FromBytes(i.IP), false
+	}
+	return uint128Zero, true
+}
+
+// fromUint128 returns the IP address for given integer
+// If v4 is true, only the least significant 32 bits are used
+func fromUint128(u Uint128, v4 bool) IP {
+	if v4 {
+		return FromInt(uint32(u.Lo))
+	}
+	ip := make(net.IP, IPv6len)
+	u.putBytes(ip)
+	return IP{ip}
 }
 
 // ipEmptyString returns an empty string when ip is unset.
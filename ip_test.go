@@ -2,8 +2,10 @@ package ipx
 
 import (
 	"bytes"
+	"math/big"
 	"net"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -315,6 +317,24 @@ var ipBeforeTests = []*struct {
 		IP{net.IP{192, 0, 2, 1}},
 		true,
 	},
+
+	{
+		MustParseIP("2001:db8::1"),
+		MustParseIP("2001:db8::1:0"),
+		true,
+	},
+
+	{
+		MustParseIP("2001:db8::1:0"),
+		MustParseIP("2001:db8::1"),
+		false,
+	},
+
+	{
+		IP{net.IP{255, 255, 255, 255}},
+		MustParseIP("::"),
+		true,
+	},
 }
 
 func TestIPBefore(t *testing.T) {
@@ -365,6 +385,12 @@ var ipToBinaryTests = []*struct {
 		IP{net.IP{0, 0, 0, 0}},
 		"00000000000000000000000000000000",
 	},
+
+	// IPv6 address
+	{
+		MustParseIP("2001:db8::1"),
+		"00100000000000010000110110111000" + strings.Repeat("0", 95) + "1",
+	},
 }
 
 func TestToBinary(t *testing.T) {
@@ -392,6 +418,12 @@ var ipToHexTests = []*struct {
 		IP{net.IP{0, 0, 0, 0}},
 		"00000000",
 	},
+
+	// IPv6 address
+	{
+		MustParseIP("2001:db8::1"),
+		"20010DB8000000000000000000000001",
+	},
 }
 
 func TestToHex(t *testing.T) {
@@ -456,6 +488,26 @@ func TestToBigInt(t *testing.T) {
 	}
 }
 
+var isPrivateTests = []struct {
+	in  IP
+	out bool
+}{
+	{IPv4(10, 1, 2, 3), true},
+	{IPv4(198, 18, 0, 1), true},
+	{IPv4(192, 18, 0, 1), false},
+	{IPv4(8, 8, 8, 8), false},
+	{MustParseIP("fd00::1"), true},
+	{MustParseIP("2606:4700::1111"), false},
+}
+
+func TestIsPrivate(t *testing.T) {
+	for _, tt := range isPrivateTests {
+		if out := tt.in.IsPrivate(); out != tt.out {
+			t.Errorf("IP.IsPrivate(%v) = %v, want %v", tt.in, out, tt.out)
+		}
+	}
+}
+
 var getNextTests = []*struct {
 	in  IP
 	out string
@@ -473,6 +525,20 @@ var getNextTests = []*struct {
 		IP{net.IP{255, 255, 255, 255}},
 		"0.0.0.0",
 	},
+
+	// IPv6 address
+	{
+		MustParseIP("2001:db8::1"),
+		"2001:db8::2",
+	},
+	{
+		MustParseIP("2001:db8::ffff:ffff:ffff:ffff"),
+		"2001:db8:0:1::",
+	},
+	{
+		MustParseIP("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"),
+		"::",
+	},
 }
 
 func TestGetNext(t *testing.T) {
@@ -504,6 +570,23 @@ var getNextNTests = []*struct {
 		uint32(26),
 		"0.0.0.25",
 	},
+
+	// IPv6 address
+	{
+		MustParseIP("2001:db8::1"),
+		uint32(0xffff),
+		"2001:db8::1:0",
+	},
+	{
+		MustParseIP("2001:db8::ffff:ffff:ffff:fff0"),
+		uint32(0x20),
+		"2001:db8:0:1::10",
+	},
+	{
+		MustParseIP("ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe"),
+		uint32(3),
+		"::1",
+	},
 }
 
 func TestGetNextN(t *testing.T) {
@@ -514,6 +597,98 @@ func TestGetNextN(t *testing.T) {
 	}
 }
 
+var getNextBigTests = []*struct {
+	in  IP
+	n   string
+	out string
+}{
+	// IPv4 address
+	{
+		IP{net.IP{172, 16, 16, 1}},
+		"4294967296",
+		"172.16.16.1",
+	},
+	{
+		IP{net.IP{172, 16, 16, 1}},
+		"-2",
+		"172.16.15.255",
+	},
+
+	// IPv6 address
+	{
+		MustParseIP("2001:db8::"),
+		"18446744073709551616",
+		"2001:db8:0:1::",
+	},
+	{
+		MustParseIP("2001:db8::"),
+		"79228162514264337593543950336",
+		"2001:db9::",
+	},
+	{
+		MustParseIP("2001:db8::"),
+		"-1",
+		"2001:db7:ffff:ffff:ffff:ffff:ffff:ffff",
+	},
+	{
+		MustParseIP("::1"),
+		"340282366920938463463374607431768211456",
+		"::1",
+	},
+}
+
+func TestGetNextBig(t *testing.T) {
+	for _, tt := range getNextBigTests {
+		n, _ := new(big.Int).SetString(tt.n, 10)
+		if out := tt.in.GetNextBig(n).String(); out != tt.out {
+			t.Errorf("IP.GetNextBig(%v)(%v) = %v, want %v", tt.in, tt.n, out, tt.out)
+		}
+
+		// GetPreviousBig must revert GetNextBig
+		if out := MustParseIP(tt.out).GetPreviousBig(n).String(); out != tt.in.String() {
+			t.Errorf("IP.GetPreviousBig(%v)(%v) = %v, want %v", tt.out, tt.n, out, tt.in)
+		}
+	}
+}
+
+var getNextUint128Tests = []*struct {
+	in  IP
+	n   Uint128
+	out string
+}{
+	// IPv4 address
+	{
+		IP{net.IP{255, 255, 255, 255}},
+		Uint128{1, 1},
+		"0.0.0.0",
+	},
+
+	// IPv6 address
+	{
+		MustParseIP("2001:db8::"),
+		Uint128{0, 1 << 63},
+		"2001:db8:0:0:8000::",
+	},
+	{
+		MustParseIP("2001:db8::ffff:ffff:ffff:ffff"),
+		Uint128{1, 1},
+		"2001:db8:0:2::",
+	},
+}
+
+func TestGetNextUint128(t *testing.T) {
+	for _, tt := range getNextUint128Tests {
+		if out := tt.in.GetNextUint128(tt.n).String(); out != tt.out {
+			t.Errorf("IP.GetNextUint128(%v)(%v) = %v, want %v", tt.in, tt.n, out, tt.out)
+		}
+
+		// GetPreviousUint128 must revert GetNextUint128
+		if out := MustParseIP(tt.out).GetPreviousUint128(tt.n); !out.Equal(tt.in) {
+			t.Errorf("IP.GetPreviousUint128(%v)(%v) = %v, want %v", tt.out, tt.n, out, tt.in)
+		}
+	}
+}
+
 var getAllNextNTests = []*struct {
 	in  IP
 	n   uint32
@@ -587,6 +762,20 @@ var getPreviousTests = []*struct {
 		IP{net.IP{0, 0, 0, 1}},
 		"0.0.0.0",
 	},
+
+	// IPv6 address
+	{
+		MustParseIP("2001:db8::2"),
+		"2001:db8::1",
+	},
+	{
+		MustParseIP("2001:db8:0:1::"),
+		"2001:db8::ffff:ffff:ffff:ffff",
+	},
+	{
+		MustParseIP("::"),
+		"ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff",
+	},
 }
 
 func TestGetPrevious(t *testing.T) {
@@ -628,6 +817,23 @@ var getPreviousNTests = []*struct {
 		uint32(3),
 		"0.0.0.0",
 	},
+
+	// IPv6 address
+	{
+		MustParseIP("2001:db8::1:0"),
+		uint32(0xffff),
+		"2001:db8::1",
+	},
+	{
+		MustParseIP("2001:db8:0:1::10"),
+		uint32(0x20),
+		"2001:db8::ffff:ffff:ffff:fff0",
+	},
+	{
+		MustParseIP("::1"),
+		uint32(3),
+		"ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe",
+	},
 }
 
 func TestGetPreviousN(t *testing.T) {
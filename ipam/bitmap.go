@@ -0,0 +1,179 @@
+package ipam
+
+import (
+	"math/bits"
+	"sort"
+
+	"github.com/hakansa/ipx"
+)
+
+// Bitmap chunk sizes
+const (
+	chunkShift = 12
+	chunkBits  = 1 << chunkShift
+	chunkWords = chunkBits / 64
+)
+
+// chunk holds the bits of chunkBits consecutive offsets
+type chunk struct {
+	words [chunkWords]uint64
+	count int // number of set bits
+}
+
+// bitmap is a sparse bitmap of 128-bit offsets
+// Chunks without set bits are not stored, so a bitmap over a large
+// IPv6 range takes memory only for the chunks in use
+type bitmap struct {
+	chunks map[ipx.Uint128]*chunk
+	count  int // number of set bits
+}
+
+// split returns the chunk key and the bit index of off
+func split(off ipx.Uint128) (ipx.Uint128, int) {
+	return off.Rsh(chunkShift), int(off.Lo & (chunkBits - 1))
+}
+
+// join returns the offset of the bit index i in the chunk key
+func join(key ipx.Uint128, i int) ipx.Uint128 {
+	return key.Lsh(chunkShift).Or(ipx.NewUint128(uint64(i)))
+}
+
+// get reports whether the bit of off is set
+func (b *bitmap) get(off ipx.Uint128) bool {
+	key, i := split(off)
+	c := b.chunks[key]
+	return c != nil && c.words[i/64]&(1<<uint(i%64)) != 0
+}
+
+// set sets the bit of off
+// It returns false if the bit is already set
+func (b *bitmap) set(off ipx.Uint128) bool {
+	if b.chunks == nil {
+		b.chunks = map[ipx.Uint128]*chunk{}
+	}
+	key, i := split(off)
+	c := b.chunks[key]
+	if c == nil {
+		c = &chunk{}
+		b.chunks[key] = c
+	}
+	if c.words[i/64]&(1<<uint(i%64)) != 0 {
+		return false
+	}
+	c.words[i/64] |= 1 << uint(i%64)
+	c.count++
+	b.count++
+	return true
+}
+
+// clear clears the bit of off
+// It returns false if the bit is not set
+func (b *bitmap) clear(off ipx.Uint128) bool {
+	key, i := split(off)
+	c := b.chunks[key]
+	if c == nil || c.words[i/64]&(1<<uint(i%64)) == 0 {
+		return false
+	}
+	c.words[i/64] &^= 1 << uint(i%64)
+	c.count--
+	b.count--
+	if c.count == 0 {
+		delete(b.chunks, key)
+	}
+	return true
+}
+
+// nextClear returns the first offset between from and to, inclusively,
+// whose bit is not set
+func (b *bitmap) nextClear(from, to ipx.Uint128) (ipx.Uint128, bool) {
+	for from.Cmp(to) <= 0 {
+		key, i := split(from)
+		c := b.chunks[key]
+		if c == nil {
+			return from, true
+		}
+
+		if c.count < chunkBits {
+			for w := i / 64; w < chunkWords; w++ {
+				word := c.words[w]
+				if w == i/64 {
+					// bits before from are treated as set
+					word |= 1<<uint(i%64) - 1
+				}
+				if word != ^uint64(0) {
+					off := join(key, w*64+bits.TrailingZeros64(^word))
+					return off, off.Cmp(to) <= 0
+				}
+			}
+		}
+
+		// continue with the next chunk
+		next, carry := key.Add(ipx.NewUint128(1))
+		if carry || next.Rsh(128-chunkShift).Lo != 0 {
+			return ipx.Uint128{}, false
+		}
+		from = next.Lsh(chunkShift)
+	}
+	return ipx.Uint128{}, false
+}
+
+// anySet reports whether the bit of any offset between from and to,
+// inclusively, is set
+func (b *bitmap) anySet(from, to ipx.Uint128) bool {
+	for key, c := range b.chunks {
+		if join(key, chunkBits-1).Cmp(from) < 0 || join(key, 0).Cmp(to) > 0 {
+			continue
+		}
+		for w, word := range c.words {
+			for word != 0 {
+				off := join(key, w*64+bits.TrailingZeros64(word))
+				if off.Cmp(from) >= 0 && off.Cmp(to) <= 0 {
+					return true
+				}
+				word &= word - 1
+			}
+		}
+	}
+	return false
+}
+
+// countSet returns the number of offsets between from and to,
+// inclusively, whose bits are set
+func (b *bitmap) countSet(from, to ipx.Uint128) int {
+	n := 0
+	for key, c := range b.chunks {
+		if join(key, chunkBits-1).Cmp(from) < 0 || join(key, 0).Cmp(to) > 0 {
+			continue
+		}
+		for w, word := range c.words {
+			for word != 0 {
+				off := join(key, w*64+bits.TrailingZeros64(word))
+				if off.Cmp(from) >= 0 && off.Cmp(to) <= 0 {
+					n++
+				}
+				word &= word - 1
+			}
+		}
+	}
+	return n
+}
+
+// offsets returns the offsets whose bits are set in ascending order
+func (b *bitmap) offsets() []ipx.Uint128 {
+	keys := make([]ipx.Uint128, 0, len(b.chunks))
+	for key := range b.chunks {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Cmp(keys[j]) < 0 })
+
+	offs := make([]ipx.Uint128, 0, b.count)
+	for _, key := range keys {
+		for w, word := range b.chunks[key].words {
+			for word != 0 {
+				offs = append(offs, join(key, w*64+bits.TrailingZeros64(word)))
+				word &= word - 1
+			}
+		}
+	}
+	return offs
+}
@@ -0,0 +1,76 @@
+package ipam
+
+import (
+	"testing"
+
+	"github.com/hakansa/ipx"
+)
+
+func TestBitmapNextClear(t *testing.T) {
+	var b bitmap
+	for i := uint64(0); i < chunkBits+3; i++ {
+		b.set(ipx.NewUint128(i))
+	}
+
+	max := ipx.Uint128{Hi: ^uint64(0), Lo: ^uint64(0)}
+	for _, tt := range []struct {
+		from, to ipx.Uint128
+		out      ipx.Uint128
+		ok       bool
+	}{
+		{ipx.NewUint128(0), max, ipx.NewUint128(chunkBits + 3), true},
+		{ipx.NewUint128(100), ipx.NewUint128(chunkBits + 2), ipx.Uint128{}, false},
+		{ipx.NewUint128(chunkBits + 10), max, ipx.NewUint128(chunkBits + 10), true},
+		{max, max, max, true},
+	} {
+		out, ok := b.nextClear(tt.from, tt.to)
+		if ok != tt.ok || ok && out != tt.out {
+			t.Errorf("bitmap.nextClear(%v, %v) = %v, %v, want %v, %v", tt.from, tt.to, out, ok, tt.out, tt.ok)
+		}
+	}
+
+	b.set(max)
+	if _, ok := b.nextClear(max, max); ok {
+		t.Errorf("bitmap.nextClear(max, max) = true, want false")
+	}
+}
+
+func TestBitmapSetClear(t *testing.T) {
+	var b bitmap
+	offs := []ipx.Uint128{{Hi: 1, Lo: 5}, ipx.NewUint128(70), ipx.NewUint128(3)}
+	for _, off := range offs {
+		if !b.set(off) || b.set(off) {
+			t.Errorf("bitmap.set(%v) twice = true", off)
+		}
+	}
+
+	got := b.offsets()
+	want := []ipx.Uint128{ipx.NewUint128(3), ipx.NewUint128(70), {Hi: 1, Lo: 5}}
+	for i := range want {
+		if i >= len(got) || got[i] != want[i] {
+			t.Fatalf("bitmap.offsets() = %v, want %v", got, want)
+		}
+	}
+
+	if !b.anySet(ipx.NewUint128(4), ipx.NewUint128(70)) {
+		t.Errorf("bitmap.anySet(4, 70) = false, want true")
+	}
+	if b.anySet(ipx.NewUint128(4), ipx.NewUint128(69)) {
+		t.Errorf("bitmap.anySet(4, 69) = true, want false")
+	}
+	if n := b.countSet(ipx.NewUint128(3), ipx.Uint128{Hi: 1, Lo: 5}); n != 3 {
+		t.Errorf("bitmap.countSet(3, 2^64+5) = %d, want 3", n)
+	}
+	if n := b.countSet(ipx.NewUint128(4), ipx.NewUint128(70)); n != 1 {
+		t.Errorf("bitmap.countSet(4, 70) = %d, want 1", n)
+	}
+
+	for _, off := range offs {
+		if !b.clear(off) || b.clear(off) {
+			t.Errorf("bitmap.clear(%v) twice = true", off)
+		}
+	}
+	if b.count != 0 || len(b.chunks) != 0 {
+		t.Errorf("bitmap has %d bits in %d chunks, want none", b.count, len(b.chunks))
+	}
+}
@@ -0,0 +1,372 @@
+package ipam
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hakansa/ipx"
+)
+
+// Error definitions
+var (
+	ErrCorruptJournal = errors.New("corrupt journal")
+)
+
+// journalHeader is the first line of a journal
+// followed by the version of the snapshot
+const journalHeader = "ipam-journal 1 "
+
+// compactMinLines is the number of transaction lines a journal has
+// before it is compacted
+const compactMinLines = 1024
+
+// FileStore is a Store kept in an append-only journal file
+//
+// The journal starts with a snapshot of the allocated addresses followed
+// by a line for each committed Tx, which is appended and synced to disk
+// before the Tx returns, so a crash loses at most the Tx being written,
+// whose incomplete line is ignored
+// When the journal grows twice as long as the snapshot of its state, it
+// is compacted by writing a new snapshot to a temporary file which is
+// synced and renamed over the journal
+//
+// Updates hold an exclusive lock on the file with the ".lock" suffix,
+// so multiple processes on one host can share a journal
+// The lock is taken with flock on unix systems, on other systems only
+// the FileStores in the same process are serialized
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+	lock *os.File
+	f    *os.File
+
+	offset  int64                 // end of the last line read
+	state   map[ipx.Addr]struct{} // allocated addresses
+	version uint64
+	lines   int // number of transaction lines after the snapshot
+}
+
+// OpenFileStore opens the journal at path, creating it if necessary
+func OpenFileStore(path string) (*FileStore, error) {
+	lock, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FileStore{path: path, lock: lock}
+	if err := s.locked(s.refresh); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Update implements the Store interface
+func (s *FileStore) Update(fn func(tx Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lock == nil {
+		return ErrStoreClosed
+	}
+
+	return s.locked(func() error {
+		if err := s.refresh(); err != nil {
+			return err
+		}
+
+		tx := &fileTx{s: s}
+		if err := fn(tx); err != nil || len(tx.ops) == 0 {
+			return err
+		}
+
+		// an incomplete line of a crashed writer is overwritten
+		line := strings.Join(tx.ops, " ") + "\n"
+		if _, err := s.f.WriteAt([]byte(line), s.offset); err != nil {
+			return err
+		}
+		if err := s.f.Truncate(s.offset + int64(len(line))); err != nil {
+			return err
+		}
+		if err := s.f.Sync(); err != nil {
+			return err
+		}
+		if err := s.apply(line[:len(line)-1]); err != nil {
+			return err
+		}
+		s.offset += int64(len(line))
+
+		if s.lines >= compactMinLines && s.lines > 2*len(s.state) {
+			return s.compact()
+		}
+		return nil
+	})
+}
+
+// Compact replaces the journal with a snapshot of its state
+func (s *FileStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lock == nil {
+		return ErrStoreClosed
+	}
+
+	return s.locked(func() error {
+		if err := s.refresh(); err != nil {
+			return err
+		}
+		return s.compact()
+	})
+}
+
+// Close implements the Store interface
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	if s.f != nil {
+		err = s.f.Close()
+		s.f = nil
+	}
+	if s.lock != nil {
+		if lerr := s.lock.Close(); err == nil {
+			err = lerr
+		}
+		s.lock = nil
+	}
+	return err
+}
+
+// locked calls fn while holding the lock of the journal
+func (s *FileStore) locked(fn func() error) error {
+	if err := lockFile(s.lock); err != nil {
+		return err
+	}
+	defer unlockFile(s.lock)
+	return fn()
+}
+
+// refresh reads the lines other processes appended to the journal
+// The journal is reopened if another process has compacted it
+func (s *FileStore) refresh() error {
+	if s.f != nil {
+		fi, err := s.f.Stat()
+		if err != nil {
+			return err
+		}
+		if pi, err := os.Stat(s.path); err == nil && os.SameFile(fi, pi) {
+			return s.read()
+		}
+		s.f.Close()
+		s.f = nil
+	}
+
+	f, err := os.OpenFile(s.path, os.O_RDWR, 0)
+	if os.IsNotExist(err) {
+		if err := s.writeSnapshot(0, nil); err != nil {
+			return err
+		}
+		f, err = os.OpenFile(s.path, os.O_RDWR, 0)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.f, s.offset, s.state, s.version, s.lines = f, 0, map[ipx.Addr]struct{}{}, 0, 0
+	if err := s.read(); err != nil {
+		return err
+	}
+	if s.offset == 0 {
+		// journals are created with a complete header
+		return ErrCorruptJournal
+	}
+	return nil
+}
+
+// read applies the complete lines after offset
+func (s *FileStore) read() error {
+	if _, err := s.f.Seek(s.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(s.f)
+	for {
+		line, err := r.ReadString('\n')
+		if err == io.EOF {
+			// an incomplete last line is the Tx of a crashed writer
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.apply(line[:len(line)-1]); err != nil {
+			return err
+		}
+		s.offset += int64(len(line))
+	}
+}
+
+// apply applies a line of the journal to the state
+// The header is followed by snapshot lines like "=10.0.0.1" and
+// transaction lines like "+10.0.0.2 -10.0.0.1"
+func (s *FileStore) apply(line string) error {
+	if s.offset == 0 {
+		if !strings.HasPrefix(line, journalHeader) {
+			return ErrCorruptJournal
+		}
+		version, err := strconv.ParseUint(line[len(journalHeader):], 10, 64)
+		if err != nil {
+			return ErrCorruptJournal
+		}
+		s.version = version
+		return nil
+	}
+
+	if strings.HasPrefix(line, "=") {
+		a, err := ipx.ParseAddr(line[1:])
+		if err != nil {
+			return ErrCorruptJournal
+		}
+		s.state[a] = struct{}{}
+		return nil
+	}
+
+	ops := strings.Fields(line)
+	addrs := make([]ipx.Addr, len(ops))
+	for i, op := range ops {
+		a, err := ipx.ParseAddr(op[1:])
+		if err != nil || op[0] != '+' && op[0] != '-' {
+			return ErrCorruptJournal
+		}
+		addrs[i] = a
+	}
+	for i, op := range ops {
+		if op[0] == '+' {
+			s.state[addrs[i]] = struct{}{}
+		} else {
+			delete(s.state, addrs[i])
+		}
+	}
+	s.version++
+	s.lines++
+	return nil
+}
+
+// compact replaces the journal with a snapshot of its state
+func (s *FileStore) compact() error {
+	if err := s.writeSnapshot(s.version, s.addrs()); err != nil {
+		return err
+	}
+	s.f.Close()
+	s.f = nil
+	return s.refresh()
+}
+
+// writeSnapshot atomically replaces the journal with a snapshot
+// of the allocated addresses at version
+func (s *FileStore) writeSnapshot(version uint64, addrs []ipx.Addr) (err error) {
+	dir, base := filepath.Split(s.path)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := os.CreateTemp(dir, base+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	w := bufio.NewWriter(tmp)
+	w.WriteString(journalHeader + strconv.FormatUint(version, 10) + "\n")
+	for _, a := range addrs {
+		w.WriteString("=" + a.String() + "\n")
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// addrs returns the allocated addresses in ascending order
+func (s *FileStore) addrs() []ipx.Addr {
+	addrs := make([]ipx.Addr, 0, len(s.state))
+	for a := range s.state {
+		addrs = append(addrs, a)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i].Less(addrs[j]) })
+	return addrs
+}
+
+// syncDir syncs the directory dir so a rename in it is durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) {
+		return err
+	}
+	return nil
+}
+
+// fileTx is a Tx of a FileStore
+type fileTx struct {
+	s   *FileStore
+	ops []string
+}
+
+// Version implements the Tx interface
+func (tx *fileTx) Version() uint64 {
+	return tx.s.version
+}
+
+// Allocations implements the Tx interface
+func (tx *fileTx) Allocations() ([]ipx.IP, error) {
+	addrs := tx.s.addrs()
+	ips := make([]ipx.IP, len(addrs))
+	for i, a := range addrs {
+		ips[i] = a.IP()
+	}
+	return ips, nil
+}
+
+// Put implements the Tx interface
+func (tx *fileTx) Put(ip ipx.IP) error {
+	return tx.record('+', ip)
+}
+
+// Delete implements the Tx interface
+func (tx *fileTx) Delete(ip ipx.IP) error {
+	return tx.record('-', ip)
+}
+
+// record appends the operation op on ip to the Tx
+func (tx *fileTx) record(op byte, ip ipx.IP) error {
+	a, ok := ipx.AddrFromIP(ip)
+	if !ok {
+		return ipx.ErrInvalidIP
+	}
+	tx.ops = append(tx.ops, string(op)+a.String())
+	return nil
+}
@@ -0,0 +1,191 @@
+package ipam
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hakansa/ipx"
+)
+
+func openTestFileStore(t *testing.T, path string) *FileStore {
+	t.Helper()
+	s, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore(%q) error = %v", path, err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func storedAllocations(t *testing.T, s Store) (uint64, []string) {
+	t.Helper()
+	var version uint64
+	var out []string
+	err := s.Update(func(tx Tx) error {
+		ips, err := tx.Allocations()
+		if err != nil {
+			return err
+		}
+		version = tx.Version()
+		for _, ip := range ips {
+			out = append(out, ip.String())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Store.Update() error = %v", err)
+	}
+	return version, out
+}
+
+func TestFileStoreReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pool.journal")
+	s := openTestFileStore(t, path)
+
+	err := s.Update(func(tx Tx) error {
+		tx.Put(ipx.MustParseIP("10.0.0.2"))
+		tx.Put(ipx.MustParseIP("10.0.0.1"))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("FileStore.Update() error = %v", err)
+	}
+	err = s.Update(func(tx Tx) error {
+		return tx.Delete(ipx.MustParseIP("10.0.0.2"))
+	})
+	if err != nil {
+		t.Fatalf("FileStore.Update() error = %v", err)
+	}
+	s.Close()
+
+	version, got := storedAllocations(t, openTestFileStore(t, path))
+	if version != 2 || strings.Join(got, ",") != "10.0.0.1" {
+		t.Errorf("reopened FileStore = %d %v, want 2 [10.0.0.1]", version, got)
+	}
+}
+
+func TestFileStoreTornLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pool.journal")
+	s := openTestFileStore(t, path)
+	s.Update(func(tx Tx) error { return tx.Put(ipx.MustParseIP("10.0.0.1")) })
+	s.Close()
+
+	// a writer crashed in the middle of a line
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("+10.0.0.2 +10.0")
+	f.Close()
+
+	s = openTestFileStore(t, path)
+	if version, got := storedAllocations(t, s); version != 1 || strings.Join(got, ",") != "10.0.0.1" {
+		t.Errorf("FileStore with torn line = %d %v, want 1 [10.0.0.1]", version, got)
+	}
+
+	s.Update(func(tx Tx) error { return tx.Put(ipx.MustParseIP("10.0.0.3")) })
+	if version, got := storedAllocations(t, openTestFileStore(t, path)); version != 2 || strings.Join(got, ",") != "10.0.0.1,10.0.0.3" {
+		t.Errorf("FileStore after torn line = %d %v, want 2 [10.0.0.1 10.0.0.3]", version, got)
+	}
+}
+
+func TestFileStoreCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pool.journal")
+	if err := os.WriteFile(path, []byte("not a journal\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenFileStore(path); err != ErrCorruptJournal {
+		t.Errorf("OpenFileStore(corrupt) error = %v, want %v", err, ErrCorruptJournal)
+	}
+}
+
+func TestFileStoreCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pool.journal")
+	s := openTestFileStore(t, path)
+	other := openTestFileStore(t, path)
+
+	ip := ipx.MustParseIP("10.0.0.1")
+	for i := 0; i < compactMinLines; i++ {
+		s.Update(func(tx Tx) error {
+			if i%2 == 0 {
+				return tx.Put(ip)
+			}
+			return tx.Delete(ip)
+		})
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := journalHeader + "1024\n"; string(data) != want {
+		t.Errorf("compacted journal = %q, want %q", data, want)
+	}
+
+	// the other store reopens the replaced journal
+	other.Update(func(tx Tx) error { return tx.Put(ip) })
+	if version, got := storedAllocations(t, s); version != compactMinLines+1 || strings.Join(got, ",") != "10.0.0.1" {
+		t.Errorf("FileStore after compaction = %d %v, want %d [10.0.0.1]", version, got, compactMinLines+1)
+	}
+	if err := s.Compact(); err != nil {
+		t.Errorf("FileStore.Compact() error = %v", err)
+	}
+	if version, got := storedAllocations(t, other); version != compactMinLines+1 || strings.Join(got, ",") != "10.0.0.1" {
+		t.Errorf("FileStore after Compact = %d %v, want %d [10.0.0.1]", version, got, compactMinLines+1)
+	}
+}
+
+func TestFileStoreClosed(t *testing.T) {
+	s := openTestFileStore(t, filepath.Join(t.TempDir(), "pool.journal"))
+	s.Close()
+	if err := s.Update(func(tx Tx) error { return nil }); err != ErrStoreClosed {
+		t.Errorf("FileStore.Update() after Close error = %v, want %v", err, ErrStoreClosed)
+	}
+}
+
+func TestFileStoreSharedPool(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pool.journal")
+	n := ipx.MustParseCIDR("10.0.0.0/24")
+
+	const workers, perWorker = 4, 20
+	pools := make([]*StoredPool, workers)
+	for i := range pools {
+		pool, _ := NewSubnetPool(n)
+		p, err := NewStoredPool(pool, openTestFileStore(t, path))
+		if err != nil {
+			t.Fatalf("NewStoredPool() error = %v", err)
+		}
+		pools[i] = p
+	}
+
+	var mu sync.Mutex
+	seen := map[string]bool{}
+	var wg sync.WaitGroup
+	for _, p := range pools {
+		wg.Add(1)
+		go func(p *StoredPool) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				ip, err := p.Allocate()
+				if err != nil {
+					t.Errorf("StoredPool.Allocate() error = %v", err)
+					return
+				}
+				mu.Lock()
+				if seen[ip.String()] {
+					t.Errorf("StoredPool.Allocate() = %v twice", ip)
+				}
+				seen[ip.String()] = true
+				mu.Unlock()
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	if _, got := storedAllocations(t, openTestFileStore(t, path)); len(got) != workers*perWorker {
+		t.Errorf("stored %d allocations, want %d", len(got), workers*perWorker)
+	}
+}
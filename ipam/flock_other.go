@@ -0,0 +1,38 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package ipam
+
+import (
+	"os"
+	"sync"
+)
+
+// fileLocks serializes the FileStores of the process since file locks
+// are not supported on this system
+var fileLocks = struct {
+	sync.Mutex
+	m map[string]*sync.Mutex
+}{m: map[string]*sync.Mutex{}}
+
+// lockFile takes an exclusive lock on the name of f in the process
+func lockFile(f *os.File) error {
+	fileLocks.Lock()
+	mu := fileLocks.m[f.Name()]
+	if mu == nil {
+		mu = &sync.Mutex{}
+		fileLocks.m[f.Name()] = mu
+	}
+	fileLocks.Unlock()
+	mu.Lock()
+	return nil
+}
+
+// unlockFile releases the lock on the name of f
+func unlockFile(f *os.File) error {
+	fileLocks.Lock()
+	mu := fileLocks.m[f.Name()]
+	fileLocks.Unlock()
+	mu.Unlock()
+	return nil
+}
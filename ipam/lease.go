@@ -0,0 +1,345 @@
+package ipam
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hakansa/ipx"
+)
+
+// Error definitions
+var (
+	ErrInvalidLeaseDuration = errors.New("invalid lease duration")
+	ErrNoLease              = errors.New("client has no lease of the ip address")
+	ErrLeaseExpired         = errors.New("lease has expired")
+)
+
+// Clock tells the current time
+// Inject a fake Clock to test leases deterministically
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the Clock of the system
+type systemClock struct{}
+
+// Now returns time.Now()
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// ClientID identifies a client, like the MAC address of a DHCPv4 client
+// or the DUID of a DHCPv6 client
+type ClientID string
+
+// MACClientID returns the ClientID of the MAC address mac
+// like "mac:00:00:5e:00:53:01"
+func MACClientID(mac net.HardwareAddr) ClientID {
+	return ClientID("mac:" + mac.String())
+}
+
+// DUIDClientID returns the ClientID of the DHCPv6 DUID duid
+// like "duid:000100015f4c3b0a00005e005301"
+func DUIDClientID(duid []byte) ClientID {
+	return ClientID("duid:" + hex.EncodeToString(duid))
+}
+
+// Lease is the binding of an address to a client
+type Lease struct {
+	IP       ipx.IP
+	ClientID ClientID
+	Start    time.Time // time the address was bound to the client
+	Expiry   time.Time // time the lease expires unless it is renewed
+}
+
+// Active reports whether the lease has not expired at now
+func (l Lease) Active(now time.Time) bool {
+	return now.Before(l.Expiry)
+}
+
+// LeaseConfig configures a LeaseManager
+type LeaseConfig struct {
+	// Duration is the duration of leases and renewals
+	Duration time.Duration
+
+	// GracePeriod is how long an expired lease is kept for its client,
+	// which can still renew it, before the address is released
+	GracePeriod time.Duration
+
+	// StickyPeriod is how long the last address of a client is
+	// remembered after its lease ends
+	// If zero, it is remembered until the address is bound to another
+	// client
+	StickyPeriod time.Duration
+
+	// Clock tells the time, the system clock is used if it is nil
+	Clock Clock
+}
+
+// LeaseManager leases the addresses of a Pool to clients
+//
+// A client that asks for an address gets its current lease, or the
+// address it requested, or the address it had last if they are free,
+// or any free address
+// Expired leases are kept until their grace period is over and then
+// released by Sweep, which also runs when the pool is exhausted
+// The last address of each client is remembered until it is bound to
+// another client or the sticky period is over, and as the pool hands
+// out addresses in order, a returning client usually gets it back
+// A LeaseManager is safe for concurrent use
+type LeaseManager struct {
+	mu     sync.Mutex
+	pool   *Pool
+	config LeaseConfig
+	leases map[ClientID]*Lease
+	byIP   map[ipx.Addr]*Lease
+	last   map[ClientID]sticky   // last address of clients without a lease
+	lastBy map[ipx.Addr]ClientID // client whose last address it is
+}
+
+// sticky is the last address of a client and the time its lease ended
+type sticky struct {
+	addr ipx.Addr
+	end  time.Time
+}
+
+// NewLeaseManager creates a new LeaseManager with pool and config
+// The addresses of the leases are allocated in pool, so it should
+// not be used by others
+// It returns ErrInvalidLeaseDuration if the duration is not positive
+// or the grace period or the sticky period is negative
+func NewLeaseManager(pool *Pool, config LeaseConfig) (*LeaseManager, error) {
+	if config.Duration <= 0 || config.GracePeriod < 0 || config.StickyPeriod < 0 {
+		return nil, ErrInvalidLeaseDuration
+	}
+	if config.Clock == nil {
+		config.Clock = systemClock{}
+	}
+
+	return &LeaseManager{
+		pool:   pool,
+		config: config,
+		leases: map[ClientID]*Lease{},
+		byIP:   map[ipx.Addr]*Lease{},
+		last:   map[ClientID]sticky{},
+		lastBy: map[ipx.Addr]ClientID{},
+	}, nil
+}
+
+// Acquire leases an address to the client id
+// If the client has a lease which is active or in its grace period,
+// the lease is renewed
+// Otherwise requested, which may be the zero IP, or the last address
+// of the client is bound if it is free, or else any free address
+// It returns ErrPoolExhausted if there is no free address
+func (m *LeaseManager) Acquire(id ClientID, requested ipx.IP) (Lease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.config.Clock.Now()
+	if l, ok := m.leases[id]; ok {
+		if m.inGrace(l, now) {
+			l.Expiry = now.Add(m.config.Duration)
+			return *l, nil
+		}
+		m.release(l)
+	}
+
+	var last ipx.IP
+	if s, ok := m.last[id]; ok && m.isSticky(s, now) {
+		last = s.addr.IP()
+	}
+	for _, ip := range []ipx.IP{requested, last} {
+		if ip.IP != nil && m.pool.AllocateSpecific(ip) == nil {
+			return m.bind(id, ip, now), nil
+		}
+	}
+
+	ip, err := m.pool.Allocate()
+	if err == ErrPoolExhausted && len(m.sweep(now)) > 0 {
+		ip, err = m.pool.Allocate()
+	}
+	if err != nil {
+		return Lease{}, err
+	}
+	return m.bind(id, ip, now), nil
+}
+
+// Renew extends the lease of ip by the client id
+// It returns ErrNoLease if the client has no lease of ip and
+// ErrLeaseExpired if the grace period of the lease is over,
+// in which case the address is released
+func (m *LeaseManager) Renew(id ClientID, ip ipx.IP) (Lease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.leases[id]
+	if !ok || !l.IP.Equal(ip) {
+		return Lease{}, ErrNoLease
+	}
+	now := m.config.Clock.Now()
+	if !m.inGrace(l, now) {
+		m.release(l)
+		return Lease{}, ErrLeaseExpired
+	}
+	l.Expiry = now.Add(m.config.Duration)
+	return *l, nil
+}
+
+// Release ends the lease of ip by the client id and releases ip
+// It returns ErrNoLease if the client has no lease of ip
+func (m *LeaseManager) Release(id ClientID, ip ipx.IP) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.leases[id]
+	if !ok || !l.IP.Equal(ip) {
+		return ErrNoLease
+	}
+	m.release(l)
+	return nil
+}
+
+// Lookup returns the lease of the client id
+func (m *LeaseManager) Lookup(id ClientID) (Lease, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.leases[id]
+	if !ok {
+		return Lease{}, false
+	}
+	return *l, true
+}
+
+// LookupIP returns the lease of ip
+func (m *LeaseManager) LookupIP(ip ipx.IP) (Lease, bool) {
+	a, ok := ipx.AddrFromIP(ip)
+	if !ok {
+		return Lease{}, false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.byIP[a]
+	if !ok {
+		return Lease{}, false
+	}
+	return *l, true
+}
+
+// Leases returns the leases, including the expired leases in their
+// grace period or not swept yet, in ascending order of their addresses
+func (m *LeaseManager) Leases() []Lease {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	addrs := make([]ipx.Addr, 0, len(m.byIP))
+	for a := range m.byIP {
+		addrs = append(addrs, a)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i].Less(addrs[j]) })
+
+	leases := make([]Lease, len(addrs))
+	for i, a := range addrs {
+		leases[i] = *m.byIP[a]
+	}
+	return leases
+}
+
+// Sweep releases the addresses of the leases whose grace period is over
+// and returns the leases
+// It also forgets the last addresses of clients whose sticky period
+// is over
+func (m *LeaseManager) Sweep() []Lease {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sweep(m.config.Clock.Now())
+}
+
+// RunSweeper calls Sweep every interval until ctx is done
+// It waits for the interval on the system clock, whatever the Clock is
+func (m *LeaseManager) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Sweep()
+		}
+	}
+}
+
+// sweep releases the leases whose grace period is over at now
+// and forgets the last addresses whose sticky period is over
+func (m *LeaseManager) sweep(now time.Time) []Lease {
+	for id, s := range m.last {
+		if !m.isSticky(s, now) {
+			m.forget(id)
+		}
+	}
+
+	var swept []Lease
+	for _, l := range m.leases {
+		if !m.inGrace(l, now) {
+			swept = append(swept, *l)
+		}
+	}
+	sort.Slice(swept, func(i, j int) bool { return swept[i].Expiry.Before(swept[j].Expiry) })
+
+	for _, l := range swept {
+		m.release(m.leases[l.ClientID])
+	}
+	return swept
+}
+
+// isSticky reports whether the last address s is remembered at now
+func (m *LeaseManager) isSticky(s sticky, now time.Time) bool {
+	return m.config.StickyPeriod == 0 || now.Before(s.end.Add(m.config.StickyPeriod))
+}
+
+// forget forgets the last address of the client id
+func (m *LeaseManager) forget(id ClientID) {
+	if s, ok := m.last[id]; ok {
+		delete(m.lastBy, s.addr)
+		delete(m.last, id)
+	}
+}
+
+// inGrace reports whether l is active or in its grace period at now
+func (m *LeaseManager) inGrace(l *Lease, now time.Time) bool {
+	return now.Before(l.Expiry.Add(m.config.GracePeriod))
+}
+
+// bind binds the allocated ip to the client id
+func (m *LeaseManager) bind(id ClientID, ip ipx.IP, now time.Time) Lease {
+	a, _ := ipx.AddrFromIP(ip)
+	l := &Lease{IP: a.IP(), ClientID: id, Start: now, Expiry: now.Add(m.config.Duration)}
+	m.leases[id] = l
+	m.byIP[a] = l
+	m.forget(id)
+	if other, ok := m.lastBy[a]; ok {
+		m.forget(other)
+	}
+	return *l
+}
+
+// release ends l and releases its address
+func (m *LeaseManager) release(l *Lease) {
+	a, _ := ipx.AddrFromIP(l.IP)
+	m.pool.Release(l.IP)
+	delete(m.leases, l.ClientID)
+	delete(m.byIP, a)
+	m.last[l.ClientID] = sticky{a, m.config.Clock.Now()}
+	m.lastBy[a] = l.ClientID
+}
@@ -0,0 +1,191 @@
+package ipam
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hakansa/ipx"
+)
+
+// fakeClock is a Clock that only moves when it is advanced
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestLeaseManager(t *testing.T, cidr string) (*LeaseManager, *fakeClock) {
+	t.Helper()
+	pool, err := NewSubnetPool(ipx.MustParseCIDR(cidr))
+	if err != nil {
+		t.Fatalf("NewSubnetPool(%q) error = %v", cidr, err)
+	}
+	clock := &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	m, err := NewLeaseManager(pool, LeaseConfig{Duration: time.Hour, GracePeriod: 10 * time.Minute, Clock: clock})
+	if err != nil {
+		t.Fatalf("NewLeaseManager() error = %v", err)
+	}
+	return m, clock
+}
+
+func TestNewLeaseManagerInvalid(t *testing.T) {
+	pool, _ := NewSubnetPool(ipx.MustParseCIDR("10.0.0.0/29"))
+	for _, config := range []LeaseConfig{{}, {Duration: -time.Second}, {Duration: time.Hour, GracePeriod: -time.Second}, {Duration: time.Hour, StickyPeriod: -time.Second}} {
+		if _, err := NewLeaseManager(pool, config); err != ErrInvalidLeaseDuration {
+			t.Errorf("NewLeaseManager(%+v) error = %v, want %v", config, err, ErrInvalidLeaseDuration)
+		}
+	}
+}
+
+func TestClientID(t *testing.T) {
+	mac, _ := net.ParseMAC("00:00:5e:00:53:01")
+	if got, want := MACClientID(mac), ClientID("mac:00:00:5e:00:53:01"); got != want {
+		t.Errorf("MACClientID(%v) = %v, want %v", mac, got, want)
+	}
+	if got, want := DUIDClientID([]byte{0, 3, 0, 1, 0, 0, 0x5e, 0, 0x53, 1}), ClientID("duid:0003000100005e005301"); got != want {
+		t.Errorf("DUIDClientID() = %v, want %v", got, want)
+	}
+}
+
+func TestLeaseManagerAcquire(t *testing.T) {
+	m, clock := newTestLeaseManager(t, "10.0.0.0/29")
+
+	l, err := m.Acquire("a", ipx.IP{})
+	if err != nil || l.IP.String() != "10.0.0.1" || !l.Expiry.Equal(clock.now.Add(time.Hour)) {
+		t.Fatalf("LeaseManager.Acquire(a) = %+v, %v", l, err)
+	}
+
+	// the client keeps its lease, which is renewed
+	clock.advance(30 * time.Minute)
+	if l, err := m.Acquire("a", ipx.MustParseIP("10.0.0.5")); err != nil || l.IP.String() != "10.0.0.1" || !l.Expiry.Equal(clock.now.Add(time.Hour)) {
+		t.Errorf("LeaseManager.Acquire(a) again = %+v, %v", l, err)
+	}
+
+	// a free requested address is bound, an allocated one is not
+	if l, err := m.Acquire("b", ipx.MustParseIP("10.0.0.5")); err != nil || l.IP.String() != "10.0.0.5" {
+		t.Errorf("LeaseManager.Acquire(b, 10.0.0.5) = %+v, %v", l, err)
+	}
+	if l, err := m.Acquire("c", ipx.MustParseIP("10.0.0.5")); err != nil || l.IP.String() != "10.0.0.2" {
+		t.Errorf("LeaseManager.Acquire(c, 10.0.0.5) = %+v, %v", l, err)
+	}
+
+	if l, ok := m.LookupIP(ipx.MustParseIP("10.0.0.5")); !ok || l.ClientID != "b" {
+		t.Errorf("LeaseManager.LookupIP(10.0.0.5) = %+v, %v", l, ok)
+	}
+	if _, ok := m.Lookup("d"); ok {
+		t.Errorf("LeaseManager.Lookup(d) = true, want false")
+	}
+	if got := len(m.Leases()); got != 3 {
+		t.Errorf("len(LeaseManager.Leases()) = %d, want 3", got)
+	}
+}
+
+func TestLeaseManagerSticky(t *testing.T) {
+	m, _ := newTestLeaseManager(t, "10.0.0.0/29")
+
+	a, _ := m.Acquire("a", ipx.IP{})
+	m.Acquire("b", ipx.IP{})
+	if err := m.Release("a", a.IP); err != nil {
+		t.Fatalf("LeaseManager.Release(a) error = %v", err)
+	}
+	if err := m.Release("a", a.IP); err != ErrNoLease {
+		t.Errorf("LeaseManager.Release(a) twice error = %v, want %v", err, ErrNoLease)
+	}
+
+	m.Acquire("c", ipx.IP{})
+	if l, err := m.Acquire("a", ipx.IP{}); err != nil || !l.IP.Equal(a.IP) {
+		t.Errorf("LeaseManager.Acquire(a) after Release = %+v, %v, want %v", l, err, a.IP)
+	}
+}
+
+func TestLeaseManagerStickyPruned(t *testing.T) {
+	m, clock := newTestLeaseManager(t, "10.0.0.0/30")
+
+	// the last address of a is forgotten when it is bound to b
+	a, _ := m.Acquire("a", ipx.IP{})
+	m.Release("a", a.IP)
+	if l, _ := m.Acquire("b", a.IP); !l.IP.Equal(a.IP) {
+		t.Fatalf("LeaseManager.Acquire(b, %v) = %v", a.IP, l.IP)
+	}
+	if len(m.last) != 0 || len(m.lastBy) != 0 {
+		t.Errorf("LeaseManager remembers %v after the address is bound to b", m.last)
+	}
+
+	// the last address of b is forgotten by Sweep after the sticky period
+	m.config.StickyPeriod = time.Hour
+	m.Release("b", a.IP)
+	clock.advance(30 * time.Minute)
+	m.Sweep()
+	if _, ok := m.last["b"]; !ok {
+		t.Errorf("LeaseManager forgot the last address of b in the sticky period")
+	}
+	clock.advance(time.Hour)
+	m.Sweep()
+	if len(m.last) != 0 || len(m.lastBy) != 0 {
+		t.Errorf("LeaseManager remembers %v after the sticky period", m.last)
+	}
+}
+
+func TestLeaseManagerRenew(t *testing.T) {
+	m, clock := newTestLeaseManager(t, "10.0.0.0/29")
+	l, _ := m.Acquire("a", ipx.IP{})
+
+	if _, err := m.Renew("b", l.IP); err != ErrNoLease {
+		t.Errorf("LeaseManager.Renew(b) error = %v, want %v", err, ErrNoLease)
+	}
+
+	// an expired lease can be renewed in its grace period
+	clock.advance(time.Hour + 5*time.Minute)
+	if got, _ := m.Lookup("a"); got.Active(clock.now) {
+		t.Errorf("Lease.Active() after expiry = true, want false")
+	}
+	if got, err := m.Renew("a", l.IP); err != nil || !got.Active(clock.now) || !got.Start.Equal(l.Start) {
+		t.Errorf("LeaseManager.Renew(a) in grace period = %+v, %v", got, err)
+	}
+
+	clock.advance(time.Hour + 10*time.Minute)
+	if _, err := m.Renew("a", l.IP); err != ErrLeaseExpired {
+		t.Errorf("LeaseManager.Renew(a) after grace period error = %v, want %v", err, ErrLeaseExpired)
+	}
+	if _, ok := m.Lookup("a"); ok {
+		t.Errorf("LeaseManager.Lookup(a) after grace period = true, want false")
+	}
+}
+
+func TestLeaseManagerSweep(t *testing.T) {
+	m, clock := newTestLeaseManager(t, "10.0.0.0/30")
+
+	a, _ := m.Acquire("a", ipx.IP{})
+	clock.advance(30 * time.Minute)
+	b, _ := m.Acquire("b", ipx.IP{})
+	if _, err := m.Acquire("c", ipx.IP{}); err != ErrPoolExhausted {
+		t.Errorf("LeaseManager.Acquire(c) error = %v, want %v", err, ErrPoolExhausted)
+	}
+
+	clock.advance(time.Hour)
+	if swept := m.Sweep(); len(swept) != 1 || swept[0].ClientID != "a" {
+		t.Errorf("LeaseManager.Sweep() = %+v, want the lease of a", swept)
+	}
+
+	// the pool is swept when it is exhausted
+	m.Acquire("c", ipx.IP{})
+	clock.advance(30 * time.Minute)
+	if l, err := m.Acquire("d", ipx.IP{}); err != nil || !l.IP.Equal(b.IP) {
+		t.Errorf("LeaseManager.Acquire(d) = %+v, %v, want %v", l, err, b.IP)
+	}
+
+	// the address of a was taken by c
+	if l, err := m.Acquire("a", ipx.IP{}); err != ErrPoolExhausted {
+		t.Errorf("LeaseManager.Acquire(a) = %+v, %v, want %v", l, err, ErrPoolExhausted)
+	}
+	if l, _ := m.Lookup("c"); !l.IP.Equal(a.IP) {
+		t.Errorf("LeaseManager.Lookup(c) = %v, want %v", l.IP, a.IP)
+	}
+}
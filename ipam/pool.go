@@ -0,0 +1,399 @@
+// Package ipam manages the allocation of IP addresses and prefixes
+package ipam
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/hakansa/ipx"
+)
+
+// Error definitions
+var (
+	ErrEmptyPool     = errors.New("pool has no addresses")
+	ErrPoolExhausted = errors.New("pool has no free addresses")
+	ErrNotInPool     = errors.New("ip address is not in the pool")
+	ErrAllocated     = errors.New("ip address is already allocated")
+	ErrNotAllocated  = errors.New("ip address is not allocated")
+	ErrReserved      = errors.New("ip address is reserved")
+)
+
+// Pool allocates the IP addresses of one or more networks or ranges
+// Addresses are handed out in order from the one after the last
+// allocated address, so released addresses are not reused immediately
+// Allocated addresses are kept in a sparse bitmap, so pools can be as
+// large as a /8 IPv4 network or an IPv6 network
+// A Pool is safe for concurrent use
+type Pool struct {
+	mu     sync.Mutex
+	ranges []*poolRange
+	next   int // index of the range the next allocation starts in
+}
+
+// poolRange is a range of consecutive addresses of a Pool
+// Addresses are kept as offsets from the first address
+type poolRange struct {
+	first    ipx.Addr
+	last     ipx.Uint128 // offset of the last address
+	used     bitmap      // allocated offsets
+	reserved []span      // sorted, non-overlapping reserved offsets
+	cursor   ipx.Uint128 // offset the next allocation starts at
+}
+
+// span represents the offsets between first and last, inclusively
+type span struct {
+	first ipx.Uint128
+	last  ipx.Uint128
+}
+
+// Stats holds the usage of a Pool
+type Stats struct {
+	Size      *big.Int // number of addresses
+	Reserved  *big.Int // number of reserved addresses
+	Allocated int      // number of allocated addresses
+	Free      *big.Int // number of addresses that can be allocated
+}
+
+// Utilization returns the ratio of the allocated addresses to the
+// addresses that are not reserved
+func (s Stats) Utilization() float64 {
+	usable := new(big.Int).Sub(s.Size, s.Reserved)
+	if usable.Sign() <= 0 {
+		return 0
+	}
+	u, _ := new(big.Float).Quo(new(big.Float).SetInt64(int64(s.Allocated)), new(big.Float).SetInt(usable)).Float64()
+	return u
+}
+
+// NewPool creates a new Pool with the addresses of given elements
+// Elements can be anything ipx.NewIPSet accepts like *ipx.IPNet,
+// *ipx.IPRange, ipx.IP and *ipx.IPSet
+// It returns ErrEmptyPool if the elements have no addresses
+func NewPool(elems ...interface{}) (*Pool, error) {
+	set, err := ipx.NewIPSet(elems...)
+	if err != nil {
+		return nil, err
+	}
+	if set.IsEmpty() {
+		return nil, ErrEmptyPool
+	}
+
+	p := &Pool{}
+	for _, ipRange := range set.Ranges() {
+		r, _ := ipx.AddrRangeFromIPRange(ipRange)
+		last, _ := r.Last().Uint128().Sub(r.First().Uint128())
+		p.ranges = append(p.ranges, &poolRange{first: r.First(), last: last})
+	}
+	return p, nil
+}
+
+// NewSubnetPool creates a new Pool with the addresses of n
+// The network and broadcast addresses of IPv4 networks up to /30 and the
+// Subnet-Router anycast address of IPv6 networks up to /126 are reserved
+// Use Reserve to reserve the gateway and other addresses
+func NewSubnetPool(n *ipx.IPNet) (*Pool, error) {
+	p, err := NewPool(n)
+	if err != nil {
+		return nil, err
+	}
+
+	ones := n.NetworkSize()
+	switch {
+	case n.IP.IsV4() && ones <= 30:
+		err = p.Reserve(n.FirstIP(), n.LastIP())
+	case !n.IP.IsV4() && ones <= 126:
+		err = p.Reserve(n.FirstIP())
+	}
+	return p, err
+}
+
+// Allocate allocates a free address
+// It returns ErrPoolExhausted if there is no free address
+func (p *Pool) Allocate() (ipx.IP, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// search from the cursor to the end of its range, then the other
+	// ranges, then from the start of the first range to the cursor
+	n := len(p.ranges)
+	for k := 0; k <= n; k++ {
+		i := (p.next + k) % n
+		r := p.ranges[i]
+
+		from, to := ipx.Uint128{}, r.last
+		switch {
+		case k == 0:
+			from = r.cursor
+		case k == n && r.cursor.IsZero():
+			return ipx.IP{}, ErrPoolExhausted
+		case k == n:
+			to, _ = r.cursor.Sub(ipx.NewUint128(1))
+		}
+
+		if off, ok := r.findFree(from, to); ok {
+			r.used.set(off)
+			r.cursor, _ = off.Add(ipx.NewUint128(1))
+			p.next = i
+			if off == r.last {
+				r.cursor = ipx.Uint128{}
+				p.next = (i + 1) % n
+			}
+			return r.addr(off), nil
+		}
+	}
+	return ipx.IP{}, ErrPoolExhausted
+}
+
+// AllocateSpecific allocates ip
+// It returns ErrNotInPool if ip is not in the pool, ErrReserved if
+// ip is reserved and ErrAllocated if ip is already allocated
+func (p *Pool) AllocateSpecific(ip ipx.IP) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r, off, ok := p.find(ip)
+	switch {
+	case !ok:
+		return ErrNotInPool
+	case r.isReserved(off):
+		return ErrReserved
+	case !r.used.set(off):
+		return ErrAllocated
+	}
+	return nil
+}
+
+// Release releases the allocated ip
+// It returns ErrNotInPool if ip is not in the pool
+// and ErrNotAllocated if ip is not allocated
+func (p *Pool) Release(ip ipx.IP) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r, off, ok := p.find(ip)
+	switch {
+	case !ok:
+		return ErrNotInPool
+	case !r.used.clear(off):
+		return ErrNotAllocated
+	}
+	return nil
+}
+
+// Reserve reserves the addresses of given elements, like a gateway or
+// custom excludes, so they are not allocated
+// Elements can be anything ipx.NewIPSet accepts
+// Addresses that are not in the pool are ignored
+// It returns ErrAllocated if any of the addresses is allocated,
+// in which case nothing is reserved
+func (p *Pool) Reserve(elems ...interface{}) error {
+	set, err := ipx.NewIPSet(elems...)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	type reservation struct {
+		r *poolRange
+		s span
+	}
+	var reservations []reservation
+	for _, r := range p.ranges {
+		last := r.first.NextN(r.last)
+		overlap := set.Intersect(ipx.MustNewIPSet(ipx.AddrRangeFrom(r.first, last)))
+		for _, ipRange := range overlap.Ranges() {
+			ar, _ := ipx.AddrRangeFromIPRange(ipRange)
+			s := span{r.offset(ar.First()), r.offset(ar.Last())}
+			if r.used.anySet(s.first, s.last) {
+				return ErrAllocated
+			}
+			reservations = append(reservations, reservation{r, s})
+		}
+	}
+
+	for _, res := range reservations {
+		res.r.reserve(res.s)
+	}
+	return nil
+}
+
+// Contains reports whether ip is in the pool
+func (p *Pool) Contains(ip ipx.IP) bool {
+	_, _, ok := p.find(ip)
+	return ok
+}
+
+// IsAllocated reports whether ip is allocated
+func (p *Pool) IsAllocated(ip ipx.IP) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r, off, ok := p.find(ip)
+	return ok && r.used.get(off)
+}
+
+// IsReserved reports whether ip is reserved
+func (p *Pool) IsReserved(ip ipx.IP) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r, off, ok := p.find(ip)
+	return ok && r.isReserved(off)
+}
+
+// Allocations returns the allocated addresses in ascending order
+func (p *Pool) Allocations() []ipx.IP {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var ips []ipx.IP
+	for _, r := range p.ranges {
+		for _, off := range r.used.offsets() {
+			ips = append(ips, r.addr(off))
+		}
+	}
+	return ips
+}
+
+// Stats returns the usage of the pool
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := Stats{Size: new(big.Int), Reserved: new(big.Int)}
+	one := big.NewInt(1)
+	unreserved := 0 // allocated addresses that are not reserved
+	for _, r := range p.ranges {
+		s.Size.Add(s.Size, r.last.BigInt()).Add(s.Size, one)
+		unreserved += r.used.count
+		for _, res := range r.reserved {
+			size, _ := res.last.Sub(res.first)
+			s.Reserved.Add(s.Reserved, size.BigInt()).Add(s.Reserved, one)
+			unreserved -= r.used.countSet(res.first, res.last)
+		}
+		s.Allocated += r.used.count
+	}
+	s.Free = new(big.Int).Sub(s.Size, s.Reserved)
+	s.Free.Sub(s.Free, big.NewInt(int64(unreserved)))
+	return s
+}
+
+// load replaces the allocated addresses with ips
+// Addresses that are not in the pool are ignored and reserved
+// addresses are loaded as allocated, so they can be released
+func (p *Pool) load(ips []ipx.IP) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, r := range p.ranges {
+		r.used = bitmap{}
+	}
+	for _, ip := range ips {
+		if r, off, ok := p.find(ip); ok {
+			r.used.set(off)
+		}
+	}
+}
+
+// mark marks ip as allocated even if it is reserved
+// Addresses that are not in the pool are ignored
+func (p *Pool) mark(ip ipx.IP) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if r, off, ok := p.find(ip); ok {
+		r.used.set(off)
+	}
+}
+
+// find returns the range of the pool that contains ip
+// and the offset of ip in it
+func (p *Pool) find(ip ipx.IP) (*poolRange, ipx.Uint128, bool) {
+	a, ok := ipx.AddrFromIP(ip)
+	if !ok {
+		return nil, ipx.Uint128{}, false
+	}
+
+	// ranges are sorted, find the first one ending at or after a
+	i := sort.Search(len(p.ranges), func(i int) bool {
+		r := p.ranges[i]
+		return r.first.NextN(r.last).Compare(a) >= 0
+	})
+	if i == len(p.ranges) || p.ranges[i].first.Compare(a) > 0 {
+		return nil, ipx.Uint128{}, false
+	}
+	return p.ranges[i], p.ranges[i].offset(a), true
+}
+
+// addr returns the address at off
+func (r *poolRange) addr(off ipx.Uint128) ipx.IP {
+	return r.first.NextN(off).IP()
+}
+
+// offset returns the offset of a
+func (r *poolRange) offset(a ipx.Addr) ipx.Uint128 {
+	off, _ := a.Uint128().Sub(r.first.Uint128())
+	return off
+}
+
+// findFree returns the first offset between from and to, inclusively,
+// that is neither allocated nor reserved
+func (r *poolRange) findFree(from, to ipx.Uint128) (ipx.Uint128, bool) {
+	for from.Cmp(to) <= 0 {
+		off, ok := r.used.nextClear(from, to)
+		if !ok {
+			return ipx.Uint128{}, false
+		}
+		s, ok := r.reservedSpan(off)
+		if !ok {
+			return off, true
+		}
+		if s.last == r.last {
+			return ipx.Uint128{}, false
+		}
+		from, _ = s.last.Add(ipx.NewUint128(1))
+	}
+	return ipx.Uint128{}, false
+}
+
+// isReserved reports whether off is reserved
+func (r *poolRange) isReserved(off ipx.Uint128) bool {
+	_, ok := r.reservedSpan(off)
+	return ok
+}
+
+// reservedSpan returns the reserved span that contains off
+func (r *poolRange) reservedSpan(off ipx.Uint128) (span, bool) {
+	i := sort.Search(len(r.reserved), func(i int) bool {
+		return r.reserved[i].last.Cmp(off) >= 0
+	})
+	if i == len(r.reserved) || r.reserved[i].first.Cmp(off) > 0 {
+		return span{}, false
+	}
+	return r.reserved[i], true
+}
+
+// reserve adds s to the reserved spans
+// Overlapping and adjacent spans are merged
+func (r *poolRange) reserve(s span) {
+	spans := append(r.reserved, s)
+	sort.Slice(spans, func(i, j int) bool { return spans[i].first.Cmp(spans[j].first) < 0 })
+
+	merged := spans[:1]
+	for _, s := range spans[1:] {
+		last := &merged[len(merged)-1]
+		next, carry := last.last.Add(ipx.NewUint128(1))
+		if carry || s.first.Cmp(next) <= 0 {
+			if s.last.Cmp(last.last) > 0 {
+				last.last = s.last
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	r.reserved = merged
+}
@@ -0,0 +1,226 @@
+package ipam
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/hakansa/ipx"
+)
+
+func TestPoolAllocate(t *testing.T) {
+	p, err := NewSubnetPool(ipx.MustParseCIDR("10.0.0.0/29"))
+	if err != nil {
+		t.Fatalf("NewSubnetPool() = %v", err)
+	}
+	if err := p.Reserve(ipx.MustParseIP("10.0.0.1")); err != nil {
+		t.Fatalf("Pool.Reserve() = %v", err)
+	}
+
+	var got []string
+	for {
+		ip, err := p.Allocate()
+		if err != nil {
+			if err != ErrPoolExhausted {
+				t.Errorf("Pool.Allocate() = %v, want %v", err, ErrPoolExhausted)
+			}
+			break
+		}
+		got = append(got, ip.String())
+	}
+	want := []string{"10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6"}
+	if len(got) != len(want) {
+		t.Fatalf("Pool.Allocate() = %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("Pool.Allocate() = %v, want %v", got, want)
+			break
+		}
+	}
+
+	// released addresses are allocated again after the cursor wraps
+	if err := p.Release(ipx.MustParseIP("10.0.0.3")); err != nil {
+		t.Errorf("Pool.Release(10.0.0.3) = %v", err)
+	}
+	if ip, err := p.Allocate(); err != nil || ip.String() != "10.0.0.3" {
+		t.Errorf("Pool.Allocate() = %v, %v, want 10.0.0.3", ip, err)
+	}
+}
+
+func TestPoolCursor(t *testing.T) {
+	p, _ := NewPool(ipx.MustParseCIDR("192.168.0.0/30"), ipx.MustParseCIDR("2001:db8::/126"))
+
+	want := []string{"192.168.0.0", "192.168.0.1", "192.168.0.2", "192.168.0.3", "2001:db8::", "2001:db8::1"}
+	for _, w := range want {
+		if ip, err := p.Allocate(); err != nil || ip.String() != w {
+			t.Errorf("Pool.Allocate() = %v, %v, want %v", ip, err, w)
+		}
+	}
+
+	// released addresses are not reused before the rest of the pool
+	p.Release(ipx.MustParseIP("192.168.0.1"))
+	for _, w := range []string{"2001:db8::2", "2001:db8::3", "192.168.0.1"} {
+		if ip, err := p.Allocate(); err != nil || ip.String() != w {
+			t.Errorf("Pool.Allocate() = %v, %v, want %v", ip, err, w)
+		}
+	}
+}
+
+var poolErrorTests = []struct {
+	op  string
+	ip  string
+	err error
+}{
+	{"allocate", "10.0.0.5", nil},
+	{"allocate", "10.0.0.5", ErrAllocated},
+	{"allocate", "10.0.0.0", ErrReserved},
+	{"allocate", "10.0.0.255", ErrReserved},
+	{"allocate", "10.0.0.100", ErrReserved},
+	{"allocate", "10.0.1.1", ErrNotInPool},
+	{"allocate", "::ffff:10.0.0.6", nil},
+	{"release", "10.0.0.6", nil},
+	{"release", "10.0.0.6", ErrNotAllocated},
+	{"release", "10.0.0.0", ErrNotAllocated},
+	{"release", "192.168.0.1", ErrNotInPool},
+	{"reserve", "10.0.0.5", ErrAllocated},
+	{"reserve", "10.0.0.7", nil},
+	{"allocate", "10.0.0.7", ErrReserved},
+}
+
+func TestPoolErrors(t *testing.T) {
+	p, _ := NewSubnetPool(ipx.MustParseCIDR("10.0.0.0/24"))
+	p.Reserve(ipx.MustParseIPRangeInclusive("10.0.0.100", "10.0.0.110"))
+
+	for _, tt := range poolErrorTests {
+		var err error
+		ip := ipx.MustParseIP(tt.ip)
+		switch tt.op {
+		case "allocate":
+			err = p.AllocateSpecific(ip)
+		case "release":
+			err = p.Release(ip)
+		case "reserve":
+			err = p.Reserve(ip)
+		}
+		if err != tt.err {
+			t.Errorf("Pool %s(%v) = %v, want %v", tt.op, tt.ip, err, tt.err)
+		}
+	}
+}
+
+func TestPoolStats(t *testing.T) {
+	p, _ := NewSubnetPool(ipx.MustParseCIDR("10.0.0.0/24"))
+	p.Reserve(ipx.MustParseIP("10.0.0.1"), ipx.MustParseCIDR("10.0.0.0/30"), ipx.MustParseCIDR("10.1.0.0/16"))
+	for i := 0; i < 63; i++ {
+		p.Allocate()
+	}
+
+	s := p.Stats()
+	if s.Size.Int64() != 256 || s.Reserved.Int64() != 5 || s.Allocated != 63 || s.Free.Int64() != 188 {
+		t.Errorf("Pool.Stats() = %v, %v, %v, %v, want 256, 5, 63, 188", s.Size, s.Reserved, s.Allocated, s.Free)
+	}
+	if u := s.Utilization(); u != 63.0/251 {
+		t.Errorf("Stats.Utilization() = %v, want %v", u, 63.0/251)
+	}
+}
+
+func TestPoolStatsLoad(t *testing.T) {
+	p, _ := NewSubnetPool(ipx.MustParseCIDR("10.0.0.0/30"))
+	p.Reserve(ipx.MustParseIP("10.0.0.1"))
+
+	// reserved addresses in the loaded allocations are counted once
+	p.load([]ipx.IP{ipx.MustParseIP("10.0.0.0"), ipx.MustParseIP("10.0.0.1"), ipx.MustParseIP("10.0.0.2")})
+	s := p.Stats()
+	if s.Size.Int64() != 4 || s.Reserved.Int64() != 3 || s.Allocated != 3 || s.Free.Int64() != 0 {
+		t.Errorf("Pool.Stats() = %v, %v, %v, %v, want 4, 3, 3, 0", s.Size, s.Reserved, s.Allocated, s.Free)
+	}
+
+	// and they can be released
+	if err := p.Release(ipx.MustParseIP("10.0.0.1")); err != nil {
+		t.Errorf("Pool.Release(10.0.0.1) error = %v", err)
+	}
+	if p.IsAllocated(ipx.MustParseIP("10.0.0.1")) || !p.IsReserved(ipx.MustParseIP("10.0.0.1")) {
+		t.Errorf("Pool.IsAllocated(10.0.0.1) after Release = true, want false")
+	}
+	if s := p.Stats(); s.Allocated != 2 || s.Free.Int64() != 0 {
+		t.Errorf("Pool.Stats() after Release = %v, %v, want 2, 0", s.Allocated, s.Free)
+	}
+}
+
+func TestPoolLarge(t *testing.T) {
+	p, err := NewSubnetPool(ipx.MustParseCIDR("10.0.0.0/8"))
+	if err != nil {
+		t.Fatalf("NewSubnetPool() = %v", err)
+	}
+	for i := 0; i < 10000; i++ {
+		if _, err := p.Allocate(); err != nil {
+			t.Fatalf("Pool.Allocate() = %v", err)
+		}
+	}
+	if ip, _ := p.Allocate(); ip.String() != "10.0.39.17" {
+		t.Errorf("Pool.Allocate() = %v, want 10.0.39.17", ip)
+	}
+	if err := p.AllocateSpecific(ipx.MustParseIP("10.255.255.254")); err != nil {
+		t.Errorf("Pool.AllocateSpecific(10.255.255.254) = %v", err)
+	}
+
+	// an IPv6 /32 pool keeps only the chunks in use
+	p, _ = NewSubnetPool(ipx.MustParseCIDR("2001:db8::/32"))
+	for _, s := range []string{"2001:db8::1", "2001:db8:ffff:ffff:ffff:ffff:ffff:ffff", "2001:db8:1234::"} {
+		if err := p.AllocateSpecific(ipx.MustParseIP(s)); err != nil {
+			t.Errorf("Pool.AllocateSpecific(%v) = %v", s, err)
+		}
+	}
+	if ip, _ := p.Allocate(); ip.String() != "2001:db8::2" {
+		t.Errorf("Pool.Allocate() = %v, want 2001:db8::2", ip)
+	}
+	size := new(big.Int).Lsh(big.NewInt(1), 96)
+	if s := p.Stats(); s.Size.Cmp(size) != 0 || s.Allocated != 4 {
+		t.Errorf("Pool.Stats() = %v, %v, want %v, 4", s.Size, s.Allocated, size)
+	}
+	if got := p.Allocations(); len(got) != 4 || got[3].String() != "2001:db8:ffff:ffff:ffff:ffff:ffff:ffff" {
+		t.Errorf("Pool.Allocations() = %v", got)
+	}
+}
+
+func TestPoolConcurrent(t *testing.T) {
+	p, _ := NewPool(ipx.MustParseCIDR("10.0.0.0/22"))
+
+	var mu sync.Mutex
+	seen := map[string]bool{}
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 128; i++ {
+				ip, err := p.Allocate()
+				if err != nil {
+					t.Errorf("Pool.Allocate() = %v", err)
+					return
+				}
+				mu.Lock()
+				if seen[ip.String()] {
+					t.Errorf("Pool.Allocate() = %v twice", ip)
+				}
+				seen[ip.String()] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := p.Allocate(); err != ErrPoolExhausted {
+		t.Errorf("Pool.Allocate() = %v, want %v", err, ErrPoolExhausted)
+	}
+}
+
+func TestNewPool(t *testing.T) {
+	if _, err := NewPool(); err != ErrEmptyPool {
+		t.Errorf("NewPool() = %v, want %v", err, ErrEmptyPool)
+	}
+	if _, err := NewPool("10.0.0.0/8"); err != ipx.ErrInvalidIPSetElement {
+		t.Errorf("NewPool(string) = %v, want %v", err, ipx.ErrInvalidIPSetElement)
+	}
+}
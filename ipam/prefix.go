@@ -0,0 +1,302 @@
+package ipam
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/hakansa/ipx"
+)
+
+// Error definitions
+var (
+	ErrInvalidNetwork     = errors.New("invalid ip network")
+	ErrNoFreePrefix       = errors.New("pool has no free prefix of the requested length")
+	ErrPrefixNotInPool    = errors.New("prefix is not in the pool")
+	ErrPrefixAllocated    = errors.New("prefix overlaps an allocated prefix")
+	ErrPrefixNotAllocated = errors.New("prefix is not allocated")
+)
+
+// PrefixPool allocates the child prefixes of a network, like the /24
+// networks of a /16 network or the /56 networks of a /48 network
+//
+// It is a buddy allocator, the free space is kept as blocks which are
+// prefixes of the network
+// A prefix is allocated from the smallest free block it fits in, which
+// is split in halves until one of them has the requested length
+// Released prefixes are merged with their free buddies, the other half
+// of the prefix one bit shorter, so free space does not stay split
+// A PrefixPool is safe for concurrent use
+type PrefixPool struct {
+	mu        sync.Mutex
+	root      ipx.Prefix
+	free      [][]ipx.Prefix // sorted free blocks by prefix length
+	allocated map[ipx.Prefix]struct{}
+}
+
+// PrefixStats holds the usage of a PrefixPool
+type PrefixStats struct {
+	Size      *big.Int // number of addresses
+	Free      *big.Int // number of addresses in free blocks
+	Allocated int      // number of allocated prefixes
+
+	// LargestFree is the largest free block, or nil if there is none
+	// It is the shortest prefix that can be allocated
+	LargestFree *ipx.IPNet
+
+	// Fragmentation is the ratio of the free addresses that are not in
+	// the largest free block, 0 if the free space is in one block
+	Fragmentation float64
+}
+
+// NewPrefixPool creates a new PrefixPool with the child prefixes of n
+// It returns ErrInvalidNetwork if n is not a network in CIDR notation
+func NewPrefixPool(n *ipx.IPNet) (*PrefixPool, error) {
+	root, ok := ipx.PrefixFromIPNet(n)
+	if !ok {
+		return nil, ErrInvalidNetwork
+	}
+
+	p := &PrefixPool{
+		root:      root,
+		free:      make([][]ipx.Prefix, root.Addr().BitLen()+1),
+		allocated: map[ipx.Prefix]struct{}{},
+	}
+	p.addFree(root)
+	return p, nil
+}
+
+// Network returns the network the prefixes are allocated from
+func (p *PrefixPool) Network() *ipx.IPNet {
+	return p.root.IPNet()
+}
+
+// Allocate allocates a free prefix whose prefix length is prefixLen
+// The prefix is taken from the smallest free block it fits in, and the
+// lowest block of that size
+// It returns ipx.ErrInvalidPrefixLen if prefixLen is shorter than the
+// prefix length of the network or longer than the address length,
+// and ErrNoFreePrefix if there is no free block large enough
+func (p *PrefixPool) Allocate(prefixLen int) (*ipx.IPNet, error) {
+	if prefixLen < p.root.Bits() || prefixLen >= len(p.free) {
+		return nil, ipx.ErrInvalidPrefixLen
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for bits := prefixLen; bits >= p.root.Bits(); bits-- {
+		if len(p.free[bits]) == 0 {
+			continue
+		}
+		block := p.free[bits][0]
+		p.removeFree(block)
+		for block.Bits() < prefixLen {
+			lower, upper := halves(block)
+			p.addFree(upper)
+			block = lower
+		}
+		p.allocated[block] = struct{}{}
+		return block.IPNet(), nil
+	}
+	return nil, ErrNoFreePrefix
+}
+
+// AllocateSpecific allocates the prefix n
+// It returns ErrPrefixNotInPool if n is not a child prefix of the
+// network and ErrPrefixAllocated if n overlaps an allocated prefix
+func (p *PrefixPool) AllocateSpecific(n *ipx.IPNet) error {
+	prefix, err := p.child(n)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// find the free block containing prefix and split off prefix
+	for bits := prefix.Bits(); bits >= p.root.Bits(); bits-- {
+		block := ipx.PrefixFrom(prefix.Addr(), bits)
+		if !p.isFree(block) {
+			continue
+		}
+		p.removeFree(block)
+		for block.Bits() < prefix.Bits() {
+			lower, upper := halves(block)
+			if lower.Contains(prefix.Addr()) {
+				p.addFree(upper)
+				block = lower
+			} else {
+				p.addFree(lower)
+				block = upper
+			}
+		}
+		p.allocated[block] = struct{}{}
+		return nil
+	}
+	return ErrPrefixAllocated
+}
+
+// Release releases the allocated prefix n and merges it with
+// the free blocks next to it
+// It returns ErrPrefixNotInPool if n is not a child prefix of the
+// network and ErrPrefixNotAllocated if n is not allocated
+func (p *PrefixPool) Release(n *ipx.IPNet) error {
+	prefix, err := p.child(n)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.allocated[prefix]; !ok {
+		return ErrPrefixNotAllocated
+	}
+	delete(p.allocated, prefix)
+
+	for prefix.Bits() > p.root.Bits() {
+		b := buddy(prefix)
+		if !p.isFree(b) {
+			break
+		}
+		p.removeFree(b)
+		prefix = ipx.PrefixFrom(prefix.Addr(), prefix.Bits()-1)
+	}
+	p.addFree(prefix)
+	return nil
+}
+
+// IsAllocated reports whether n is an allocated prefix
+func (p *PrefixPool) IsAllocated(n *ipx.IPNet) bool {
+	prefix, ok := ipx.PrefixFromIPNet(n)
+	if !ok {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, ok = p.allocated[prefix]
+	return ok
+}
+
+// Allocations returns the allocated prefixes in ascending order
+func (p *PrefixPool) Allocations() []*ipx.IPNet {
+	p.mu.Lock()
+	prefixes := make([]ipx.Prefix, 0, len(p.allocated))
+	for prefix := range p.allocated {
+		prefixes = append(prefixes, prefix)
+	}
+	p.mu.Unlock()
+
+	sort.Slice(prefixes, func(i, j int) bool { return prefixes[i].Addr().Less(prefixes[j].Addr()) })
+	nets := make([]*ipx.IPNet, len(prefixes))
+	for i, prefix := range prefixes {
+		nets[i] = prefix.IPNet()
+	}
+	return nets
+}
+
+// FreeBlocks returns the free blocks in ascending order
+func (p *PrefixPool) FreeBlocks() []*ipx.IPNet {
+	p.mu.Lock()
+	var prefixes []ipx.Prefix
+	for _, blocks := range p.free {
+		prefixes = append(prefixes, blocks...)
+	}
+	p.mu.Unlock()
+
+	sort.Slice(prefixes, func(i, j int) bool { return prefixes[i].Addr().Less(prefixes[j].Addr()) })
+	nets := make([]*ipx.IPNet, len(prefixes))
+	for i, prefix := range prefixes {
+		nets[i] = prefix.IPNet()
+	}
+	return nets
+}
+
+// Stats returns the usage of the pool
+func (p *PrefixPool) Stats() PrefixStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := PrefixStats{
+		Size:      blockSize(p.root),
+		Free:      new(big.Int),
+		Allocated: len(p.allocated),
+	}
+	var largest *big.Int
+	for _, blocks := range p.free {
+		for _, block := range blocks {
+			size := blockSize(block)
+			if largest == nil {
+				largest = size
+				s.LargestFree = block.IPNet()
+			}
+			s.Free.Add(s.Free, size)
+		}
+	}
+	if largest != nil {
+		r := new(big.Float).Quo(new(big.Float).SetInt(largest), new(big.Float).SetInt(s.Free))
+		f, _ := r.Float64()
+		s.Fragmentation = 1 - f
+	}
+	return s
+}
+
+// child returns the Prefix of n
+// It returns ErrPrefixNotInPool if n is not a child prefix of the network
+func (p *PrefixPool) child(n *ipx.IPNet) (ipx.Prefix, error) {
+	prefix, ok := ipx.PrefixFromIPNet(n)
+	if !ok || prefix.Bits() < p.root.Bits() || !p.root.Contains(prefix.Addr()) {
+		return ipx.Prefix{}, ErrPrefixNotInPool
+	}
+	return prefix, nil
+}
+
+// isFree reports whether block is a free block
+func (p *PrefixPool) isFree(block ipx.Prefix) bool {
+	blocks := p.free[block.Bits()]
+	i := sort.Search(len(blocks), func(i int) bool { return !blocks[i].Addr().Less(block.Addr()) })
+	return i < len(blocks) && blocks[i] == block
+}
+
+// addFree adds block to the free blocks
+func (p *PrefixPool) addFree(block ipx.Prefix) {
+	blocks := p.free[block.Bits()]
+	i := sort.Search(len(blocks), func(i int) bool { return !blocks[i].Addr().Less(block.Addr()) })
+	blocks = append(blocks, ipx.Prefix{})
+	copy(blocks[i+1:], blocks[i:])
+	blocks[i] = block
+	p.free[block.Bits()] = blocks
+}
+
+// removeFree removes block from the free blocks
+func (p *PrefixPool) removeFree(block ipx.Prefix) {
+	blocks := p.free[block.Bits()]
+	i := sort.Search(len(blocks), func(i int) bool { return !blocks[i].Addr().Less(block.Addr()) })
+	p.free[block.Bits()] = append(blocks[:i], blocks[i+1:]...)
+}
+
+// halves returns the lower and the upper half of block
+func halves(block ipx.Prefix) (ipx.Prefix, ipx.Prefix) {
+	lower := ipx.PrefixFrom(block.Addr(), block.Bits()+1)
+	upper := ipx.PrefixFrom(lower.LastAddr().Next(), block.Bits()+1)
+	return lower, upper
+}
+
+// buddy returns the other half of the prefix one bit shorter
+// than block
+func buddy(block ipx.Prefix) ipx.Prefix {
+	lower, upper := halves(ipx.PrefixFrom(block.Addr(), block.Bits()-1))
+	if lower == block {
+		return upper
+	}
+	return lower
+}
+
+// blockSize returns the number of addresses in block
+func blockSize(block ipx.Prefix) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(block.Addr().BitLen()-block.Bits()))
+}
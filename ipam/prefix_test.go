@@ -0,0 +1,138 @@
+package ipam
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hakansa/ipx"
+)
+
+func netStrings(nets []*ipx.IPNet) string {
+	s := make([]string, len(nets))
+	for i, n := range nets {
+		s[i] = n.String()
+	}
+	return strings.Join(s, " ")
+}
+
+func TestPrefixPoolAllocate(t *testing.T) {
+	p, err := NewPrefixPool(ipx.MustParseCIDR("10.0.0.0/16"))
+	if err != nil {
+		t.Fatalf("NewPrefixPool() error = %v", err)
+	}
+
+	for _, tt := range []struct {
+		prefixLen int
+		out       string
+		err       error
+	}{
+		{24, "10.0.0.0/24", nil},
+		{18, "10.0.64.0/18", nil},
+		{24, "10.0.1.0/24", nil},
+		{17, "10.0.128.0/17", nil},
+		{17, "", ErrNoFreePrefix},
+		{15, "", ipx.ErrInvalidPrefixLen},
+		{33, "", ipx.ErrInvalidPrefixLen},
+		// best fit takes the /23 block instead of splitting the /22
+		{23, "10.0.2.0/23", nil},
+		{32, "10.0.4.0/32", nil},
+	} {
+		out, err := p.Allocate(tt.prefixLen)
+		if err != tt.err || err == nil && out.String() != tt.out {
+			t.Errorf("PrefixPool.Allocate(%d) = %v, %v, want %v, %v", tt.prefixLen, out, err, tt.out, tt.err)
+		}
+	}
+
+	got := netStrings(p.Allocations())
+	want := "10.0.0.0/24 10.0.1.0/24 10.0.2.0/23 10.0.4.0/32 10.0.64.0/18 10.0.128.0/17"
+	if got != want {
+		t.Errorf("PrefixPool.Allocations() = %v, want %v", got, want)
+	}
+}
+
+func TestPrefixPoolRelease(t *testing.T) {
+	p, _ := NewPrefixPool(ipx.MustParseCIDR("2001:db8::/48"))
+
+	var nets []*ipx.IPNet
+	for i := 0; i < 4; i++ {
+		n, err := p.Allocate(56)
+		if err != nil {
+			t.Fatalf("PrefixPool.Allocate(56) error = %v", err)
+		}
+		nets = append(nets, n)
+	}
+	if got, want := netStrings(nets), "2001:db8::/56 2001:db8:0:100::/56 2001:db8:0:200::/56 2001:db8:0:300::/56"; got != want {
+		t.Fatalf("PrefixPool.Allocate(56) = %v, want %v", got, want)
+	}
+
+	if err := p.Release(ipx.MustParseCIDR("2001:db8::/55")); err != ErrPrefixNotAllocated {
+		t.Errorf("PrefixPool.Release(/55) error = %v, want %v", err, ErrPrefixNotAllocated)
+	}
+	if err := p.Release(ipx.MustParseCIDR("2001:db9::/56")); err != ErrPrefixNotInPool {
+		t.Errorf("PrefixPool.Release(outside) error = %v, want %v", err, ErrPrefixNotInPool)
+	}
+
+	// released buddies are merged
+	p.Release(nets[1])
+	p.Release(nets[0])
+	if got, want := netStrings(p.FreeBlocks()), "2001:db8::/55 2001:db8:0:400::/54 2001:db8:0:800::/53 2001:db8:0:1000::/52 2001:db8:0:2000::/51 2001:db8:0:4000::/50 2001:db8:0:8000::/49"; got != want {
+		t.Errorf("PrefixPool.FreeBlocks() = %v, want %v", got, want)
+	}
+
+	p.Release(nets[3])
+	p.Release(nets[2])
+	if got, want := netStrings(p.FreeBlocks()), "2001:db8::/48"; got != want {
+		t.Errorf("PrefixPool.FreeBlocks() after releasing all = %v, want %v", got, want)
+	}
+	if err := p.Release(nets[2]); err != ErrPrefixNotAllocated {
+		t.Errorf("PrefixPool.Release() twice error = %v, want %v", err, ErrPrefixNotAllocated)
+	}
+}
+
+func TestPrefixPoolAllocateSpecific(t *testing.T) {
+	p, _ := NewPrefixPool(ipx.MustParseCIDR("10.0.0.0/16"))
+
+	for _, tt := range []struct {
+		in  string
+		err error
+	}{
+		{"10.0.5.0/24", nil},
+		{"10.0.4.0/22", ErrPrefixAllocated},
+		{"10.0.5.128/25", ErrPrefixAllocated},
+		{"10.0.4.0/24", nil},
+		{"10.1.0.0/24", ErrPrefixNotInPool},
+		{"10.0.0.0/15", ErrPrefixNotInPool},
+		{"2001:db8::/64", ErrPrefixNotInPool},
+	} {
+		if err := p.AllocateSpecific(ipx.MustParseCIDR(tt.in)); err != tt.err {
+			t.Errorf("PrefixPool.AllocateSpecific(%v) = %v, want %v", tt.in, err, tt.err)
+		}
+	}
+
+	if !p.IsAllocated(ipx.MustParseCIDR("10.0.5.0/24")) || p.IsAllocated(ipx.MustParseCIDR("10.0.6.0/24")) {
+		t.Errorf("PrefixPool.IsAllocated() is wrong")
+	}
+	if n, err := p.Allocate(23); err != nil || n.String() != "10.0.6.0/23" {
+		t.Errorf("PrefixPool.Allocate(23) = %v, %v, want 10.0.6.0/23", n, err)
+	}
+}
+
+func TestPrefixPoolStats(t *testing.T) {
+	p, _ := NewPrefixPool(ipx.MustParseCIDR("10.0.0.0/24"))
+	if s := p.Stats(); s.Size.Int64() != 256 || s.Free.Int64() != 256 || s.Fragmentation != 0 || s.LargestFree.String() != "10.0.0.0/24" {
+		t.Errorf("PrefixPool.Stats() = %+v", s)
+	}
+
+	p.AllocateSpecific(ipx.MustParseCIDR("10.0.0.64/26"))
+	p.AllocateSpecific(ipx.MustParseCIDR("10.0.0.128/26"))
+	s := p.Stats()
+	if s.Allocated != 2 || s.Free.Int64() != 128 || s.LargestFree.String() != "10.0.0.0/26" || s.Fragmentation != 0.5 {
+		t.Errorf("PrefixPool.Stats() = %+v", s)
+	}
+
+	p.AllocateSpecific(ipx.MustParseCIDR("10.0.0.0/26"))
+	p.AllocateSpecific(ipx.MustParseCIDR("10.0.0.192/26"))
+	if s := p.Stats(); s.Free.Int64() != 0 || s.LargestFree != nil || s.Fragmentation != 0 {
+		t.Errorf("PrefixPool.Stats() of a full pool = %+v", s)
+	}
+}
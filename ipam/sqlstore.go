@@ -0,0 +1,191 @@
+package ipam
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+
+	"github.com/hakansa/ipx"
+)
+
+// Error definitions
+var (
+	ErrInvalidTableName = errors.New("invalid table name")
+)
+
+// SQLStore is a Store kept in a database/sql database like an embedded
+// SQLite database, which can be shared by multiple processes
+//
+// The allocated addresses of each pool are rows of a table, and the
+// version of the state of each pool is a row of a second table whose
+// name has the "_versions" suffix, which NewSQLStore creates
+// Each Tx is a database transaction that first increments the version,
+// so updates of a pool are serialized by the database
+// Queries use "?" placeholders as SQLite and MySQL do
+type SQLStore struct {
+	db       *sql.DB
+	table    string
+	versions string
+	pool     string
+}
+
+// NewSQLStore creates a new SQLStore for the pool named pool in the
+// table of db, creating the tables and the version row of the pool
+// if necessary
+// It returns ErrInvalidTableName if table is not a valid SQL identifier
+func NewSQLStore(db *sql.DB, table, pool string) (*SQLStore, error) {
+	if !isIdentifier(table) {
+		return nil, ErrInvalidTableName
+	}
+
+	s := &SQLStore{db: db, table: table, versions: table + "_versions", pool: pool}
+	for _, query := range []string{
+		"CREATE TABLE IF NOT EXISTS " + s.table + " (pool VARCHAR(255) NOT NULL, ip VARCHAR(64) NOT NULL, PRIMARY KEY (pool, ip))",
+		"CREATE TABLE IF NOT EXISTS " + s.versions + " (pool VARCHAR(255) NOT NULL PRIMARY KEY, version BIGINT NOT NULL)",
+	} {
+		if _, err := db.Exec(query); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.createVersion(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// createVersion inserts the version row of the pool if it does not exist
+// The insert fails with a duplicate key error if the row exists, which
+// is ignored once the row is found, as there is no portable statement
+// to insert a row only if it does not exist
+func (s *SQLStore) createVersion() error {
+	_, err := s.db.Exec("INSERT INTO "+s.versions+" (pool, version) VALUES (?, 0)", s.pool)
+	if err == nil {
+		return nil
+	}
+	var version int64
+	if s.db.QueryRow("SELECT version FROM "+s.versions+" WHERE pool = ?", s.pool).Scan(&version) == nil {
+		return nil
+	}
+	return err
+}
+
+// Update implements the Store interface
+// A Tx without changes is rolled back, so it does not change the version
+func (s *SQLStore) Update(fn func(tx Tx) error) error {
+	dbTx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	// the first statement is a write, so the transaction holds
+	// the write lock from the start
+	if _, err := dbTx.Exec("UPDATE "+s.versions+" SET version = version + 1 WHERE pool = ?", s.pool); err != nil {
+		return err
+	}
+
+	var version int64
+	if err := dbTx.QueryRow("SELECT version FROM "+s.versions+" WHERE pool = ?", s.pool).Scan(&version); err != nil {
+		return err
+	}
+
+	tx := &sqlTx{s: s, tx: dbTx, version: uint64(version - 1)}
+	if err := fn(tx); err != nil || !tx.changed {
+		return err
+	}
+	return dbTx.Commit()
+}
+
+// Close implements the Store interface
+// It does not close the database
+func (s *SQLStore) Close() error {
+	return nil
+}
+
+// isIdentifier reports whether s is a valid unquoted SQL identifier
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// sqlTx is a Tx of a SQLStore
+type sqlTx struct {
+	s       *SQLStore
+	tx      *sql.Tx
+	version uint64
+	changed bool
+}
+
+// Version implements the Tx interface
+func (tx *sqlTx) Version() uint64 {
+	return tx.version
+}
+
+// Allocations implements the Tx interface
+func (tx *sqlTx) Allocations() ([]ipx.IP, error) {
+	rows, err := tx.tx.Query("SELECT ip FROM "+tx.s.table+" WHERE pool = ?", tx.s.pool)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addrs []ipx.Addr
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		a, err := ipx.ParseAddr(s)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i].Less(addrs[j]) })
+	ips := make([]ipx.IP, len(addrs))
+	for i, a := range addrs {
+		ips[i] = a.IP()
+	}
+	return ips, nil
+}
+
+// Put implements the Tx interface
+func (tx *sqlTx) Put(ip ipx.IP) error {
+	a, ok := ipx.AddrFromIP(ip)
+	if !ok {
+		return ipx.ErrInvalidIP
+	}
+	if _, err := tx.tx.Exec("INSERT INTO "+tx.s.table+" (pool, ip) VALUES (?, ?)", tx.s.pool, a.String()); err != nil {
+		return err
+	}
+	tx.changed = true
+	return nil
+}
+
+// Delete implements the Tx interface
+func (tx *sqlTx) Delete(ip ipx.IP) error {
+	a, ok := ipx.AddrFromIP(ip)
+	if !ok {
+		return ipx.ErrInvalidIP
+	}
+	if _, err := tx.tx.Exec("DELETE FROM "+tx.s.table+" WHERE pool = ? AND ip = ?", tx.s.pool, a.String()); err != nil {
+		return err
+	}
+	tx.changed = true
+	return nil
+}
@@ -0,0 +1,91 @@
+//go:build sqlite
+// +build sqlite
+
+// The SQLStore is tested against a real SQLite database with
+//
+//	go get modernc.org/sqlite && go test -tags sqlite ./ipam
+
+package ipam
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hakansa/ipx"
+	_ "modernc.org/sqlite"
+)
+
+func openSQLiteStore(t *testing.T, path, pool string) *SQLStore {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(10000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	s, err := NewSQLStore(db, "ipam_allocations", pool)
+	if err != nil {
+		t.Fatalf("NewSQLStore() error = %v", err)
+	}
+	return s
+}
+
+func TestSQLiteStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipam.db")
+	s := openSQLiteStore(t, path, "lan")
+
+	err := s.Update(func(tx Tx) error {
+		tx.Put(ipx.MustParseIP("10.0.0.10"))
+		return tx.Put(ipx.MustParseIP("2001:db8::1"))
+	})
+	if err != nil {
+		t.Fatalf("SQLStore.Update() error = %v", err)
+	}
+	err = s.Update(func(tx Tx) error {
+		return tx.Put(ipx.MustParseIP("10.0.0.10"))
+	})
+	if err == nil {
+		t.Errorf("SQLStore.Update() putting an allocated address error = nil")
+	}
+
+	// the version row exists already, so it is kept
+	other := openSQLiteStore(t, path, "lan")
+	if version, got := storedAllocations(t, other); version != 1 || strings.Join(got, ",") != "10.0.0.10,2001:db8::1" {
+		t.Errorf("SQLStore = %d %v, want 1 [10.0.0.10 2001:db8::1]", version, got)
+	}
+	if version, got := storedAllocations(t, openSQLiteStore(t, path, "wan")); version != 0 || len(got) != 0 {
+		t.Errorf("other pool = %d %v, want 0 []", version, got)
+	}
+}
+
+func TestSQLiteStoreConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipam.db")
+	pools := []*StoredPool{
+		newTestStoredPool(t, "10.0.0.0/24", openSQLiteStore(t, path, "lan")),
+		newTestStoredPool(t, "10.0.0.0/24", openSQLiteStore(t, path, "lan")),
+	}
+
+	// the first writes of both stores do not race to create the version row
+	var wg sync.WaitGroup
+	for _, p := range pools {
+		wg.Add(1)
+		go func(p *StoredPool) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				if _, err := p.Allocate(); err != nil {
+					t.Errorf("StoredPool.Allocate() error = %v", err)
+					return
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	version, got := storedAllocations(t, openSQLiteStore(t, path, "lan"))
+	if version != 40 || len(got) != 40 {
+		t.Errorf("SQLStore = %d with %d addresses, want 40 with 40", version, len(got))
+	}
+}
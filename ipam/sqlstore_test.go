@@ -0,0 +1,295 @@
+package ipam
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hakansa/ipx"
+)
+
+// fakeDB is the state of a database of the fake driver, which
+// understands the queries of SQLStore
+// Transactions are serialized and rolled back from a snapshot
+// Commits fail with failCommit set
+type fakeDB struct {
+	mu         sync.Mutex // held by the open transaction
+	versions   map[string]int64
+	ips        map[string]map[string]bool
+	failCommit error
+}
+
+var (
+	fakeDBsMu sync.Mutex
+	fakeDBs   = map[string]*fakeDB{}
+)
+
+func init() {
+	sql.Register("ipamfake", fakeDriver{})
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeDBsMu.Lock()
+	defer fakeDBsMu.Unlock()
+	db, ok := fakeDBs[name]
+	if !ok {
+		db = &fakeDB{versions: map[string]int64{}, ips: map[string]map[string]bool{}}
+		fakeDBs[name] = db
+	}
+	return &fakeConn{db: db}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	tx := &fakeTx{db: c.db, versions: map[string]int64{}, ips: map[string]map[string]bool{}}
+	for pool, v := range c.db.versions {
+		tx.versions[pool] = v
+	}
+	for pool, ips := range c.db.ips {
+		tx.ips[pool] = map[string]bool{}
+		for ip := range ips {
+			tx.ips[pool][ip] = true
+		}
+	}
+	return tx, nil
+}
+
+// fakeTx holds the snapshot of the database the transaction started with
+type fakeTx struct {
+	db       *fakeDB
+	versions map[string]int64
+	ips      map[string]map[string]bool
+}
+
+func (tx *fakeTx) Commit() error {
+	if err := tx.db.failCommit; err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.db.mu.Unlock()
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.db.versions, tx.db.ips = tx.versions, tx.ips
+	tx.db.mu.Unlock()
+	return nil
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	db := s.db
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE"):
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(s.query, "UPDATE"):
+		pool := args[0].(string)
+		if _, ok := db.versions[pool]; !ok {
+			return driver.RowsAffected(0), nil
+		}
+		db.versions[pool]++
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(s.query, "INSERT") && strings.Contains(s.query, "_versions"):
+		pool := args[0].(string)
+		if _, ok := db.versions[pool]; ok {
+			return nil, errors.New("UNIQUE constraint failed")
+		}
+		db.versions[pool] = 0
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(s.query, "INSERT"):
+		pool, ip := args[0].(string), args[1].(string)
+		if db.ips[pool][ip] {
+			return nil, errors.New("UNIQUE constraint failed")
+		}
+		if db.ips[pool] == nil {
+			db.ips[pool] = map[string]bool{}
+		}
+		db.ips[pool][ip] = true
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(s.query, "DELETE"):
+		delete(db.ips[args[0].(string)], args[1].(string))
+		return driver.RowsAffected(1), nil
+	}
+	return nil, errors.New("unexpected query: " + s.query)
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	pool := args[0].(string)
+	switch {
+	case strings.HasPrefix(s.query, "SELECT version"):
+		v, ok := s.db.versions[pool]
+		if !ok {
+			return &fakeRows{column: "version"}, nil
+		}
+		return &fakeRows{column: "version", values: []driver.Value{v}}, nil
+	case strings.HasPrefix(s.query, "SELECT ip"):
+		rows := &fakeRows{column: "ip"}
+		for ip := range s.db.ips[pool] {
+			rows.values = append(rows.values, ip)
+		}
+		return rows, nil
+	}
+	return nil, errors.New("unexpected query: " + s.query)
+}
+
+type fakeRows struct {
+	column string
+	values []driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{r.column}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+func openTestSQLStore(t *testing.T, pool string) *SQLStore {
+	t.Helper()
+	db, err := sql.Open("ipamfake", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	s, err := NewSQLStore(db, "ipam_allocations", pool)
+	if err != nil {
+		t.Fatalf("NewSQLStore() error = %v", err)
+	}
+	return s
+}
+
+func TestSQLStoreUpdate(t *testing.T) {
+	s := openTestSQLStore(t, "lan")
+
+	err := s.Update(func(tx Tx) error {
+		tx.Put(ipx.MustParseIP("10.0.0.10"))
+		tx.Put(ipx.MustParseIP("10.0.0.9"))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("SQLStore.Update() error = %v", err)
+	}
+
+	// changes of a failed Tx are rolled back
+	errFailed := errors.New("failed")
+	err = s.Update(func(tx Tx) error {
+		tx.Delete(ipx.MustParseIP("10.0.0.9"))
+		return errFailed
+	})
+	if err != errFailed {
+		t.Errorf("SQLStore.Update() error = %v, want %v", err, errFailed)
+	}
+	err = s.Update(func(tx Tx) error {
+		return tx.Put(ipx.MustParseIP("10.0.0.9"))
+	})
+	if err == nil {
+		t.Errorf("SQLStore.Update() putting an allocated address error = nil")
+	}
+
+	if version, got := storedAllocations(t, s); version != 1 || strings.Join(got, ",") != "10.0.0.9,10.0.0.10" {
+		t.Errorf("SQLStore = %d %v, want 1 [10.0.0.9 10.0.0.10]", version, got)
+	}
+
+	// pools sharing the table are separate
+	if version, got := storedAllocations(t, openTestSQLStore(t, "wan")); version != 0 || len(got) != 0 {
+		t.Errorf("other pool = %d %v, want 0 []", version, got)
+	}
+}
+
+func TestNewSQLStoreExistingPool(t *testing.T) {
+	s := openTestSQLStore(t, "lan")
+	if err := s.Update(func(tx Tx) error { return tx.Put(ipx.MustParseIP("10.0.0.1")) }); err != nil {
+		t.Fatalf("SQLStore.Update() error = %v", err)
+	}
+
+	// the version of the pool is kept
+	if version, got := storedAllocations(t, openTestSQLStore(t, "lan")); version != 1 || len(got) != 1 {
+		t.Errorf("SQLStore = %d %v, want 1 [10.0.0.1]", version, got)
+	}
+}
+
+func TestSQLStoreSharedPool(t *testing.T) {
+	a := newTestStoredPool(t, "10.0.0.0/29", openTestSQLStore(t, "lan"))
+	b := newTestStoredPool(t, "10.0.0.0/29", openTestSQLStore(t, "lan"))
+
+	ip, err := a.Allocate()
+	if err != nil {
+		t.Fatalf("StoredPool.Allocate() error = %v", err)
+	}
+	if err := b.AllocateSpecific(ip); err != ErrAllocated {
+		t.Errorf("StoredPool.AllocateSpecific(%v) error = %v, want %v", ip, err, ErrAllocated)
+	}
+	if err := b.Release(ip); err != nil {
+		t.Errorf("StoredPool.Release(%v) error = %v", ip, err)
+	}
+	if err := a.AllocateSpecific(ip); err != nil {
+		t.Errorf("StoredPool.AllocateSpecific(%v) after Release error = %v", ip, err)
+	}
+}
+
+func TestSQLStoreFailedCommit(t *testing.T) {
+	s := openTestSQLStore(t, "lan")
+	p := newTestStoredPool(t, "10.0.0.0/29", s)
+
+	db := fakeDBs[t.Name()]
+	db.failCommit = errors.New("disk I/O error")
+	if _, err := p.Allocate(); err == nil {
+		t.Errorf("StoredPool.Allocate() with a failing commit error = nil")
+	}
+	db.failCommit = nil
+
+	if got := p.Pool().Stats().Allocated; got != 0 {
+		t.Errorf("Stats().Allocated after failed commit = %d, want 0", got)
+	}
+	if version, got := storedAllocations(t, s); version != 0 || len(got) != 0 {
+		t.Errorf("SQLStore = %d %v, want 0 []", version, got)
+	}
+}
+
+func TestNewSQLStoreInvalidTable(t *testing.T) {
+	for _, table := range []string{"", "1ips", "ips; DROP TABLE users", "ip-pool"} {
+		if _, err := NewSQLStore(nil, table, "lan"); err != ErrInvalidTableName {
+			t.Errorf("NewSQLStore(%q) error = %v, want %v", table, err, ErrInvalidTableName)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package ipam
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/hakansa/ipx"
+)
+
+// Error definitions
+var (
+	ErrStoreClosed = errors.New("store is closed")
+)
+
+// Store persists the allocated addresses of a pool
+// Implementations serialize updates, also between processes sharing
+// the same state, so a StoredPool never hands out an address that
+// another process has allocated
+type Store interface {
+	// Update calls fn with a Tx on the latest state and commits the
+	// changes fn records in the Tx atomically
+	// If fn returns an error, the changes are discarded and Update
+	// returns the error
+	Update(fn func(tx Tx) error) error
+
+	// Close releases the resources of the Store
+	Close() error
+}
+
+// Tx is an update of the state in a Store
+type Tx interface {
+	// Version returns the version of the state the Tx started with
+	// The version is increased by each committed Tx that has changes
+	Version() uint64
+
+	// Allocations returns the allocated addresses
+	// the Tx started with
+	Allocations() ([]ipx.IP, error)
+
+	// Put records ip as allocated
+	Put(ip ipx.IP) error
+
+	// Delete records ip as released
+	Delete(ip ipx.IP) error
+}
+
+// StoredPool is a Pool whose allocations are kept in a Store
+// Every change of the pool is committed to the Store, and the pool is
+// reloaded from the Store whenever another StoredPool sharing the
+// Store has changed it
+// A StoredPool is safe for concurrent use
+type StoredPool struct {
+	mu      sync.Mutex
+	pool    *Pool
+	store   Store
+	version uint64 // version of the state pool holds
+	synced  bool   // whether pool holds any version of the state
+}
+
+// NewStoredPool creates a new StoredPool with pool and store
+// and loads the allocations of pool from store
+// Reservations are not stored, so they must be made on pool, and
+// stored allocations of reserved addresses are kept until released
+func NewStoredPool(pool *Pool, store Store) (*StoredPool, error) {
+	p := &StoredPool{pool: pool, store: store}
+	if err := p.Sync(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Pool returns the underlying pool as of the last update
+// Use it for queries like Stats and IsAllocated, changes made on it
+// directly are not stored
+func (p *StoredPool) Pool() *Pool {
+	return p.pool
+}
+
+// Sync loads the allocations made by others from the store
+func (p *StoredPool) Sync() error {
+	return p.update(func(tx Tx) (func(), error) {
+		return nil, nil
+	})
+}
+
+// Allocate allocates a free address and stores it
+// It returns ErrPoolExhausted if there is no free address
+func (p *StoredPool) Allocate() (ipx.IP, error) {
+	var ip ipx.IP
+	err := p.update(func(tx Tx) (func(), error) {
+		var err error
+		if ip, err = p.pool.Allocate(); err != nil {
+			return nil, err
+		}
+		return func() { p.pool.Release(ip) }, tx.Put(ip)
+	})
+	if err != nil {
+		return ipx.IP{}, err
+	}
+	return ip, nil
+}
+
+// AllocateSpecific allocates ip and stores it
+// It returns the errors of Pool.AllocateSpecific
+func (p *StoredPool) AllocateSpecific(ip ipx.IP) error {
+	return p.update(func(tx Tx) (func(), error) {
+		if err := p.pool.AllocateSpecific(ip); err != nil {
+			return nil, err
+		}
+		return func() { p.pool.Release(ip) }, tx.Put(ip)
+	})
+}
+
+// Release releases the allocated ip and stores it
+// It returns the errors of Pool.Release
+func (p *StoredPool) Release(ip ipx.IP) error {
+	return p.update(func(tx Tx) (func(), error) {
+		if err := p.pool.Release(ip); err != nil {
+			return nil, err
+		}
+		return func() { p.pool.mark(ip) }, tx.Delete(ip)
+	})
+}
+
+// update brings the pool up to date and calls fn in a Tx of the store
+// fn returns a function undoing its change of the pool, or nil if it
+// did not change the pool
+// If the update fails after fn changed the pool, like when the commit
+// fails, the change is undone and the pool is reloaded on the next update
+func (p *StoredPool) update(fn func(tx Tx) (func(), error)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var undo func()
+	err := p.store.Update(func(tx Tx) error {
+		if !p.synced || tx.Version() != p.version {
+			ips, err := tx.Allocations()
+			if err != nil {
+				return err
+			}
+			p.pool.load(ips)
+			p.version, p.synced = tx.Version(), true
+		}
+
+		var err error
+		undo, err = fn(tx)
+		return err
+	})
+
+	switch {
+	case err == nil && undo != nil:
+		p.version++
+	case err != nil && undo != nil:
+		undo()
+		p.synced = false
+	}
+	return err
+}
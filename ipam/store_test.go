@@ -0,0 +1,173 @@
+package ipam
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/hakansa/ipx"
+)
+
+// memStore is a Store kept in memory
+// Commits fail with failCommit set
+type memStore struct {
+	mu         sync.Mutex
+	state      map[ipx.Addr]struct{}
+	version    uint64
+	failCommit error
+}
+
+func newMemStore() *memStore {
+	return &memStore{state: map[ipx.Addr]struct{}{}}
+}
+
+func (s *memStore) Update(fn func(tx Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &memTx{s: s}
+	if err := fn(tx); err != nil || len(tx.ops) == 0 {
+		return err
+	}
+	if s.failCommit != nil {
+		return s.failCommit
+	}
+	for _, op := range tx.ops {
+		if op.put {
+			s.state[op.a] = struct{}{}
+		} else {
+			delete(s.state, op.a)
+		}
+	}
+	s.version++
+	return nil
+}
+
+func (s *memStore) Close() error {
+	return nil
+}
+
+type memOp struct {
+	put bool
+	a   ipx.Addr
+}
+
+type memTx struct {
+	s   *memStore
+	ops []memOp
+}
+
+func (tx *memTx) Version() uint64 {
+	return tx.s.version
+}
+
+func (tx *memTx) Allocations() ([]ipx.IP, error) {
+	var addrs []ipx.Addr
+	for a := range tx.s.state {
+		addrs = append(addrs, a)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i].Less(addrs[j]) })
+	ips := make([]ipx.IP, len(addrs))
+	for i, a := range addrs {
+		ips[i] = a.IP()
+	}
+	return ips, nil
+}
+
+func (tx *memTx) Put(ip ipx.IP) error {
+	a, _ := ipx.AddrFromIP(ip)
+	tx.ops = append(tx.ops, memOp{true, a})
+	return nil
+}
+
+func (tx *memTx) Delete(ip ipx.IP) error {
+	a, _ := ipx.AddrFromIP(ip)
+	tx.ops = append(tx.ops, memOp{false, a})
+	return nil
+}
+
+func newTestStoredPool(t *testing.T, cidr string, s Store) *StoredPool {
+	t.Helper()
+	pool, err := NewSubnetPool(ipx.MustParseCIDR(cidr))
+	if err != nil {
+		t.Fatalf("NewSubnetPool(%q) error = %v", cidr, err)
+	}
+	p, err := NewStoredPool(pool, s)
+	if err != nil {
+		t.Fatalf("NewStoredPool() error = %v", err)
+	}
+	return p
+}
+
+func TestStoredPoolShared(t *testing.T) {
+	s := newMemStore()
+	a := newTestStoredPool(t, "10.0.0.0/29", s)
+	b := newTestStoredPool(t, "10.0.0.0/29", s)
+
+	ip, err := a.Allocate()
+	if err != nil {
+		t.Fatalf("StoredPool.Allocate() error = %v", err)
+	}
+	if err := b.AllocateSpecific(ip); err != ErrAllocated {
+		t.Errorf("StoredPool.AllocateSpecific(%v) error = %v, want %v", ip, err, ErrAllocated)
+	}
+	if next, err := b.Allocate(); err != nil || next.Equal(ip) {
+		t.Errorf("StoredPool.Allocate() = %v, %v, want an address other than %v", next, err, ip)
+	}
+
+	if err := b.Release(ip); err != nil {
+		t.Fatalf("StoredPool.Release(%v) error = %v", ip, err)
+	}
+	if !a.Pool().IsAllocated(ip) {
+		t.Errorf("Pool.IsAllocated(%v) before Sync = false, want true", ip)
+	}
+	if err := a.Sync(); err != nil {
+		t.Fatalf("StoredPool.Sync() error = %v", err)
+	}
+	if a.Pool().IsAllocated(ip) {
+		t.Errorf("Pool.IsAllocated(%v) after Sync = true, want false", ip)
+	}
+
+	// a new pool starts with the stored state
+	c := newTestStoredPool(t, "10.0.0.0/29", s)
+	if got := c.Pool().Stats().Allocated; got != 1 {
+		t.Errorf("loaded Stats().Allocated = %d, want 1", got)
+	}
+}
+
+func TestStoredPoolFailedCommit(t *testing.T) {
+	s := newMemStore()
+	p := newTestStoredPool(t, "10.0.0.0/29", s)
+
+	errDisk := errors.New("disk full")
+	s.failCommit = errDisk
+	if _, err := p.Allocate(); err != errDisk {
+		t.Errorf("StoredPool.Allocate() error = %v, want %v", err, errDisk)
+	}
+
+	// the address allocated in memory is not kept, even before a reload
+	if got := p.Pool().Stats().Allocated; got != 0 {
+		t.Errorf("Stats().Allocated after failed commit = %d, want 0", got)
+	}
+	s.failCommit = nil
+	if err := p.Sync(); err != nil {
+		t.Fatalf("StoredPool.Sync() error = %v", err)
+	}
+	if got := p.Pool().Stats().Allocated; got != 0 {
+		t.Errorf("Stats().Allocated after Sync = %d, want 0", got)
+	}
+
+	// nor is the address released in memory
+	ip, err := p.Allocate()
+	if err != nil {
+		t.Fatalf("StoredPool.Allocate() error = %v", err)
+	}
+	s.failCommit = errDisk
+	if err := p.Release(ip); err != errDisk {
+		t.Errorf("StoredPool.Release(%v) error = %v, want %v", ip, err, errDisk)
+	}
+	if !p.Pool().IsAllocated(ip) {
+		t.Errorf("Pool.IsAllocated(%v) after failed commit = false, want true", ip)
+	}
+}
@@ -1,11 +1,17 @@
 package ipx
 
 import (
+	"encoding/binary"
+	"math/big"
+	"math/bits"
 	"math/rand"
 	"net"
 	"strconv"
 )
 
+// maxInt is the maximum value of an int
+const maxInt = int(^uint(0) >> 1)
+
 var privateNetworks = []*IPNet{
 	MustParseCIDR("10.0.0.0/8"),         // RFC1918
 	MustParseCIDR("172.16.0.0/12"),      // private
@@ -18,7 +24,7 @@ var privateNetworks = []*IPNet{
 	MustParseCIDR("198.51.100.0/24"),    // Assigned as TEST-NET-2
 	MustParseCIDR("203.0.113.0/24"),     // Assigned as TEST-NET-3
 	MustParseCIDR("192.88.99.0/24"),     // RFC 3068
-	MustParseCIDR("192.18.0.0/15"),      // RFC 2544
+	MustParseCIDR("198.18.0.0/15"),      // RFC 2544
 	MustParseCIDR("224.0.0.0/4"),        // RFC 3171
 	MustParseCIDR("240.0.0.0/4"),        // RFC 1112
 	MustParseCIDR("255.255.255.255/32"), // RFC 919 Section 7
@@ -89,22 +95,47 @@ func (n *IPNet) Contains(ip IP) bool {
 }
 
 // IPNumber returns the number of ip addresses in the network
+// If the number does not fit into an int, as for most IPv6 networks,
+// it returns the maximum int value. Use IPNumberBig for IPv6 networks
 func (n *IPNet) IPNumber() int {
-	return 1 << (32 - simpleMaskLength(n.Mask.IPMask))
+	return bigIntToInt(n.IPNumberBig())
+}
+
+// IPNumberBig returns the number of ip addresses in the network
+func (n *IPNet) IPNumberBig() *big.Int {
+	_, host, _ := n.uint128()
+	hostBits := bits.OnesCount64(host.Hi) + bits.OnesCount64(host.Lo)
+	return new(big.Int).Lsh(big.NewInt(1), uint(hostBits))
 }
 
 // UsableIPNumber returns the number of usable ip addresses in the network
-// Basically it excludes the network address and broadcast address
+// For IPv4 networks, it excludes the network address and broadcast address
+// For IPv6 networks, it excludes the Subnet-Router anycast address
+// (RFC 4291 Section 2.6.1) since there is no broadcast in IPv6
+// /31 and /32 IPv4 networks (RFC 3021) and /127 and /128 IPv6
+// networks (RFC 6164) have no reserved addresses
+// If the number does not fit into an int, it returns the maximum int value
 func (n *IPNet) UsableIPNumber() int {
-	num := n.IPNumber()
+	return bigIntToInt(n.UsableIPNumberBig())
+}
+
+// UsableIPNumberBig returns the number of usable ip addresses in the network
+// exactly as UsableIPNumber does
+func (n *IPNet) UsableIPNumberBig() *big.Int {
+	num := n.IPNumberBig()
 
-	// return the exact network size for /31 and /32
-	if num <= 2 {
+	// return the exact network size for point-to-point networks
+	if num.Cmp(big.NewInt(2)) <= 0 {
 		return num
 	}
 
+	// exclude Subnet-Router anycast address for ipv6
+	if n.IP.IsV6() {
+		return num.Sub(num, big.NewInt(1))
+	}
+
 	// exclude network address and broadcast address
-	return num - 2
+	return num.Sub(num, big.NewInt(2))
 }
 
 // NetworkSize returns the network size
@@ -118,28 +149,35 @@ func (n *IPNet) FirstIP() IP {
 }
 
 // FirstUsableIP returns the first usable ip in the network
+// If n is a /127 or /128 IPv6 network, returns the firstIP
 func (n *IPNet) FirstUsableIP() IP {
+	if n.IP.IsV6() && n.IPNumberBig().Cmp(big.NewInt(2)) <= 0 {
+		return n.IP
+	}
 	return n.IP.GetNext()
 }
 
 // LastIP returns the last ip in the network
+// It is the network address with all host bits set
 func (n *IPNet) LastIP() IP {
-	if n.IPNumber() == 1 {
-		return n.IP
-	}
-	return n.IP.GetNextN(uint32(n.IPNumber() - 1))
+	network, host, v4 := n.uint128()
+	return fromUint128(network.Or(host), v4)
 }
 
 // LastUsableIP returns the last usable ip in the network
-// If n is a /31 or /32 network, returns the firstIP
+// If n is a /31 or /32 IPv4 network, returns the LastIP since all of
+// its addresses are usable (RFC 3021)
+// Since there is no broadcast address in IPv6,
+// it returns the LastIP for IPv6 networks
 func (n *IPNet) LastUsableIP() IP {
-	if n.IPNumber() <= 2 {
-		return n.IP
+	if n.IP.IsV6() || n.IPNumber() <= 2 {
+		return n.LastIP()
 	}
-	return n.IP.GetNextN(uint32(n.IPNumber() - 2))
+	return n.LastIP().GetPrevious()
 }
 
 // GetAllIP returns all ip addresses in network
+// Use Iter to walk large networks in constant memory
 func (n *IPNet) GetAllIP() []IP {
 	var ipList []IP
 	ip := n.FirstIP()
@@ -152,6 +190,7 @@ func (n *IPNet) GetAllIP() []IP {
 }
 
 // GetAllUsableIP returns all usable (adressable) ip addresses in network
+// Use Iter with SkipReserved to walk large networks in constant memory
 func (n *IPNet) GetAllUsableIP() []IP {
 	var ipList []IP
 
@@ -165,8 +204,8 @@ func (n *IPNet) GetAllUsableIP() []IP {
 		return ipList
 	}
 
-	ip := n.FirstIP().GetNext()
-	for i := 0; i < n.IPNumber()-2; i++ {
+	ip := n.FirstUsableIP()
+	for i := 0; i < n.UsableIPNumber(); i++ {
 		ipList = append(ipList, ip)
 		ip = ip.GetNext()
 	}
@@ -175,8 +214,17 @@ func (n *IPNet) GetAllUsableIP() []IP {
 }
 
 // RandomIP returns a random ip address in n network
+// Use a Sampler to choose the source of randomness
 func (n *IPNet) RandomIP() IP {
-	return FromInt(uint32(rand.Intn(n.IPNumber())) + n.FirstIP().ToInt())
+	network, host, v4 := n.uint128()
+	random := Uint128{rand.Uint64(), rand.Uint64()}
+	return fromUint128(network.Or(random.And(host)), v4)
+}
+
+// Range returns the IPRange between the first and the last ip in the network
+// Both boundaries of the IPRange are included
+func (n *IPNet) Range() *IPRange {
+	return NewIPRangeInclusive(n.FirstIP(), n.LastIP())
 }
 
 // Network returns the address's network name, "ip+net".
@@ -205,6 +253,25 @@ func (n *IPNet) Intersects(n2 IPNet) bool {
 	return n.Contains(n2.IP) || n2.Contains(n.IP)
 }
 
+// uint128 returns the network number and the host bits of n as integers
+// and whether n is an IPv4 network
+func (n *IPNet) uint128() (network, host Uint128, v4 bool) {
+	nn, m := networkNumberAndMask(n)
+	if nn == nil || m == nil {
+		return uint128Zero, uint128Zero, true
+	}
+
+	v4 = len(nn) == IPv4len
+	if v4 {
+		network = NewUint128(uint64(binary.BigEndian.Uint32(nn)))
+		host = NewUint128(uint64(^binary.BigEndian.Uint32(m)))
+		return network.And(host.Not()), host, v4
+	}
+	network = uint128FromBytes(nn)
+	host = uint128FromBytes(m).Not()
+	return network.And(host.Not()), host, v4
+}
+
 func networkNumberAndMask(n *IPNet) (ip net.IP, m net.IPMask) {
 	if ip = n.IP.To4().IP; ip == nil {
 		ip = n.IP.IP
@@ -228,6 +295,15 @@ func networkNumberAndMask(n *IPNet) (ip net.IP, m net.IPMask) {
 	return
 }
 
+// bigIntToInt returns x as an int
+// If x does not fit into an int, it returns the maximum int value
+func bigIntToInt(x *big.Int) int {
+	if !x.IsInt64() || x.Int64() > int64(maxInt) {
+		return maxInt
+	}
+	return int(x.Int64())
+}
+
 // If mask is a sequence of 1 bits followed by 0 bits,
 // return the number of 1 bits.
 func simpleMaskLength(mask net.IPMask) int {
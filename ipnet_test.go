@@ -150,6 +150,8 @@ var ipNumberTests = []struct {
 	{IPNet{IP: IPv4(135, 104, 0, 0), Mask: IPv4Mask(255, 255, 252, 0)}, 1024},
 	{IPNet{IP: IPv4(135, 104, 0, 1), Mask: IPv4Mask(0, 0, 0, 0)}, 4294967296},
 	{IPNet{IP: IPv4(135, 104, 0, 0), Mask: IPv4Mask(255, 255, 255, 0)}, 256},
+	{*MustParseCIDR("2001:db8::/120"), 256},
+	{*MustParseCIDR("2001:db8::/64"), maxInt},
 }
 
 func TestIPNumber(t *testing.T) {
@@ -171,6 +173,10 @@ var usableIPNumberTests = []struct {
 	{IPNet{IP: IPv4(135, 104, 0, 0), Mask: IPv4Mask(255, 255, 252, 0)}, 1022},
 	{IPNet{IP: IPv4(135, 104, 0, 1), Mask: IPv4Mask(0, 0, 0, 0)}, 4294967294},
 	{IPNet{IP: IPv4(135, 104, 0, 0), Mask: IPv4Mask(255, 255, 255, 0)}, 254},
+	{*MustParseCIDR("2001:db8::/128"), 1},
+	{*MustParseCIDR("2001:db8::/127"), 2},
+	{*MustParseCIDR("2001:db8::/126"), 3},
+	{*MustParseCIDR("2001:db8::/120"), 255},
 }
 
 func TestUsableIPNumber(t *testing.T) {
@@ -182,6 +188,28 @@ func TestUsableIPNumber(t *testing.T) {
 	}
 }
 
+var ipNumberBigTests = []struct {
+	in        IPNet
+	out       string
+	outUsable string
+}{
+	{*MustParseCIDR("0.0.0.0/0"), "4294967296", "4294967294"},
+	{*MustParseCIDR("2001:db8::/64"), "18446744073709551616", "18446744073709551615"},
+	{*MustParseCIDR("2001:db8::/32"), "79228162514264337593543950336", "79228162514264337593543950335"},
+	{*MustParseCIDR("::/0"), "340282366920938463463374607431768211456", "340282366920938463463374607431768211455"},
+}
+
+func TestIPNumberBig(t *testing.T) {
+	for _, tt := range ipNumberBigTests {
+		if out := tt.in.IPNumberBig().String(); out != tt.out {
+			t.Errorf("IPNet.IPNumberBig(%v) = %v, want %v", tt.in, out, tt.out)
+		}
+		if out := tt.in.UsableIPNumberBig().String(); out != tt.outUsable {
+			t.Errorf("IPNet.UsableIPNumberBig(%v) = %v, want %v", tt.in, out, tt.outUsable)
+		}
+	}
+}
+
 var firstIPTests = []struct {
 	in        IPNet
 	out       IP
@@ -207,6 +235,16 @@ var firstIPTests = []struct {
 		IPv4(192, 168, 96, 0),
 		IPv4(192, 168, 96, 1),
 	},
+	{
+		*MustParseCIDR("2001:db8::/64"),
+		MustParseIP("2001:db8::"),
+		MustParseIP("2001:db8::1"),
+	},
+	{
+		*MustParseCIDR("2001:db8::/127"),
+		MustParseIP("2001:db8::"),
+		MustParseIP("2001:db8::"),
+	},
 }
 
 func TestFirstIP(t *testing.T) {
@@ -252,6 +290,36 @@ var lastIPTests = []struct {
 		IPv4(192, 168, 111, 255),
 		IPv4(192, 168, 111, 254),
 	},
+	{
+		*MustParseCIDR("2001:db8::/64"),
+		MustParseIP("2001:db8::ffff:ffff:ffff:ffff"),
+		MustParseIP("2001:db8::ffff:ffff:ffff:ffff"),
+	},
+	{
+		*MustParseCIDR("2001:db8:1234::/46"),
+		MustParseIP("2001:db8:1237:ffff:ffff:ffff:ffff:ffff"),
+		MustParseIP("2001:db8:1237:ffff:ffff:ffff:ffff:ffff"),
+	},
+	{
+		*MustParseCIDR("::/0"),
+		MustParseIP("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"),
+		MustParseIP("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"),
+	},
+	{
+		*MustParseCIDR("0.0.0.0/0"),
+		IPv4(255, 255, 255, 255),
+		IPv4(255, 255, 255, 254),
+	},
+	{
+		*MustParseCIDR("192.0.2.2/31"),
+		IPv4(192, 0, 2, 3),
+		IPv4(192, 0, 2, 3),
+	},
+	{
+		*MustParseCIDR("192.0.2.2/32"),
+		IPv4(192, 0, 2, 2),
+		IPv4(192, 0, 2, 2),
+	},
 }
 
 func TestLastIP(t *testing.T) {
@@ -319,6 +387,20 @@ var getAllIPTests = []struct {
 			IPv4(172, 16, 16, 0),
 		},
 	},
+	{
+		*MustParseCIDR("2001:db8::/126"),
+		[]IP{
+			MustParseIP("2001:db8::"),
+			MustParseIP("2001:db8::1"),
+			MustParseIP("2001:db8::2"),
+			MustParseIP("2001:db8::3"),
+		},
+		[]IP{
+			MustParseIP("2001:db8::1"),
+			MustParseIP("2001:db8::2"),
+			MustParseIP("2001:db8::3"),
+		},
+	},
 }
 
 func TestGetAllIP(t *testing.T) {
@@ -377,3 +459,27 @@ func TestIPNetIntersects(t *testing.T) {
 
 	}
 }
+
+var ipNetRangeTests = []struct {
+	in    *IPNet
+	first IP
+	last  IP
+}{
+	{MustParseCIDR("172.16.16.0/24"), IPv4(172, 16, 16, 0), IPv4(172, 16, 16, 255)},
+	{MustParseCIDR("0.0.0.0/0"), IPv4(0, 0, 0, 0), IPv4(255, 255, 255, 255)},
+	{MustParseCIDR("2001:db8::/64"), MustParseIP("2001:db8::"), MustParseIP("2001:db8::ffff:ffff:ffff:ffff")},
+}
+
+func TestIPNetRange(t *testing.T) {
+	for _, tt := range ipNetRangeTests {
+		out := tt.in.Range()
+		if !out.FirstIP().Equal(tt.first) || !out.LastIP().Equal(tt.last) {
+			t.Errorf("IPNet.Range(%v) = %v-%v, want %v-%v", tt.in, out.FirstIP(), out.LastIP(), tt.first, tt.last)
+		}
+
+		// Prefixes must revert Range
+		if prefixes := out.Prefixes(); len(prefixes) != 1 || prefixes[0].String() != tt.in.String() {
+			t.Errorf("IPRange.Prefixes(IPNet.Range(%v)) = %v, want [%v]", tt.in, prefixes, tt.in)
+		}
+	}
+}
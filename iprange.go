@@ -1,9 +1,19 @@
 package ipx
 
-import "math/rand"
+import (
+	"errors"
+	"math/big"
+)
+
+// Error definitions
+var (
+	ErrMixedIPFamily = errors.New("ip addresses are not in the same address family")
+)
 
 // IPRange represents an IP range
 // Lower boundary is included and Upper boundary is not included
+// A nil Upper boundary stands for the end of the address family of
+// Lower, so the range includes the last address of the family
 type IPRange struct {
 	Lower IP // LowerIP
 	Upper IP // UpperIP
@@ -11,27 +21,46 @@ type IPRange struct {
 
 // ParseIPRange parses x and y as an IPRange
 // Order is not imported on input parameters
+// It returns ErrMixedIPFamily if x and y are not in the same address family
 func ParseIPRange(x, y string) (*IPRange, error) {
-	xIP, err := ParseIP(x)
+	xIP, yIP, err := parseIPRangeBoundaries(x, y)
 	if err != nil {
 		return nil, err
 	}
 
-	yIP, err := ParseIP(y)
+	return NewIPRange(xIP, yIP), nil
+}
+
+// MustParseIPRange parses x and y as an IPRange
+// It throws panic if x or y is not a valid IP address
+// Order is not imported on input parameters
+func MustParseIPRange(x, y string) *IPRange {
+	ipRange, err := ParseIPRange(x, y)
 	if err != nil {
-		return nil, err
+		panic(err)
 	}
+	return ipRange
+}
 
-	ipRange := NewIPRange(xIP, yIP)
+// ParseIPRangeInclusive parses x and y as an IPRange
+// whose both boundaries are included
+// Order is not imported on input parameters
+// It returns ErrMixedIPFamily if x and y are not in the same address family
+func ParseIPRangeInclusive(x, y string) (*IPRange, error) {
+	xIP, yIP, err := parseIPRangeBoundaries(x, y)
+	if err != nil {
+		return nil, err
+	}
 
-	return ipRange, nil
+	return NewIPRangeInclusive(xIP, yIP), nil
 }
 
-// MustParseIPRange parses x and y as an IPRange
+// MustParseIPRangeInclusive parses x and y as an IPRange
+// whose both boundaries are included
 // It throws panic if x or y is not a valid IP address
 // Order is not imported on input parameters
-func MustParseIPRange(x, y string) *IPRange {
-	ipRange, err := ParseIPRange(x, y)
+func MustParseIPRangeInclusive(x, y string) *IPRange {
+	ipRange, err := ParseIPRangeInclusive(x, y)
 	if err != nil {
 		panic(err)
 	}
@@ -40,23 +69,62 @@ func MustParseIPRange(x, y string) *IPRange {
 
 // NewIPRange creates a new IPRange with x and y
 // Order is not important
+// x and y must be in the same address family
 func NewIPRange(x, y IP) *IPRange {
-	if x.ToInt() < y.ToInt() {
+	if x.Before(y) {
 		return &IPRange{x, y}
 	}
 
 	return &IPRange{y, x}
 }
 
+// NewIPRangeInclusive creates a new IPRange with x and y
+// whose both boundaries are included
+// Upper boundary of the IPRange is the ip after the greater of x and y,
+// or nil if it is 255.255.255.255 or ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff
+// Order is not important
+// x and y must be in the same address family
+func NewIPRangeInclusive(x, y IP) *IPRange {
+	ipRange := NewIPRange(x, y)
+	if next := ipRange.Upper.GetNext(); next.Before(ipRange.Upper) {
+		ipRange.Upper = IP{}
+	} else {
+		ipRange.Upper = next
+	}
+	return ipRange
+}
+
 // Contains reports whether the IPRange includes ip
 func (i *IPRange) Contains(ip IP) bool {
-	ipInt := ip.ToInt()
-	return ipInt >= i.Lower.ToInt() && ipInt < i.Upper.ToInt()
+	first, last, v4, ok := i.bounds()
+	if !ok {
+		return false
+	}
+
+	val, ipV4 := ip.toUint128()
+	if ip.IP == nil || ipV4 != v4 {
+		return false
+	}
+	return val.Cmp(first) >= 0 && val.Cmp(last) <= 0
 }
 
 // IPNumber returns the number of ip addresses in IPRange
+// If the number does not fit into an int, it returns the maximum int value
+// Use IPNumberBig for IPv6 ranges
 func (i *IPRange) IPNumber() int {
-	return int(i.Upper.ToInt() - i.Lower.ToInt())
+	return bigIntToInt(i.IPNumberBig())
+}
+
+// IPNumberBig returns the number of ip addresses in IPRange
+func (i *IPRange) IPNumberBig() *big.Int {
+	first, last, _, ok := i.bounds()
+	if !ok {
+		return big.NewInt(0)
+	}
+
+	diff, _ := last.Sub(first)
+	num := diff.BigInt()
+	return num.Add(num, big.NewInt(1))
 }
 
 // FirstIP returns the first ip in IPRange
@@ -66,13 +134,18 @@ func (i *IPRange) FirstIP() IP {
 
 // LastIP returns the last ip in IPRange
 func (i *IPRange) LastIP() IP {
+	if i.Upper.IP == nil {
+		_, last, v4, _ := i.bounds()
+		return fromUint128(last, v4)
+	}
 	if i.Lower.Equal(i.Upper) {
-		return i.Lower
+		return i.Upper
 	}
 	return i.Upper.GetPrevious()
 }
 
 // GetAllIP returns all IP's in IPRange
+// Use Iter to walk large ranges in constant memory
 func (i *IPRange) GetAllIP() []IP {
 
 	ipList := []IP{i.Lower}
@@ -87,11 +160,91 @@ func (i *IPRange) GetAllIP() []IP {
 }
 
 // RandomIP returns a random ip address in IPRange
+// Use a Sampler to choose the source of randomness
 func (i *IPRange) RandomIP() IP {
-	return FromInt(uint32(rand.Intn(i.IPNumber())) + i.Lower.ToInt())
+	first, last, v4, ok := i.bounds()
+	if !ok {
+		return i.Lower
+	}
+
+	diff, _ := last.Sub(first)
+	val, _ := first.Add(randomUint128(diff))
+	return fromUint128(val, v4)
 }
 
 // Intersects checks whether the IPRange intersects the other IPrange
 func (i *IPRange) Intersects(i2 IPRange) bool {
-	return i.Contains(i2.Lower) || i.Contains(i2.LastIP()) || i2.Contains(i.Lower) || i2.Contains(i.LastIP())
+	first, last, v4, ok := i.bounds()
+	first2, last2, v42, ok2 := i2.bounds()
+	if !ok || !ok2 || v4 != v42 {
+		return false
+	}
+	return first.Cmp(last2) <= 0 && first2.Cmp(last) <= 0
+}
+
+// Prefixes returns the minimal list of CIDR prefixes covering IPRange
+func (i *IPRange) Prefixes() []*IPNet {
+	first, last, v4, ok := i.bounds()
+	if !ok {
+		return nil
+	}
+	return ipSpan{first, last}.prefixes(v4)
+}
+
+// String returns the first and the last ip in IPRange
+// like "10.0.0.1-10.0.0.9"
+// It returns "<nil>" if the IPRange is empty or its boundaries
+// are not in the same address family
+func (i *IPRange) String() string {
+	first, last, v4, ok := i.bounds()
+	if !ok {
+		return "<nil>"
+	}
+	return fromUint128(first, v4).String() + "-" + fromUint128(last, v4).String()
+}
+
+// bounds returns the first and the last ip in IPRange as integers
+// and whether the IPRange is an IPv4 range
+// ok is false if the IPRange is empty or its boundaries
+// are not in the same address family
+func (i *IPRange) bounds() (first, last Uint128, v4 bool, ok bool) {
+	if i.Lower.IP == nil {
+		return
+	}
+
+	first, v4 = i.Lower.toUint128()
+	if i.Upper.IP == nil {
+		last = Uint128{^uint64(0), ^uint64(0)}
+		if v4 {
+			last = NewUint128(0xffffffff)
+		}
+		return first, last, v4, true
+	}
+
+	last, upperV4 := i.Upper.toUint128()
+	if v4 != upperV4 || first.Cmp(last) >= 0 {
+		return
+	}
+	last, _ = last.Sub(uint128One)
+	return first, last, v4, true
+}
+
+// parseIPRangeBoundaries parses x and y as IP addresses
+// in the same address family
+func parseIPRangeBoundaries(x, y string) (IP, IP, error) {
+	xIP, err := ParseIP(x)
+	if err != nil {
+		return IP{}, IP{}, err
+	}
+
+	yIP, err := ParseIP(y)
+	if err != nil {
+		return IP{}, IP{}, err
+	}
+
+	if xIP.IsV4() != yIP.IsV4() {
+		return IP{}, IP{}, ErrMixedIPFamily
+	}
+
+	return xIP, yIP, nil
 }
@@ -14,6 +14,8 @@ var parseIPRangeTests = []struct {
 }{
 	{"172.16.16.1", "172.16.16.100", IPRange{IPv4(172, 16, 16, 1), IPv4(172, 16, 16, 100)}, false, nil},
 	{"172.16.16.100", "172.16.16.1", IPRange{IPv4(172, 16, 16, 1), IPv4(17, 16, 16, 100)}, true, nil},
+	{"2001:db8::1", "2001:db8::ff", IPRange{Lower: MustParseIP("2001:db8::1"), Upper: MustParseIP("2001:db8::ff")}, false, nil},
+	{"2001:db8::ff", "2001:db8::1", IPRange{Lower: MustParseIP("2001:db8::1"), Upper: MustParseIP("2001:db8::ff")}, true, nil},
 }
 
 func TestParseIPRange(t *testing.T) {
@@ -60,6 +62,46 @@ func TestParseIPRange(t *testing.T) {
 	}
 }
 
+var parseIPRangeErrorTests = []struct {
+	inLower string
+	inUpper string
+	err     error
+}{
+	{"172.16.16.1", "2001:db8::1", ErrMixedIPFamily},
+	{"2001:db8::1", "172.16.16.1", ErrMixedIPFamily},
+	{"172.16.16.1", "172.16.16.256", ErrInvalidIP},
+}
+
+func TestParseIPRangeError(t *testing.T) {
+	for _, tt := range parseIPRangeErrorTests {
+		if _, err := ParseIPRange(tt.inLower, tt.inUpper); err != tt.err {
+			t.Errorf("ParseIPRange(%v,%v) = %v, want %v", tt.inLower, tt.inUpper, err, tt.err)
+		}
+		if _, err := ParseIPRangeInclusive(tt.inLower, tt.inUpper); err != tt.err {
+			t.Errorf("ParseIPRangeInclusive(%v,%v) = %v, want %v", tt.inLower, tt.inUpper, err, tt.err)
+		}
+	}
+}
+
+var newIPRangeInclusiveTests = []struct {
+	x, y IP
+	out  IPRange
+}{
+	{IPv4(10, 0, 0, 9), IPv4(10, 0, 0, 1), IPRange{IPv4(10, 0, 0, 1), IPv4(10, 0, 0, 10)}},
+	{MustParseIP("2001:db8::"), MustParseIP("2001:db8::ffff"), IPRange{MustParseIP("2001:db8::"), MustParseIP("2001:db8::1:0")}},
+	{IPv4(255, 255, 255, 0), IPv4(255, 255, 255, 255), IPRange{IPv4(255, 255, 255, 0), IP{}}},
+	{MustParseIP("::"), MustParseIP("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"), IPRange{MustParseIP("::"), IP{}}},
+}
+
+func TestNewIPRangeInclusive(t *testing.T) {
+	for _, tt := range newIPRangeInclusiveTests {
+		out := NewIPRangeInclusive(tt.x, tt.y)
+		if !out.Lower.Equal(tt.out.Lower) || !out.Upper.Equal(tt.out.Upper) || (out.Upper.IP == nil) != (tt.out.Upper.IP == nil) {
+			t.Errorf("NewIPRangeInclusive(%v, %v) = {%v %v}, want {%v %v}", tt.x, tt.y, out.Lower, out.Upper, tt.out.Lower, tt.out.Upper)
+		}
+	}
+}
+
 var ipRangeContainsTests = []struct {
 	ip      IP
 	ipRange *IPRange
@@ -69,6 +111,14 @@ var ipRangeContainsTests = []struct {
 	{IPv4(172, 16, 16, 100), &IPRange{Lower: IPv4(172, 16, 16, 0), Upper: IPv4(172, 16, 16, 100)}, false},
 	{IPv4(172, 16, 15, 254), &IPRange{Lower: IPv4(172, 16, 16, 0), Upper: IPv4(172, 16, 16, 100)}, false},
 	{IPv4(172, 16, 16, 0), &IPRange{Lower: IPv4(172, 16, 16, 0), Upper: IPv4(172, 16, 16, 100)}, true},
+	{MustParseIP("2001:db8::1"), MustParseIPRange("2001:db8::", "2001:db8::ff"), true},
+	{MustParseIP("2001:db8::ff"), MustParseIPRange("2001:db8::", "2001:db8::ff"), false},
+	{MustParseIP("2001:db8::ff"), MustParseIPRangeInclusive("2001:db8::", "2001:db8::ff"), true},
+	{IPv4(0, 0, 0, 1), MustParseIPRange("::", "::ff"), false},
+	{MustParseIP("::1"), MustParseIPRange("0.0.0.0", "0.0.0.255"), false},
+	{IPv4(255, 255, 255, 255), MustParseIPRange("255.255.255.0", "255.255.255.255"), false},
+	{IPv4(255, 255, 255, 255), MustParseIPRangeInclusive("255.255.255.0", "255.255.255.255"), true},
+	{MustParseIP("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"), MustParseIPRangeInclusive("::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"), true},
 }
 
 func TestIPRangeContains(t *testing.T) {
@@ -85,6 +135,10 @@ var ipRangeIPNumberTests = []struct {
 }{
 	{&IPRange{Lower: IPv4(172, 16, 16, 0), Upper: IPv4(172, 16, 16, 100)}, 100},
 	{&IPRange{Lower: IPv4(172, 16, 15, 254), Upper: IPv4(172, 16, 16, 4)}, 6},
+	{MustParseIPRange("2001:db8::", "2001:db8::100"), 256},
+	{MustParseIPRangeInclusive("2001:db8::", "2001:db8::100"), 257},
+	{MustParseIPRangeInclusive("0.0.0.0", "255.255.255.255"), 4294967296},
+	{MustParseIPRange("2001:db8::", "2001:db8:0:1::"), maxInt},
 }
 
 func TestIPRangeIPNumber(t *testing.T) {
@@ -116,6 +170,21 @@ var ipRangeFirstLastIPTests = []struct {
 		IPv4(192, 168, 100, 50),
 		IPv4(192, 168, 100, 199),
 	},
+	{
+		MustParseIPRange("2001:db8::", "2001:db8:0:1::"),
+		MustParseIP("2001:db8::"),
+		MustParseIP("2001:db8::ffff:ffff:ffff:ffff"),
+	},
+	{
+		MustParseIPRangeInclusive("255.255.255.0", "255.255.255.255"),
+		IPv4(255, 255, 255, 0),
+		IPv4(255, 255, 255, 255),
+	},
+	{
+		MustParseIPRangeInclusive("::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"),
+		MustParseIP("::"),
+		MustParseIP("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"),
+	},
 }
 
 func TestIPRangeFirstIP(t *testing.T) {
@@ -215,6 +284,26 @@ var ipRangeIntersectsTests = []struct {
 		MustParseIPRange("172.16.15.250", "172.16.16.1"),
 		true,
 	},
+	{
+		MustParseIPRange("2001:db8::", "2001:db8::100"),
+		MustParseIPRange("2001:db8::ff", "2001:db8::1ff"),
+		true,
+	},
+	{
+		MustParseIPRange("2001:db8::", "2001:db8::100"),
+		MustParseIPRange("2001:db8::100", "2001:db8::1ff"),
+		false,
+	},
+	{
+		MustParseIPRangeInclusive("2001:db8::", "2001:db8::100"),
+		MustParseIPRange("2001:db8::100", "2001:db8::1ff"),
+		true,
+	},
+	{
+		MustParseIPRange("0.0.0.0", "0.0.0.255"),
+		MustParseIPRange("::", "::ff"),
+		false,
+	},
 }
 
 func TestIPRangeIntersects(t *testing.T) {
@@ -226,3 +315,42 @@ func TestIPRangeIntersects(t *testing.T) {
 
 	}
 }
+
+var ipRangePrefixesTests = []struct {
+	in  *IPRange
+	out []string
+}{
+	{
+		MustParseIPRange("172.16.16.0", "172.16.17.0"),
+		[]string{"172.16.16.0/24"},
+	},
+	{
+		MustParseIPRangeInclusive("172.16.16.1", "172.16.16.10"),
+		[]string{"172.16.16.1/32", "172.16.16.2/31", "172.16.16.4/30", "172.16.16.8/31", "172.16.16.10/32"},
+	},
+	{
+		MustParseIPRangeInclusive("0.0.0.0", "255.255.255.255"),
+		[]string{"0.0.0.0/0"},
+	},
+	{
+		MustParseIPRangeInclusive("2001:db8::ff", "2001:db8::1:0"),
+		[]string{"2001:db8::ff/128", "2001:db8::100/120", "2001:db8::200/119", "2001:db8::400/118", "2001:db8::800/117", "2001:db8::1000/116", "2001:db8::2000/115", "2001:db8::4000/114", "2001:db8::8000/113", "2001:db8::1:0/128"},
+	},
+	{
+		MustParseIPRangeInclusive("8000::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"),
+		[]string{"8000::/1"},
+	},
+	{
+		MustParseIPRange("172.16.16.1", "172.16.16.1"),
+		nil,
+	},
+}
+
+func TestIPRangePrefixes(t *testing.T) {
+	for _, tt := range ipRangePrefixesTests {
+		out := prefixStrings(tt.in.Prefixes())
+		if !reflect.DeepEqual(out, tt.out) {
+			t.Errorf("IPRange.Prefixes(%v) = %v, want %v", tt.in, out, tt.out)
+		}
+	}
+}
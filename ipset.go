@@ -0,0 +1,367 @@
+package ipx
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Error definitions
+var (
+	ErrInvalidIPSetElement = errors.New("invalid ip set element")
+)
+
+// IPSet represents an immutable set of IP addresses
+// IPv4 and IPv6 addresses are kept separately as sorted,
+// non-overlapping and non-adjacent ranges
+type IPSet struct {
+	v4 []ipSpan
+	v6 []ipSpan
+}
+
+// ipSpan represents the addresses between first and last, inclusively
+type ipSpan struct {
+	first Uint128
+	last  Uint128
+}
+
+// NewIPSet creates a new IPSet with given elements
+// Elements can be any mix of IP, *IPNet, *IPRange, *IPSet, Addr,
+// Prefix and AddrRange, and netip.Addr and netip.Prefix on go 1.18
+// or later
+// It returns ErrInvalidIPSetElement if an element is not one of them,
+// is not a valid IP address, has a non-canonical mask
+// or has boundaries in different address families
+func NewIPSet(elems ...interface{}) (*IPSet, error) {
+	s := &IPSet{}
+	for _, elem := range elems {
+		switch e := elem.(type) {
+		case IP:
+			if e.IP == nil {
+				return nil, ErrInvalidIPSetElement
+			}
+			val, v4 := e.toUint128()
+			s.add(ipSpan{val, val}, v4)
+		case *IPNet:
+			if e == nil || e.NetworkSize() == -1 {
+				return nil, ErrInvalidIPSetElement
+			}
+			network, host, v4 := e.uint128()
+			s.add(ipSpan{network, network.Or(host)}, v4)
+		case *IPRange:
+			if e == nil || e.Lower.IP == nil || e.Upper.IP != nil && e.Lower.IsV4() != e.Upper.IsV4() {
+				return nil, ErrInvalidIPSetElement
+			}
+			if first, last, v4, ok := e.bounds(); ok {
+				s.add(ipSpan{first, last}, v4)
+			}
+		case *IPSet:
+			if e == nil {
+				return nil, ErrInvalidIPSetElement
+			}
+			s.v4 = append(s.v4, e.v4...)
+			s.v6 = append(s.v6, e.v6...)
+		case Addr:
+			if !e.IsValid() {
+				return nil, ErrInvalidIPSetElement
+			}
+			s.add(ipSpan{e.u, e.u}, e.Is4())
+		case Prefix:
+			if !e.IsValid() {
+				return nil, ErrInvalidIPSetElement
+			}
+			s.add(ipSpan{e.addr.u, e.LastAddr().u}, e.addr.Is4())
+		case AddrRange:
+			if !e.IsValid() {
+				return nil, ErrInvalidIPSetElement
+			}
+			s.add(ipSpan{e.first.u, e.last.u}, e.first.Is4())
+		default:
+			span, v4, ok := netipSpan(elem)
+			if !ok {
+				return nil, ErrInvalidIPSetElement
+			}
+			s.add(span, v4)
+		}
+	}
+
+	s.v4 = normalizeSpans(s.v4)
+	s.v6 = normalizeSpans(s.v6)
+	return s, nil
+}
+
+// MustNewIPSet creates a new IPSet with given elements
+// It throws a panic if an element is not valid
+func MustNewIPSet(elems ...interface{}) *IPSet {
+	s, err := NewIPSet(elems...)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// IsEmpty reports whether the IPSet has no addresses
+func (s *IPSet) IsEmpty() bool {
+	return len(s.v4) == 0 && len(s.v6) == 0
+}
+
+// Contains reports whether the IPSet includes ip
+func (s *IPSet) Contains(ip IP) bool {
+	if ip.IP == nil {
+		return false
+	}
+
+	val, v4 := ip.toUint128()
+	spans := s.spans(v4)
+
+	// find the first span ending at or after val
+	j := sort.Search(len(spans), func(k int) bool {
+		return spans[k].last.Cmp(val) >= 0
+	})
+	return j < len(spans) && spans[j].first.Cmp(val) <= 0
+}
+
+// Union returns the set of addresses in s or x
+func (s *IPSet) Union(x *IPSet) *IPSet {
+	return &IPSet{
+		v4: normalizeSpans(append(append([]ipSpan{}, s.v4...), x.v4...)),
+		v6: normalizeSpans(append(append([]ipSpan{}, s.v6...), x.v6...)),
+	}
+}
+
+// Intersect returns the set of addresses in both s and x
+func (s *IPSet) Intersect(x *IPSet) *IPSet {
+	return &IPSet{
+		v4: intersectSpans(s.v4, x.v4),
+		v6: intersectSpans(s.v6, x.v6),
+	}
+}
+
+// Difference returns the set of addresses in s but not in x
+func (s *IPSet) Difference(x *IPSet) *IPSet {
+	return &IPSet{
+		v4: intersectSpans(s.v4, complementSpans(x.v4, true)),
+		v6: intersectSpans(s.v6, complementSpans(x.v6, false)),
+	}
+}
+
+// Complement returns the set of addresses not in s
+// The complement is taken per address family, so the complement of
+// an IPSet holding only IPv4 addresses has the whole IPv6 address space
+func (s *IPSet) Complement() *IPSet {
+	return &IPSet{
+		v4: complementSpans(s.v4, true),
+		v6: complementSpans(s.v6, false),
+	}
+}
+
+// Overlaps reports whether s and x have any address in common
+func (s *IPSet) Overlaps(x *IPSet) bool {
+	return !s.Intersect(x).IsEmpty()
+}
+
+// Equal reports whether s and x have exactly the same addresses
+func (s *IPSet) Equal(x *IPSet) bool {
+	return equalSpans(s.v4, x.v4) && equalSpans(s.v6, x.v6)
+}
+
+// Ranges returns the addresses in the IPSet as sorted, non-overlapping
+// ranges whose both boundaries are included
+// IPv4 ranges come before IPv6 ranges
+func (s *IPSet) Ranges() []*IPRange {
+	var ranges []*IPRange
+	for _, span := range s.v4 {
+		ranges = append(ranges, span.ipRange(true))
+	}
+	for _, span := range s.v6 {
+		ranges = append(ranges, span.ipRange(false))
+	}
+	return ranges
+}
+
+// Prefixes returns the addresses in the IPSet as the minimal list of
+// sorted CIDR prefixes
+// IPv4 prefixes come before IPv6 prefixes
+func (s *IPSet) Prefixes() []*IPNet {
+	var prefixes []*IPNet
+	for _, span := range s.v4 {
+		prefixes = append(prefixes, span.prefixes(true)...)
+	}
+	for _, span := range s.v6 {
+		prefixes = append(prefixes, span.prefixes(false)...)
+	}
+	return prefixes
+}
+
+// String returns the ranges of the IPSet separated by commas
+// like "10.0.0.1-10.0.0.3, 10.0.0.5, 2001:db8::-2001:db8::ff"
+// It returns an empty string for an empty IPSet
+func (s *IPSet) String() string {
+	var b strings.Builder
+	for _, r := range s.Ranges() {
+		if b.Len() > 0 {
+			b.WriteString(", ")
+		}
+		if r.Lower.Equal(r.LastIP()) {
+			b.WriteString(r.Lower.String())
+		} else {
+			b.WriteString(r.String())
+		}
+	}
+	return b.String()
+}
+
+// add appends span to the spans of given address family
+func (s *IPSet) add(span ipSpan, v4 bool) {
+	if v4 {
+		s.v4 = append(s.v4, span)
+		return
+	}
+	s.v6 = append(s.v6, span)
+}
+
+// spans returns the spans of given address family
+func (s *IPSet) spans(v4 bool) []ipSpan {
+	if v4 {
+		return s.v4
+	}
+	return s.v6
+}
+
+// ipRange returns the span as an inclusive IPRange
+func (span ipSpan) ipRange(v4 bool) *IPRange {
+	return NewIPRangeInclusive(fromUint128(span.first, v4), fromUint128(span.last, v4))
+}
+
+// prefixes returns the minimal list of CIDR prefixes covering the span
+func (span ipSpan) prefixes(v4 bool) []*IPNet {
+	var prefixes []*IPNet
+	span.walkPrefixes(v4, func(network Uint128, ones int) {
+		prefixes = append(prefixes, newIPNet(network, ones, v4))
+	})
+	return prefixes
+}
+
+// walkPrefixes calls fn for each prefix of the minimal list of
+// CIDR prefixes covering the span in ascending order
+func (span ipSpan) walkPrefixes(v4 bool, fn func(network Uint128, ones int)) {
+	width := addressBits(v4)
+	first := span.first
+	for {
+		// find the largest block aligned at first that fits into the span
+		hostBits := first.TrailingZeros()
+		if hostBits > width {
+			hostBits = width
+		}
+		last := first.Or(uint128Max.Rsh(uint(128 - hostBits)))
+		for last.Cmp(span.last) > 0 {
+			hostBits--
+			last = first.Or(uint128Max.Rsh(uint(128 - hostBits)))
+		}
+
+		fn(first, width-hostBits)
+
+		if last.Cmp(span.last) >= 0 {
+			return
+		}
+		first, _ = last.Add(uint128One)
+	}
+}
+
+// normalizeSpans sorts spans and merges overlapping and adjacent spans
+func normalizeSpans(spans []ipSpan) []ipSpan {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].first.Cmp(spans[j].first) < 0
+	})
+
+	merged := []ipSpan{spans[0]}
+	for _, span := range spans[1:] {
+		prev := &merged[len(merged)-1]
+		next, overflow := prev.last.Add(uint128One)
+		if overflow || span.first.Cmp(next) <= 0 {
+			if span.last.Cmp(prev.last) > 0 {
+				prev.last = span.last
+			}
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
+
+// intersectSpans returns the intersection of two normalized span lists
+func intersectSpans(x, y []ipSpan) []ipSpan {
+	var spans []ipSpan
+	for i, j := 0, 0; i < len(x) && j < len(y); {
+		first, last := x[i].first, x[i].last
+		if y[j].first.Cmp(first) > 0 {
+			first = y[j].first
+		}
+		if y[j].last.Cmp(last) < 0 {
+			last = y[j].last
+		}
+		if first.Cmp(last) <= 0 {
+			spans = append(spans, ipSpan{first, last})
+		}
+
+		if x[i].last.Cmp(y[j].last) < 0 {
+			i++
+		} else {
+			j++
+		}
+	}
+	return spans
+}
+
+// complementSpans returns the complement of a normalized span list
+// in the address space of given address family
+func complementSpans(spans []ipSpan, v4 bool) []ipSpan {
+	var complement []ipSpan
+
+	max := uint128Max.Rsh(uint(128 - addressBits(v4)))
+	next := uint128Zero
+	for _, span := range spans {
+		if span.first.Cmp(next) > 0 {
+			last, _ := span.first.Sub(uint128One)
+			complement = append(complement, ipSpan{next, last})
+		}
+		if span.last.Cmp(max) >= 0 {
+			return complement
+		}
+		next, _ = span.last.Add(uint128One)
+	}
+	return append(complement, ipSpan{next, max})
+}
+
+// equalSpans reports whether two span lists are equal
+func equalSpans(x, y []ipSpan) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// addressBits returns the length of the addresses in bits
+func addressBits(v4 bool) int {
+	if v4 {
+		return IPv4len * 8
+	}
+	return IPv6len * 8
+}
+
+// newIPNet returns the network with given network number and prefix length
+func newIPNet(network Uint128, ones int, v4 bool) *IPNet {
+	return &IPNet{
+		IP:   fromUint128(network, v4),
+		Mask: CIDRMask(ones, addressBits(v4)),
+	}
+}
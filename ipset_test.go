@@ -0,0 +1,227 @@
+package ipx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func rangeStrings(ranges []*IPRange) []string {
+	var out []string
+	for _, r := range ranges {
+		out = append(out, r.FirstIP().String()+"-"+r.LastIP().String())
+	}
+	return out
+}
+
+func prefixStrings(prefixes []*IPNet) []string {
+	var out []string
+	for _, p := range prefixes {
+		out = append(out, p.String())
+	}
+	return out
+}
+
+var newIPSetTests = []struct {
+	in       []interface{}
+	ranges   []string
+	prefixes []string
+}{
+	{
+		[]interface{}{
+			MustParseCIDR("10.0.0.0/24"),
+			MustParseCIDR("10.0.1.0/24"),
+			IPv4(10, 0, 2, 0),
+		},
+		[]string{"10.0.0.0-10.0.2.0"},
+		[]string{"10.0.0.0/23", "10.0.2.0/32"},
+	},
+	{
+		[]interface{}{
+			MustParseIPRange("192.168.0.10", "192.168.0.20"),
+			MustParseIPRange("192.168.0.15", "192.168.0.30"),
+			MustParseCIDR("2001:db8::/64"),
+			MustParseIP("2001:db8:0:1::"),
+		},
+		[]string{"192.168.0.10-192.168.0.29", "2001:db8::-2001:db8:0:1::"},
+		[]string{"192.168.0.10/31", "192.168.0.12/30", "192.168.0.16/29", "192.168.0.24/30", "192.168.0.28/31", "2001:db8::/64", "2001:db8:0:1::/128"},
+	},
+	{
+		[]interface{}{
+			MustParseIPRangeInclusive("255.255.255.0", "255.255.255.255"),
+			MustParseCIDR("255.255.254.0/24"),
+		},
+		[]string{"255.255.254.0-255.255.255.255"},
+		[]string{"255.255.254.0/23"},
+	},
+	{
+		[]interface{}{
+			MustParseCIDR("::/0"),
+			MustParseCIDR("0.0.0.0/0"),
+		},
+		[]string{"0.0.0.0-255.255.255.255", "::-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"},
+		[]string{"0.0.0.0/0", "::/0"},
+	},
+	{
+		[]interface{}{
+			MustParseIPRange("10.0.0.1", "10.0.0.1"),
+		},
+		nil,
+		nil,
+	},
+}
+
+func TestNewIPSet(t *testing.T) {
+	for _, tt := range newIPSetTests {
+		s, err := NewIPSet(tt.in...)
+		if err != nil {
+			t.Fatalf("NewIPSet(%v) = %v", tt.in, err)
+		}
+		if out := rangeStrings(s.Ranges()); !reflect.DeepEqual(out, tt.ranges) {
+			t.Errorf("IPSet.Ranges(%v) = %v, want %v", tt.in, out, tt.ranges)
+		}
+		if out := prefixStrings(s.Prefixes()); !reflect.DeepEqual(out, tt.prefixes) {
+			t.Errorf("IPSet.Prefixes(%v) = %v, want %v", tt.in, out, tt.prefixes)
+		}
+	}
+}
+
+var newIPSetErrorTests = [][]interface{}{
+	{"10.0.0.1"},
+	{IP{}},
+	{&IPRange{Lower: IPv4(10, 0, 0, 1), Upper: MustParseIP("2001:db8::1")}},
+	{&IPNet{IP: IPv4(192, 168, 1, 0), Mask: IPv4Mask(255, 0, 255, 0)}},
+}
+
+func TestNewIPSetError(t *testing.T) {
+	for _, tt := range newIPSetErrorTests {
+		if _, err := NewIPSet(tt...); err != ErrInvalidIPSetElement {
+			t.Errorf("NewIPSet(%v) = %v, want %v", tt, err, ErrInvalidIPSetElement)
+		}
+	}
+}
+
+var ipSetContainsTests = []struct {
+	ip IP
+	ok bool
+}{
+	{IPv4(10, 0, 0, 0), true},
+	{IPv4(10, 255, 255, 255), true},
+	{IPv4(11, 0, 0, 0), false},
+	{IPv4(192, 168, 1, 5), true},
+	{IPv4(192, 168, 1, 6), false},
+	{MustParseIP("2001:db8::ffff"), true},
+	{MustParseIP("2001:db9::"), false},
+	{MustParseIP("::a00:1"), false},
+	{IP{}, false},
+}
+
+func TestIPSetContains(t *testing.T) {
+	s := MustNewIPSet(MustParseCIDR("10.0.0.0/8"), IPv4(192, 168, 1, 5), MustParseCIDR("2001:db8::/32"))
+	for _, tt := range ipSetContainsTests {
+		if ok := s.Contains(tt.ip); ok != tt.ok {
+			t.Errorf("IPSet.Contains(%v) = %v, want %v", tt.ip, ok, tt.ok)
+		}
+	}
+}
+
+var ipSetOperationTests = []struct {
+	x          *IPSet
+	y          *IPSet
+	union      []string
+	intersect  []string
+	difference []string
+	overlaps   bool
+}{
+	{
+		MustNewIPSet(MustParseCIDR("10.0.0.0/24")),
+		MustNewIPSet(MustParseCIDR("10.0.0.128/25"), MustParseCIDR("10.0.1.0/24")),
+		[]string{"10.0.0.0/23"},
+		[]string{"10.0.0.128/25"},
+		[]string{"10.0.0.0/25"},
+		true,
+	},
+	{
+		MustNewIPSet(MustParseCIDR("10.0.0.0/24"), MustParseCIDR("2001:db8::/32")),
+		MustNewIPSet(IPv4(10, 0, 0, 7), MustParseCIDR("2001:db8:8000::/33")),
+		[]string{"10.0.0.0/24", "2001:db8::/32"},
+		[]string{"10.0.0.7/32", "2001:db8:8000::/33"},
+		[]string{"10.0.0.0/30", "10.0.0.4/31", "10.0.0.6/32", "10.0.0.8/29", "10.0.0.16/28", "10.0.0.32/27", "10.0.0.64/26", "10.0.0.128/25", "2001:db8::/33"},
+		true,
+	},
+	{
+		MustNewIPSet(MustParseCIDR("10.0.0.0/24")),
+		MustNewIPSet(MustParseCIDR("::a00:0/120")),
+		[]string{"10.0.0.0/24", "::a00:0/120"},
+		nil,
+		[]string{"10.0.0.0/24"},
+		false,
+	},
+}
+
+func TestIPSetOperations(t *testing.T) {
+	for _, tt := range ipSetOperationTests {
+		if out := prefixStrings(tt.x.Union(tt.y).Prefixes()); !reflect.DeepEqual(out, tt.union) {
+			t.Errorf("IPSet.Union(%v)(%v) = %v, want %v", tt.x.Prefixes(), tt.y.Prefixes(), out, tt.union)
+		}
+		if out := prefixStrings(tt.x.Intersect(tt.y).Prefixes()); !reflect.DeepEqual(out, tt.intersect) {
+			t.Errorf("IPSet.Intersect(%v)(%v) = %v, want %v", tt.x.Prefixes(), tt.y.Prefixes(), out, tt.intersect)
+		}
+		if out := prefixStrings(tt.x.Difference(tt.y).Prefixes()); !reflect.DeepEqual(out, tt.difference) {
+			t.Errorf("IPSet.Difference(%v)(%v) = %v, want %v", tt.x.Prefixes(), tt.y.Prefixes(), out, tt.difference)
+		}
+		if out := tt.x.Overlaps(tt.y); out != tt.overlaps {
+			t.Errorf("IPSet.Overlaps(%v)(%v) = %v, want %v", tt.x.Prefixes(), tt.y.Prefixes(), out, tt.overlaps)
+		}
+	}
+}
+
+var ipSetComplementTests = []struct {
+	in  *IPSet
+	out []string
+}{
+	{
+		MustNewIPSet(),
+		[]string{"0.0.0.0/0", "::/0"},
+	},
+	{
+		MustNewIPSet(MustParseCIDR("128.0.0.0/1"), MustParseCIDR("::/1")),
+		[]string{"0.0.0.0/1", "8000::/1"},
+	},
+	{
+		MustNewIPSet(MustParseCIDR("0.0.0.0/0"), MustParseCIDR("::/0")),
+		nil,
+	},
+}
+
+func TestIPSetComplement(t *testing.T) {
+	for _, tt := range ipSetComplementTests {
+		out := tt.in.Complement()
+		if prefixes := prefixStrings(out.Prefixes()); !reflect.DeepEqual(prefixes, tt.out) {
+			t.Errorf("IPSet.Complement(%v) = %v, want %v", tt.in.Prefixes(), prefixes, tt.out)
+		}
+		if !out.Complement().Equal(tt.in) {
+			t.Errorf("IPSet.Complement(IPSet.Complement(%v)) = %v", tt.in.Prefixes(), out.Complement().Prefixes())
+		}
+	}
+}
+
+func TestIPSetEqual(t *testing.T) {
+	x := MustNewIPSet(MustParseCIDR("10.0.0.0/25"), MustParseCIDR("10.0.0.128/25"))
+	y := MustNewIPSet(MustParseIPRangeInclusive("10.0.0.0", "10.0.0.255"))
+	if !x.Equal(y) {
+		t.Errorf("IPSet.Equal(%v)(%v) = false, want true", x.Ranges(), y.Ranges())
+	}
+	if x.Equal(MustNewIPSet(MustParseCIDR("10.0.0.0/25"))) {
+		t.Errorf("IPSet.Equal(%v)(10.0.0.0/25) = true, want false", x.Ranges())
+	}
+}
+
+func TestIPSetString(t *testing.T) {
+	s := MustNewIPSet(MustParseCIDR("10.0.0.0/30"), IPv4(10, 0, 0, 5), MustParseCIDR("2001:db8::/120"))
+	if want := "10.0.0.0-10.0.0.3, 10.0.0.5, 2001:db8::-2001:db8::ff"; s.String() != want {
+		t.Errorf("IPSet.String() = %v, want %v", s.String(), want)
+	}
+	if out := MustNewIPSet().String(); out != "" {
+		t.Errorf("IPSet.String() = %v, want empty string", out)
+	}
+}
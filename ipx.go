@@ -1,5 +1,7 @@
 package ipx
 
+import "strconv"
+
 // AddrError declares the address error
 type AddrError struct {
 	Err  string
@@ -26,10 +28,20 @@ type ParseError struct {
 
 	// Text is the malformed text string.
 	Text string
+
+	// Column is the 1-based position of the offending character
+	// in Text, or zero if it is unknown.
+	Column int
 }
 
 // Error ..
-func (e *ParseError) Error() string { return "invalid " + e.Type + ": " + e.Text }
+func (e *ParseError) Error() string {
+	s := "invalid " + e.Type + ": " + e.Text
+	if e.Column > 0 {
+		s += " (column " + strconv.Itoa(e.Column) + ")"
+	}
+	return s
+}
 
 // Timeout ..
 func (e *ParseError) Timeout() bool { return false }
@@ -0,0 +1,217 @@
+package ipx
+
+// IterOptions configures an IPIterator
+type IterOptions struct {
+	// Step is the distance between two consecutive addresses
+	// Zero means one, so every address is visited
+	Step Uint128
+
+	// Reverse visits the addresses in descending order
+	Reverse bool
+
+	// SkipReserved skips the network and broadcast addresses of IPv4
+	// networks and the Subnet-Router anycast address of IPv6 networks
+	// exactly as UsableIPNumber does
+	// It is only used by IPNet.Iter
+	SkipReserved bool
+}
+
+// IPIterator iterates over ip addresses lazily, so it can walk
+// huge networks in constant memory
+//
+//	it := ipNet.Iter(ipx.IterOptions{})
+//	for it.Next() {
+//		ip := it.IP()
+//	}
+type IPIterator struct {
+	segments []ipSegment
+	step     Uint128
+	reverse  bool
+	idx      int     // index of the current segment
+	next     Uint128 // next address in the current segment
+	cur      Addr
+}
+
+// ipSegment is a span of addresses in an address family
+type ipSegment struct {
+	span ipSpan
+	v4   bool
+}
+
+// newIPIterator returns an IPIterator over the segments in ascending order
+func newIPIterator(segments []ipSegment, opts IterOptions) *IPIterator {
+	it := &IPIterator{
+		segments: segments,
+		step:     opts.Step,
+		reverse:  opts.Reverse,
+	}
+	if it.step.IsZero() {
+		it.step = uint128One
+	}
+
+	if len(segments) == 0 {
+		return it
+	}
+	if it.reverse {
+		for i, j := 0, len(segments)-1; i < j; i, j = i+1, j-1 {
+			segments[i], segments[j] = segments[j], segments[i]
+		}
+		it.next = segments[0].span.last
+	} else {
+		it.next = segments[0].span.first
+	}
+	return it
+}
+
+// Next advances the iterator to the next ip address
+// It returns false when there are no more addresses
+func (it *IPIterator) Next() bool {
+	if it.idx >= len(it.segments) {
+		it.cur = Addr{}
+		return false
+	}
+
+	seg := it.segments[it.idx]
+	it.cur = AddrFromUint128(it.next, seg.v4)
+
+	if it.reverse {
+		it.retreat()
+	} else {
+		it.advance()
+	}
+	return true
+}
+
+// IP returns the current ip address
+func (it *IPIterator) IP() IP {
+	if !it.cur.IsValid() {
+		return IP{}
+	}
+	return it.cur.IP()
+}
+
+// Addr returns the current ip address as an Addr
+// Unlike IP, it does not allocate
+func (it *IPIterator) Addr() Addr {
+	return it.cur
+}
+
+// Walk calls fn for each remaining ip address
+// Walk stops if fn returns false
+func (it *IPIterator) Walk(fn func(ip IP) bool) {
+	for it.Next() {
+		if !fn(it.IP()) {
+			return
+		}
+	}
+}
+
+// advance moves next forward by step, continuing in the following
+// segments if the current segment is exhausted
+func (it *IPIterator) advance() {
+	span := it.segments[it.idx].span
+	remaining, _ := span.last.Sub(it.next)
+	if it.step.Cmp(remaining) <= 0 {
+		it.next, _ = it.next.Add(it.step)
+		return
+	}
+
+	carry, _ := it.step.Sub(remaining)
+	carry, _ = carry.Sub(uint128One)
+	for it.idx++; it.idx < len(it.segments); it.idx++ {
+		span = it.segments[it.idx].span
+		size, _ := span.last.Sub(span.first)
+		if carry.Cmp(size) <= 0 {
+			it.next, _ = span.first.Add(carry)
+			return
+		}
+		carry, _ = carry.Sub(size)
+		carry, _ = carry.Sub(uint128One)
+	}
+}
+
+// retreat moves next backward by step, continuing in the following
+// segments if the current segment is exhausted
+func (it *IPIterator) retreat() {
+	span := it.segments[it.idx].span
+	remaining, _ := it.next.Sub(span.first)
+	if it.step.Cmp(remaining) <= 0 {
+		it.next, _ = it.next.Sub(it.step)
+		return
+	}
+
+	carry, _ := it.step.Sub(remaining)
+	carry, _ = carry.Sub(uint128One)
+	for it.idx++; it.idx < len(it.segments); it.idx++ {
+		span = it.segments[it.idx].span
+		size, _ := span.last.Sub(span.first)
+		if carry.Cmp(size) <= 0 {
+			it.next, _ = span.last.Sub(carry)
+			return
+		}
+		carry, _ = carry.Sub(size)
+		carry, _ = carry.Sub(uint128One)
+	}
+}
+
+// Iter returns an iterator over the ip addresses in the network
+func (n *IPNet) Iter(opts IterOptions) *IPIterator {
+	network, host, v4 := n.uint128()
+	if n.IP.IP == nil {
+		return newIPIterator(nil, opts)
+	}
+	span := ipSpan{network, network.Or(host)}
+
+	// point-to-point networks have no reserved addresses
+	if opts.SkipReserved && host.Cmp(uint128One) > 0 {
+		span.first, _ = span.first.Add(uint128One)
+		if v4 {
+			span.last, _ = span.last.Sub(uint128One)
+		}
+	}
+
+	return newIPIterator([]ipSegment{{span, v4}}, opts)
+}
+
+// Iter returns an iterator over the ip addresses in IPRange
+func (i *IPRange) Iter(opts IterOptions) *IPIterator {
+	first, last, v4, ok := i.bounds()
+	if !ok {
+		return newIPIterator(nil, opts)
+	}
+	return newIPIterator([]ipSegment{{ipSpan{first, last}, v4}}, opts)
+}
+
+// Iter returns an iterator over the ip addresses in IPSet
+// IPv4 addresses come before IPv6 addresses
+func (s *IPSet) Iter(opts IterOptions) *IPIterator {
+	segments := make([]ipSegment, 0, len(s.v4)+len(s.v6))
+	for _, span := range s.v4 {
+		segments = append(segments, ipSegment{span, true})
+	}
+	for _, span := range s.v6 {
+		segments = append(segments, ipSegment{span, false})
+	}
+	return newIPIterator(segments, opts)
+}
+
+// IterNextN returns an iterator over the ip addresses until n'th next IP
+// exactly as GetAllNextN returns
+func (i IP) IterNextN(n uint32) *IPIterator {
+	if n == 0 {
+		return newIPIterator(nil, IterOptions{})
+	}
+
+	val, v4 := i.toUint128()
+	max := uint128Max.Rsh(uint(128 - addressBits(v4)))
+	first, _ := val.Add(uint128One)
+	first = first.And(max)
+	last, _ := val.Add(NewUint128(uint64(n)))
+	last = last.And(max)
+
+	// wrap around at the end of the address space
+	if last.Cmp(first) < 0 {
+		return newIPIterator([]ipSegment{{ipSpan{first, max}, v4}, {ipSpan{uint128Zero, last}, v4}}, IterOptions{})
+	}
+	return newIPIterator([]ipSegment{{ipSpan{first, last}, v4}}, IterOptions{})
+}
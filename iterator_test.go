@@ -0,0 +1,153 @@
+package ipx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func iterStrings(it *IPIterator) []string {
+	var out []string
+	for it.Next() {
+		out = append(out, it.IP().String())
+	}
+	return out
+}
+
+var ipNetIterTests = []struct {
+	in   *IPNet
+	opts IterOptions
+	out  []string
+	more bool // whether there are more addresses than out
+}{
+	{
+		MustParseCIDR("172.16.16.0/30"),
+		IterOptions{},
+		[]string{"172.16.16.0", "172.16.16.1", "172.16.16.2", "172.16.16.3"},
+		false,
+	},
+	{
+		MustParseCIDR("172.16.16.0/30"),
+		IterOptions{SkipReserved: true},
+		[]string{"172.16.16.1", "172.16.16.2"},
+		false,
+	},
+	{
+		MustParseCIDR("172.16.16.0/31"),
+		IterOptions{SkipReserved: true},
+		[]string{"172.16.16.0", "172.16.16.1"},
+		false,
+	},
+	{
+		MustParseCIDR("172.16.16.0/29"),
+		IterOptions{Reverse: true, Step: NewUint128(3)},
+		[]string{"172.16.16.7", "172.16.16.4", "172.16.16.1"},
+		false,
+	},
+	{
+		MustParseCIDR("2001:db8::/126"),
+		IterOptions{SkipReserved: true},
+		[]string{"2001:db8::1", "2001:db8::2", "2001:db8::3"},
+		false,
+	},
+	{
+		MustParseCIDR("2001:db8::/32"),
+		IterOptions{Step: Uint128{1 << 16, 0}},
+		[]string{"2001:db8::", "2001:db8:1::", "2001:db8:2::"},
+		true,
+	},
+	{
+		MustParseCIDR("::/0"),
+		IterOptions{Step: Uint128{1 << 63, 0}},
+		[]string{"::", "8000::"},
+		false,
+	},
+}
+
+func TestIPNetIter(t *testing.T) {
+	for _, tt := range ipNetIterTests {
+		it := tt.in.Iter(tt.opts)
+		var out []string
+		for i := 0; i < len(tt.out) && it.Next(); i++ {
+			out = append(out, it.IP().String())
+		}
+		if !reflect.DeepEqual(out, tt.out) || it.Next() != tt.more {
+			t.Errorf("IPNet.Iter(%v, %+v) = %v, want %v", tt.in, tt.opts, out, tt.out)
+		}
+	}
+}
+
+func TestIPRangeIter(t *testing.T) {
+	in := MustParseIPRangeInclusive("255.255.255.253", "255.255.255.255")
+	want := []string{"255.255.255.253", "255.255.255.254", "255.255.255.255"}
+	if out := iterStrings(in.Iter(IterOptions{})); !reflect.DeepEqual(out, want) {
+		t.Errorf("IPRange.Iter(%v) = %v, want %v", in, out, want)
+	}
+
+	want = []string{"255.255.255.255", "255.255.255.254", "255.255.255.253"}
+	if out := iterStrings(in.Iter(IterOptions{Reverse: true})); !reflect.DeepEqual(out, want) {
+		t.Errorf("IPRange.Iter(%v, reverse) = %v, want %v", in, out, want)
+	}
+
+	empty := MustParseIPRange("10.0.0.1", "10.0.0.1")
+	if out := iterStrings(empty.Iter(IterOptions{})); out != nil {
+		t.Errorf("IPRange.Iter(%v) = %v, want nothing", empty, out)
+	}
+}
+
+var ipSetIterTests = []struct {
+	opts IterOptions
+	out  []string
+}{
+	{
+		IterOptions{},
+		[]string{"10.0.0.1", "10.0.0.2", "10.0.0.10", "2001:db8::1", "2001:db8::2"},
+	},
+	{
+		IterOptions{Step: NewUint128(2)},
+		[]string{"10.0.0.1", "10.0.0.10", "2001:db8::2"},
+	},
+	{
+		IterOptions{Step: NewUint128(2), Reverse: true},
+		[]string{"2001:db8::2", "10.0.0.10", "10.0.0.1"},
+	},
+	{
+		IterOptions{Step: NewUint128(4)},
+		[]string{"10.0.0.1", "2001:db8::2"},
+	},
+}
+
+func TestIPSetIter(t *testing.T) {
+	s := MustNewIPSet(
+		MustParseIPRangeInclusive("10.0.0.1", "10.0.0.2"),
+		IPv4(10, 0, 0, 10),
+		MustParseIPRangeInclusive("2001:db8::1", "2001:db8::2"),
+	)
+	for _, tt := range ipSetIterTests {
+		if out := iterStrings(s.Iter(tt.opts)); !reflect.DeepEqual(out, tt.out) {
+			t.Errorf("IPSet.Iter(%+v) = %v, want %v", tt.opts, out, tt.out)
+		}
+	}
+}
+
+func TestIterNextN(t *testing.T) {
+	for _, tt := range getAllNextNTests {
+		var out []string
+		for _, ip := range tt.in.GetAllNextN(tt.n) {
+			out = append(out, ip.String())
+		}
+		if it := iterStrings(tt.in.IterNextN(tt.n)); !reflect.DeepEqual(it, out) {
+			t.Errorf("IP.IterNextN(%v)(%v) = %v, want %v", tt.in, tt.n, it, out)
+		}
+	}
+}
+
+func TestIPIteratorWalk(t *testing.T) {
+	var out []string
+	MustParseCIDR("10.0.0.0/8").Iter(IterOptions{}).Walk(func(ip IP) bool {
+		out = append(out, ip.String())
+		return len(out) < 2
+	})
+	if want := []string{"10.0.0.0", "10.0.0.1"}; !reflect.DeepEqual(out, want) {
+		t.Errorf("IPIterator.Walk(10.0.0.0/8) = %v, want %v", out, want)
+	}
+}
@@ -0,0 +1,348 @@
+package ipx
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+)
+
+// Error definitions
+var (
+	ErrInvalidEncoding = errors.New("invalid binary encoding")
+)
+
+// MarshalText implements the encoding.TextMarshaler interface
+// The encoding is the CIDR notation like "10.0.0.0/8"
+// The zero IPNet is encoded as an empty string
+func (n IPNet) MarshalText() ([]byte, error) {
+	if n.IP.IP == nil && n.Mask.IPMask == nil {
+		return []byte(""), nil
+	}
+	p, ok := PrefixFromIPNet(&n)
+	if !ok {
+		return nil, &AddrError{Err: "invalid network", Addr: n.String()}
+	}
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface
+// It accepts the CIDR notation as ParseCIDR does
+// An empty string is decoded as the zero IPNet
+func (n *IPNet) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*n = IPNet{}
+		return nil
+	}
+	_, ipNet, err := ParseCIDR(string(text))
+	if err != nil {
+		return err
+	}
+	*n = *ipNet
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface
+// The encoding is the text encoding as a JSON string
+func (n IPNet) MarshalJSON() ([]byte, error) {
+	return marshalJSONText(n)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+// JSON null leaves n unchanged
+func (n *IPNet) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONText(data, n)
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface
+// The encoding is the 4 or 16 bytes of the network number
+// followed by one byte of the prefix length
+// The zero IPNet is encoded as no bytes
+func (n IPNet) MarshalBinary() ([]byte, error) {
+	if n.IP.IP == nil && n.Mask.IPMask == nil {
+		return []byte{}, nil
+	}
+	p, ok := PrefixFromIPNet(&n)
+	if !ok {
+		return nil, &AddrError{Err: "invalid network", Addr: n.String()}
+	}
+	return append(p.addr.NetIP(), byte(p.bits)), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface
+// It returns ErrInvalidEncoding if data is not a valid encoding
+func (n *IPNet) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		*n = IPNet{}
+		return nil
+	}
+	if len(data) != IPv4len+1 && len(data) != IPv6len+1 {
+		return ErrInvalidEncoding
+	}
+
+	a, _ := AddrFromNetIP(net.IP(data[:len(data)-1]))
+	if len(data) == IPv6len+1 {
+		a = AddrFrom16(a.As16())
+	}
+	p, err := a.Prefix(int(data[len(data)-1]))
+	if err != nil {
+		return ErrInvalidEncoding
+	}
+	*n = *p.IPNet()
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface
+// The encoding is the first and the last ip like "10.0.0.1-10.0.0.9"
+// An empty IPRange is encoded as an empty string
+func (i IPRange) MarshalText() ([]byte, error) {
+	r, ok := AddrRangeFromIPRange(&i)
+	if !ok {
+		return []byte(""), nil
+	}
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface
+// It accepts the first and the last ip separated by a hyphen
+// like "10.0.0.1-10.0.0.9" and decodes them as an IPRange
+// whose both boundaries are included
+// An empty string is decoded as the zero IPRange
+func (i *IPRange) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*i = IPRange{}
+		return nil
+	}
+
+	s := string(text)
+	sep := strings.IndexByte(s, '-')
+	if sep < 0 {
+		return &ParseError{Type: "IP range", Text: s}
+	}
+	ipRange, err := ParseIPRangeInclusive(strings.TrimSpace(s[:sep]), strings.TrimSpace(s[sep+1:]))
+	if err != nil {
+		return err
+	}
+	*i = *ipRange
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface
+// The encoding is the text encoding as a JSON string
+func (i IPRange) MarshalJSON() ([]byte, error) {
+	return marshalJSONText(i)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+// JSON null leaves i unchanged
+func (i *IPRange) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONText(data, i)
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface
+// The encoding is the 4 or 16 bytes of the first ip
+// followed by the same number of bytes of the last ip
+// An empty IPRange is encoded as no bytes
+func (i IPRange) MarshalBinary() ([]byte, error) {
+	r, ok := AddrRangeFromIPRange(&i)
+	if !ok {
+		return []byte{}, nil
+	}
+	return append(r.first.NetIP(), r.last.NetIP()...), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface
+// It returns ErrInvalidEncoding if data is not a valid encoding
+func (i *IPRange) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		*i = IPRange{}
+		return nil
+	}
+	if len(data) != 2*IPv4len && len(data) != 2*IPv6len {
+		return ErrInvalidEncoding
+	}
+
+	first := append(net.IP(nil), data[:len(data)/2]...)
+	last := append(net.IP(nil), data[len(data)/2:]...)
+	if first.To4() != nil != (last.To4() != nil) {
+		return ErrInvalidEncoding
+	}
+	*i = *NewIPRangeInclusive(IP{first}, IP{last})
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface
+// IPv4 masks are encoded in dotted decimal form like "255.255.255.0"
+// and IPv6 masks in IPv6 address form like "ffff:ffff:ffff:ffff::"
+// The zero IPMask is encoded as an empty string
+func (m IPMask) MarshalText() ([]byte, error) {
+	switch len(m.IPMask) {
+	case 0:
+		return []byte(""), nil
+	case IPv4len, IPv6len:
+		return []byte(net.IP(m.IPMask).String()), nil
+	}
+	return nil, &AddrError{Err: "invalid mask", Addr: m.String()}
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface
+// It accepts the forms MarshalText returns and the hexadecimal form
+// like "ffffff00" IPMask.String returns
+// An empty string is decoded as the zero IPMask
+func (m *IPMask) UnmarshalText(text []byte) error {
+	s := string(text)
+	if s == "" {
+		*m = IPMask{}
+		return nil
+	}
+
+	if b, err := hex.DecodeString(s); err == nil && (len(b) == IPv4len || len(b) == IPv6len) {
+		*m = IPMask{net.IPMask(b)}
+		return nil
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return &ParseError{Type: "IP mask", Text: s}
+	}
+	if !strings.Contains(s, ":") {
+		ip = ip.To4()
+	}
+	*m = IPMask{net.IPMask(ip)}
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface
+// The encoding is the text encoding as a JSON string
+func (m IPMask) MarshalJSON() ([]byte, error) {
+	return marshalJSONText(m)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+// JSON null leaves m unchanged
+func (m *IPMask) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONText(data, m)
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface
+// The encoding is the 4 or 16 bytes of the mask
+func (m IPMask) MarshalBinary() ([]byte, error) {
+	switch len(m.IPMask) {
+	case 0, IPv4len, IPv6len:
+		return append([]byte{}, m.IPMask...), nil
+	}
+	return nil, &AddrError{Err: "invalid mask", Addr: m.String()}
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface
+// It returns ErrInvalidEncoding if data is not a valid encoding
+func (m *IPMask) UnmarshalBinary(data []byte) error {
+	switch len(data) {
+	case 0:
+		*m = IPMask{}
+	case IPv4len, IPv6len:
+		*m = IPMask{append(net.IPMask(nil), data...)}
+	default:
+		return ErrInvalidEncoding
+	}
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface
+// The encoding is the ip with its zone like "fe80::1%eth0"
+// The zero IPAddr is encoded as an empty string
+func (a IPAddr) MarshalText() ([]byte, error) {
+	if a.IP.IP == nil {
+		return []byte(""), nil
+	}
+	addr, ok := AddrFromIP(a.IP)
+	if !ok {
+		return nil, &AddrError{Err: "invalid IP address", Addr: a.String()}
+	}
+	return []byte(addr.WithZone(a.Zone).String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface
+// It accepts an ip with an optional IPv6 zone like "fe80::1%eth0"
+// An empty string is decoded as the zero IPAddr
+func (a *IPAddr) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*a = IPAddr{}
+		return nil
+	}
+	addr, err := ParseAddr(string(text))
+	if err != nil {
+		return &ParseError{Type: "IP address", Text: string(text)}
+	}
+	*a = *addr.IPAddr()
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface
+// The encoding is the text encoding as a JSON string
+func (a IPAddr) MarshalJSON() ([]byte, error) {
+	return marshalJSONText(a)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+// JSON null leaves a unchanged
+func (a *IPAddr) UnmarshalJSON(data []byte) error {
+	return unmarshalJSONText(data, a)
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface
+// The encoding is the 4 bytes of an IPv4 address, or the 16 bytes of
+// an IPv6 address followed by its zone
+// The zero IPAddr is encoded as no bytes
+func (a IPAddr) MarshalBinary() ([]byte, error) {
+	if a.IP.IP == nil {
+		return []byte{}, nil
+	}
+	addr, ok := AddrFromIP(a.IP)
+	if !ok {
+		return nil, &AddrError{Err: "invalid IP address", Addr: a.String()}
+	}
+	if addr.Is4() {
+		return addr.NetIP(), nil
+	}
+	return append(addr.NetIP(), a.Zone...), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface
+// It returns ErrInvalidEncoding if data is not a valid encoding
+func (a *IPAddr) UnmarshalBinary(data []byte) error {
+	switch {
+	case len(data) == 0:
+		*a = IPAddr{}
+	case len(data) == IPv4len:
+		*a = IPAddr{IP: IP{append(net.IP(nil), data...)}}
+	case len(data) >= IPv6len:
+		*a = IPAddr{IP: IP{append(net.IP(nil), data[:IPv6len]...)}, Zone: string(data[IPv6len:])}
+	default:
+		return ErrInvalidEncoding
+	}
+	return nil
+}
+
+// marshalJSONText returns the text encoding of m as a JSON string
+func marshalJSONText(m interface{ MarshalText() ([]byte, error) }) ([]byte, error) {
+	text, err := m.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+// unmarshalJSONText decodes the JSON string data with the text decoding of u
+// JSON null is ignored
+func unmarshalJSONText(data []byte, u interface{ UnmarshalText([]byte) error }) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return u.UnmarshalText([]byte(s))
+}
@@ -0,0 +1,208 @@
+package ipx
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+var ipNetMarshalTests = []struct {
+	in     IPNet
+	text   string
+	binary []byte
+}{
+	{*MustParseCIDR("10.0.0.0/8"), "10.0.0.0/8", []byte{10, 0, 0, 0, 8}},
+	{IPNet{IP: IPv4(192, 0, 2, 0), Mask: CIDRMask(24, 32)}, "192.0.2.0/24", []byte{192, 0, 2, 0, 24}},
+	{*MustParseCIDR("2001:db8::/32"), "2001:db8::/32", []byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 32}},
+	{IPNet{}, "", []byte{}},
+}
+
+func TestIPNetMarshal(t *testing.T) {
+	for _, tt := range ipNetMarshalTests {
+		text, err := tt.in.MarshalText()
+		if err != nil || string(text) != tt.text {
+			t.Errorf("IPNet.MarshalText(%v) = %s, %v, want %v", tt.in, text, err, tt.text)
+		}
+		var out IPNet
+		if err := out.UnmarshalText(text); err != nil || out.String() != tt.in.String() {
+			t.Errorf("IPNet.UnmarshalText(%s) = %v, %v, want %v", text, out.String(), err, tt.in.String())
+		}
+
+		b, err := tt.in.MarshalBinary()
+		if err != nil || !bytes.Equal(b, tt.binary) {
+			t.Errorf("IPNet.MarshalBinary(%v) = %v, %v, want %v", tt.in, b, err, tt.binary)
+		}
+		out = IPNet{}
+		if err := out.UnmarshalBinary(b); err != nil || out.String() != tt.in.String() {
+			t.Errorf("IPNet.UnmarshalBinary(%v) = %v, %v, want %v", b, out.String(), err, tt.in.String())
+		}
+	}
+
+	nonCanonical := IPNet{IP: IPv4(10, 0, 0, 0), Mask: IPv4Mask(255, 0, 255, 0)}
+	if _, err := nonCanonical.MarshalText(); err == nil {
+		t.Errorf("IPNet.MarshalText(%v) = nil error, want error", nonCanonical.String())
+	}
+	var out IPNet
+	if err := out.UnmarshalBinary([]byte{10, 0, 0, 0, 33}); err != ErrInvalidEncoding {
+		t.Errorf("IPNet.UnmarshalBinary(10.0.0.0/33) = %v, want %v", err, ErrInvalidEncoding)
+	}
+	if err := out.UnmarshalText([]byte("10.0.0.0")); err == nil {
+		t.Errorf("IPNet.UnmarshalText(10.0.0.0) = nil error, want error")
+	}
+}
+
+var ipRangeMarshalTests = []struct {
+	in     *IPRange
+	text   string
+	binary []byte
+}{
+	{MustParseIPRangeInclusive("10.0.0.9", "10.0.0.1"), "10.0.0.1-10.0.0.9", []byte{10, 0, 0, 1, 10, 0, 0, 9}},
+	{MustParseIPRange("10.0.0.1", "10.0.0.10"), "10.0.0.1-10.0.0.9", []byte{10, 0, 0, 1, 10, 0, 0, 9}},
+	{MustParseIPRangeInclusive("::", "::1"), "::-::1", append(make([]byte, 31), 1)},
+	{&IPRange{}, "", []byte{}},
+}
+
+func TestIPRangeMarshal(t *testing.T) {
+	for _, tt := range ipRangeMarshalTests {
+		text, err := tt.in.MarshalText()
+		if err != nil || string(text) != tt.text {
+			t.Errorf("IPRange.MarshalText(%v) = %s, %v, want %v", tt.in, text, err, tt.text)
+		}
+		var out IPRange
+		if err := out.UnmarshalText(text); err != nil || out.String() != tt.in.String() {
+			t.Errorf("IPRange.UnmarshalText(%s) = %v, %v, want %v", text, out.String(), err, tt.in)
+		}
+
+		b, err := tt.in.MarshalBinary()
+		if err != nil || !bytes.Equal(b, tt.binary) {
+			t.Errorf("IPRange.MarshalBinary(%v) = %v, %v, want %v", tt.in, b, err, tt.binary)
+		}
+		out = IPRange{}
+		if err := out.UnmarshalBinary(b); err != nil || out.String() != tt.in.String() {
+			t.Errorf("IPRange.UnmarshalBinary(%v) = %v, %v, want %v", b, out.String(), err, tt.in)
+		}
+	}
+
+	var out IPRange
+	for _, s := range []string{"10.0.0.1", "10.0.0.1-::1", "10.0.0.1-x"} {
+		if err := out.UnmarshalText([]byte(s)); err == nil {
+			t.Errorf("IPRange.UnmarshalText(%v) = nil error, want error", s)
+		}
+	}
+	if err := out.UnmarshalText([]byte(" 10.0.0.1 - 10.0.0.9 ")); err != nil || out.String() != "10.0.0.1-10.0.0.9" {
+		t.Errorf("IPRange.UnmarshalText(10.0.0.1 - 10.0.0.9) = %v, %v, want 10.0.0.1-10.0.0.9", out.String(), err)
+	}
+}
+
+var ipMaskMarshalTests = []struct {
+	in   IPMask
+	text string
+}{
+	{CIDRMask(24, 32), "255.255.255.0"},
+	{IPv4Mask(255, 0, 255, 0), "255.0.255.0"},
+	{CIDRMask(64, 128), "ffff:ffff:ffff:ffff::"},
+	{CIDRMask(0, 128), "::"},
+	{IPMask{}, ""},
+}
+
+func TestIPMaskMarshal(t *testing.T) {
+	for _, tt := range ipMaskMarshalTests {
+		text, err := tt.in.MarshalText()
+		if err != nil || string(text) != tt.text {
+			t.Errorf("IPMask.MarshalText(%v) = %s, %v, want %v", tt.in, text, err, tt.text)
+		}
+		var out IPMask
+		if err := out.UnmarshalText(text); err != nil || !bytes.Equal(out.IPMask, tt.in.IPMask) {
+			t.Errorf("IPMask.UnmarshalText(%s) = %v, %v, want %v", text, out, err, tt.in)
+		}
+
+		b, _ := tt.in.MarshalBinary()
+		out = IPMask{}
+		if err := out.UnmarshalBinary(b); err != nil || !bytes.Equal(out.IPMask, tt.in.IPMask) {
+			t.Errorf("IPMask.UnmarshalBinary(%v) = %v, %v, want %v", b, out, err, tt.in)
+		}
+	}
+
+	var out IPMask
+	if err := out.UnmarshalText([]byte("ffffff00")); err != nil || !bytes.Equal(out.IPMask, CIDRMask(24, 32).IPMask) {
+		t.Errorf("IPMask.UnmarshalText(ffffff00) = %v, %v, want ffffff00", out, err)
+	}
+	if err := out.UnmarshalBinary([]byte{255, 255, 255}); err != ErrInvalidEncoding {
+		t.Errorf("IPMask.UnmarshalBinary(3 bytes) = %v, want %v", err, ErrInvalidEncoding)
+	}
+}
+
+var ipAddrMarshalTests = []struct {
+	in     IPAddr
+	text   string
+	binary []byte
+}{
+	{IPAddr{IP: IPv4(192, 0, 2, 1)}, "192.0.2.1", []byte{192, 0, 2, 1}},
+	{IPAddr{IP: MustParseIP("fe80::1"), Zone: "eth0"}, "fe80::1%eth0", []byte{0xfe, 0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 'e', 't', 'h', '0'}},
+	{IPAddr{IP: MustParseIP("2001:db8::1")}, "2001:db8::1", []byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}},
+	{IPAddr{}, "", []byte{}},
+}
+
+func TestIPAddrMarshal(t *testing.T) {
+	for _, tt := range ipAddrMarshalTests {
+		text, err := tt.in.MarshalText()
+		if err != nil || string(text) != tt.text {
+			t.Errorf("IPAddr.MarshalText(%v) = %s, %v, want %v", tt.in.String(), text, err, tt.text)
+		}
+		var out IPAddr
+		if err := out.UnmarshalText(text); err != nil || out.String() != tt.in.String() {
+			t.Errorf("IPAddr.UnmarshalText(%s) = %v, %v, want %v", text, out.String(), err, tt.in.String())
+		}
+
+		b, err := tt.in.MarshalBinary()
+		if err != nil || !bytes.Equal(b, tt.binary) {
+			t.Errorf("IPAddr.MarshalBinary(%v) = %v, %v, want %v", tt.in.String(), b, err, tt.binary)
+		}
+		out = IPAddr{}
+		if err := out.UnmarshalBinary(b); err != nil || out.String() != tt.in.String() {
+			t.Errorf("IPAddr.UnmarshalBinary(%v) = %v, %v, want %v", b, out.String(), err, tt.in.String())
+		}
+	}
+}
+
+type marshalConfig struct {
+	Network IPNet    `json:"network"`
+	Range   *IPRange `json:"range"`
+	Mask    IPMask   `json:"mask"`
+	Gateway IPAddr   `json:"gateway"`
+	IP      IP       `json:"ip"`
+}
+
+func TestMarshalJSON(t *testing.T) {
+	in := marshalConfig{
+		Network: *MustParseCIDR("10.0.0.0/8"),
+		Range:   MustParseIPRangeInclusive("10.0.0.1", "10.0.0.9"),
+		Mask:    CIDRMask(8, 32),
+		Gateway: IPAddr{IP: MustParseIP("fe80::1"), Zone: "eth0"},
+		IP:      IPv4(10, 0, 0, 1),
+	}
+	want := `{"network":"10.0.0.0/8","range":"10.0.0.1-10.0.0.9","mask":"255.0.0.0","gateway":"fe80::1%eth0","ip":"10.0.0.1"}`
+
+	b, err := json.Marshal(in)
+	if err != nil || string(b) != want {
+		t.Fatalf("json.Marshal() = %s, %v, want %v", b, err, want)
+	}
+
+	var out marshalConfig
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("json.Unmarshal(%s) = %v", b, err)
+	}
+	if out.Network.String() != in.Network.String() || out.Range.String() != in.Range.String() ||
+		!reflect.DeepEqual(out.Mask, in.Mask) || out.Gateway.String() != in.Gateway.String() || !out.IP.Equal(in.IP) {
+		t.Errorf("json.Unmarshal(%s) = %+v, want %+v", b, out, in)
+	}
+
+	out = marshalConfig{Range: in.Range}
+	if err := json.Unmarshal([]byte(`{"range":null,"network":null}`), &out); err != nil || out.Range != nil {
+		t.Errorf("json.Unmarshal(null) = %v, %v, want nil range", out.Range, err)
+	}
+	if err := json.Unmarshal([]byte(`{"network":"10.0.0.0/33"}`), &out); err == nil {
+		t.Errorf("json.Unmarshal(10.0.0.0/33) = nil error, want error")
+	}
+}
@@ -0,0 +1,177 @@
+//go:build go1.18
+// +build go1.18
+
+package ipx
+
+import "net/netip"
+
+// AddrFromNetipAddr returns the Addr of ip with its zone
+// IPv4-mapped IPv6 addresses are kept as IPv6 addresses
+// It returns the zero Addr if ip is not valid
+func AddrFromNetipAddr(ip netip.Addr) Addr {
+	switch {
+	case ip.Is4():
+		return AddrFrom4(ip.As4())
+	case ip.Is6():
+		return AddrFrom16(ip.As16()).WithZone(ip.Zone())
+	}
+	return Addr{}
+}
+
+// NetipAddr returns a as a netip.Addr with its zone
+// It returns the zero netip.Addr for the zero Addr
+func (a Addr) NetipAddr() netip.Addr {
+	switch {
+	case a.Is4():
+		return netip.AddrFrom4(a.As4())
+	case a.Is6():
+		return netip.AddrFrom16(a.As16()).WithZone(a.Zone())
+	}
+	return netip.Addr{}
+}
+
+// PrefixFromNetipPrefix returns the Prefix of p
+// The host bits of p are cleared
+// It returns the zero Prefix if p is not valid
+func PrefixFromNetipPrefix(p netip.Prefix) Prefix {
+	if !p.IsValid() {
+		return Prefix{}
+	}
+	return PrefixFrom(AddrFromNetipAddr(p.Addr()), p.Bits())
+}
+
+// NetipPrefix returns p as a netip.Prefix
+// It returns the zero netip.Prefix for the zero Prefix
+func (p Prefix) NetipPrefix() netip.Prefix {
+	if !p.IsValid() {
+		return netip.Prefix{}
+	}
+	return netip.PrefixFrom(p.addr.NetipAddr(), p.bits)
+}
+
+// IPFromNetipAddr returns ip as an IP without its zone
+// It returns the zero IP if ip is not valid
+func IPFromNetipAddr(ip netip.Addr) IP {
+	return AddrFromNetipAddr(ip).IP()
+}
+
+// NetipAddr returns i as a netip.Addr
+// IPv4 addresses in 16-byte form are returned as IPv4 addresses
+// It returns false if i is not a valid IP address
+func (i IP) NetipAddr() (netip.Addr, bool) {
+	a, ok := AddrFromIP(i)
+	return a.NetipAddr(), ok
+}
+
+// IPNetFromNetipPrefix returns p as an IPNet
+// The host bits of p are cleared as ParseCIDR does
+// It returns nil if p is not valid
+func IPNetFromNetipPrefix(p netip.Prefix) *IPNet {
+	return PrefixFromNetipPrefix(p).IPNet()
+}
+
+// NetipPrefix returns n as a netip.Prefix
+// It returns false if n is not a valid network in CIDR notation
+func (n *IPNet) NetipPrefix() (netip.Prefix, bool) {
+	p, ok := PrefixFromIPNet(n)
+	return p.NetipPrefix(), ok
+}
+
+// IPRangeFromNetipAddrs returns the IPRange between x and y
+// whose both boundaries are included
+// Order is not important and the zones are ignored
+// It returns ErrInvalidIP if x or y is not valid and ErrMixedIPFamily
+// if x and y are not in the same address family
+func IPRangeFromNetipAddrs(x, y netip.Addr) (*IPRange, error) {
+	if !x.IsValid() || !y.IsValid() {
+		return nil, ErrInvalidIP
+	}
+	xIP, yIP := IPFromNetipAddr(x.Unmap()), IPFromNetipAddr(y.Unmap())
+	if xIP.IsV4() != yIP.IsV4() {
+		return nil, ErrMixedIPFamily
+	}
+	return NewIPRangeInclusive(xIP, yIP), nil
+}
+
+// NetipAddrs returns the first and the last ip in IPRange as netip.Addrs
+// It returns false if the IPRange is empty or its boundaries
+// are not in the same address family
+func (i *IPRange) NetipAddrs() (first, last netip.Addr, ok bool) {
+	r, ok := AddrRangeFromIPRange(i)
+	return r.first.NetipAddr(), r.last.NetipAddr(), ok
+}
+
+// NetipPrefixes returns the minimal list of CIDR prefixes covering IPRange
+func (i *IPRange) NetipPrefixes() []netip.Prefix {
+	first, last, v4, ok := i.bounds()
+	if !ok {
+		return nil
+	}
+	return appendNetipPrefixes(nil, ipSpan{first, last}, v4)
+}
+
+// NetipPrefixes returns the minimal list of CIDR prefixes covering IPSet
+// as Prefixes does
+func (s *IPSet) NetipPrefixes() []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, span := range s.v4 {
+		prefixes = appendNetipPrefixes(prefixes, span, true)
+	}
+	for _, span := range s.v6 {
+		prefixes = appendNetipPrefixes(prefixes, span, false)
+	}
+	return prefixes
+}
+
+// IPAddrFromNetipAddr returns ip as an IPAddr with its zone
+// It returns nil if ip is not valid
+func IPAddrFromNetipAddr(ip netip.Addr) *IPAddr {
+	if !ip.IsValid() {
+		return nil
+	}
+	return AddrFromNetipAddr(ip).IPAddr()
+}
+
+// NetipAddr returns a as a netip.Addr with its zone
+// It returns false if a is not a valid IP address
+func (a *IPAddr) NetipAddr() (netip.Addr, bool) {
+	addr, ok := AddrFromIP(a.IP)
+	return addr.WithZone(a.Zone).NetipAddr(), ok
+}
+
+// IPAddrFromNetipAddrPort returns the address of ap as an IPAddr
+// with its zone and the port of ap
+// It returns nil if ap is not valid
+func IPAddrFromNetipAddrPort(ap netip.AddrPort) (*IPAddr, uint16) {
+	return IPAddrFromNetipAddr(ap.Addr()), ap.Port()
+}
+
+// NetipAddrPort returns a and port as a netip.AddrPort
+// It returns false if a is not a valid IP address
+func (a *IPAddr) NetipAddrPort(port uint16) (netip.AddrPort, bool) {
+	addr, ok := a.NetipAddr()
+	return netip.AddrPortFrom(addr, port), ok
+}
+
+// appendNetipPrefixes appends the minimal list of CIDR prefixes
+// covering span to dst
+func appendNetipPrefixes(dst []netip.Prefix, span ipSpan, v4 bool) []netip.Prefix {
+	span.walkPrefixes(v4, func(network Uint128, ones int) {
+		dst = append(dst, netip.PrefixFrom(AddrFromUint128(network, v4).NetipAddr(), ones))
+	})
+	return dst
+}
+
+// netipSpan returns the span of the netip.Addr or netip.Prefix elem
+// IPv4-mapped IPv6 addresses are treated as IPv4 addresses as IP does
+func netipSpan(elem interface{}) (span ipSpan, v4 bool, ok bool) {
+	switch e := elem.(type) {
+	case netip.Addr:
+		a := AddrFromNetipAddr(e.Unmap())
+		return ipSpan{a.u, a.u}, a.Is4(), a.IsValid()
+	case netip.Prefix:
+		p := PrefixFromNetipPrefix(e)
+		return ipSpan{p.addr.u, p.LastAddr().u}, p.addr.Is4(), p.IsValid()
+	}
+	return ipSpan{}, false, false
+}
@@ -0,0 +1,9 @@
+//go:build !go1.18
+// +build !go1.18
+
+package ipx
+
+// netipSpan returns false since net/netip requires go 1.18 or later
+func netipSpan(elem interface{}) (span ipSpan, v4 bool, ok bool) {
+	return ipSpan{}, false, false
+}
@@ -0,0 +1,156 @@
+//go:build go1.18
+// +build go1.18
+
+package ipx
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestNetipAddr(t *testing.T) {
+	for _, s := range []string{"192.0.2.1", "2001:db8::68", "fe80::1%eth0", "::ffff:192.0.2.1"} {
+		ip := netip.MustParseAddr(s)
+		a := AddrFromNetipAddr(ip)
+		if out := a.NetipAddr(); out != ip {
+			t.Errorf("AddrFromNetipAddr(%v).NetipAddr() = %v, want %v", ip, out, ip)
+		}
+	}
+
+	if a := AddrFromNetipAddr(netip.Addr{}); a.IsValid() || a.NetipAddr().IsValid() {
+		t.Errorf("AddrFromNetipAddr(invalid) = %v, want invalid", a)
+	}
+}
+
+var netipIPTests = []struct {
+	in  IP
+	out string
+	ok  bool
+}{
+	{IPv4(192, 0, 2, 1), "192.0.2.1", true},
+	{FromInt(0xc0000201), "192.0.2.1", true},
+	{MustParseIP("2001:db8::68"), "2001:db8::68", true},
+	{IP{}, "invalid IP", false},
+}
+
+func TestNetipIP(t *testing.T) {
+	for _, tt := range netipIPTests {
+		out, ok := tt.in.NetipAddr()
+		if ok != tt.ok || out.String() != tt.out {
+			t.Errorf("IP.NetipAddr(%v) = %v, %v, want %v, %v", tt.in, out, ok, tt.out, tt.ok)
+		}
+		if ip := IPFromNetipAddr(out); !ip.Equal(tt.in) {
+			t.Errorf("IPFromNetipAddr(%v) = %v, want %v", out, ip, tt.in)
+		}
+	}
+
+	if ip := IPFromNetipAddr(netip.MustParseAddr("fe80::1%eth0")); ip.String() != "fe80::1" {
+		t.Errorf("IPFromNetipAddr(fe80::1%%eth0) = %v, want fe80::1", ip)
+	}
+}
+
+var netipPrefixTests = []struct {
+	in  string
+	out string
+}{
+	{"10.1.2.3/8", "10.0.0.0/8"},
+	{"0.0.0.0/0", "0.0.0.0/0"},
+	{"2001:db8::1/128", "2001:db8::1/128"},
+	{"2001:db8:1::/32", "2001:db8::/32"},
+}
+
+func TestNetipPrefix(t *testing.T) {
+	for _, tt := range netipPrefixTests {
+		n := IPNetFromNetipPrefix(netip.MustParsePrefix(tt.in))
+		if n.String() != tt.out {
+			t.Errorf("IPNetFromNetipPrefix(%v) = %v, want %v", tt.in, n, tt.out)
+		}
+		if p, ok := n.NetipPrefix(); !ok || p.String() != tt.out {
+			t.Errorf("IPNet.NetipPrefix(%v) = %v, %v, want %v", n, p, ok, tt.out)
+		}
+		if p := PrefixFromNetipPrefix(netip.MustParsePrefix(tt.in)); p.NetipPrefix().String() != tt.out {
+			t.Errorf("PrefixFromNetipPrefix(%v) = %v, want %v", tt.in, p, tt.out)
+		}
+	}
+
+	if n := IPNetFromNetipPrefix(netip.Prefix{}); n != nil {
+		t.Errorf("IPNetFromNetipPrefix(invalid) = %v, want nil", n)
+	}
+	if p, ok := (&IPNet{IP: IPv4(10, 0, 0, 0), Mask: IPv4Mask(255, 0, 255, 0)}).NetipPrefix(); ok {
+		t.Errorf("IPNet.NetipPrefix(non-canonical) = %v, want false", p)
+	}
+}
+
+func TestNetipIPRange(t *testing.T) {
+	r, err := IPRangeFromNetipAddrs(netip.MustParseAddr("10.0.0.6"), netip.MustParseAddr("::ffff:10.0.0.1"))
+	if err != nil || r.FirstIP().String() != "10.0.0.1" || r.LastIP().String() != "10.0.0.6" {
+		t.Fatalf("IPRangeFromNetipAddrs(10.0.0.6, ::ffff:10.0.0.1) = %v, %v, want 10.0.0.1-10.0.0.6", r, err)
+	}
+	if first, last, ok := r.NetipAddrs(); !ok || first.String() != "10.0.0.1" || last.String() != "10.0.0.6" {
+		t.Errorf("IPRange.NetipAddrs() = %v, %v, %v, want 10.0.0.1, 10.0.0.6", first, last, ok)
+	}
+
+	want := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.1/32"),
+		netip.MustParsePrefix("10.0.0.2/31"),
+		netip.MustParsePrefix("10.0.0.4/31"),
+		netip.MustParsePrefix("10.0.0.6/32"),
+	}
+	if out := r.NetipPrefixes(); !reflect.DeepEqual(out, want) {
+		t.Errorf("IPRange.NetipPrefixes() = %v, want %v", out, want)
+	}
+
+	if _, err := IPRangeFromNetipAddrs(netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("::1")); err != ErrMixedIPFamily {
+		t.Errorf("IPRangeFromNetipAddrs(10.0.0.1, ::1) = %v, want %v", err, ErrMixedIPFamily)
+	}
+	if _, err := IPRangeFromNetipAddrs(netip.Addr{}, netip.MustParseAddr("::1")); err != ErrInvalidIP {
+		t.Errorf("IPRangeFromNetipAddrs(invalid, ::1) = %v, want %v", err, ErrInvalidIP)
+	}
+}
+
+func TestNetipIPSet(t *testing.T) {
+	s := MustNewIPSet(
+		netip.MustParseAddr("::ffff:10.0.0.1"),
+		netip.MustParsePrefix("10.0.0.2/31"),
+		netip.MustParsePrefix("2001:db8::/32"),
+		MustParsePrefix("10.0.0.4/31"),
+		MustParseAddr("10.0.0.6"),
+	)
+	want := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.1/32"),
+		netip.MustParsePrefix("10.0.0.2/31"),
+		netip.MustParsePrefix("10.0.0.4/31"),
+		netip.MustParsePrefix("10.0.0.6/32"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}
+	if out := s.NetipPrefixes(); !reflect.DeepEqual(out, want) {
+		t.Errorf("IPSet.NetipPrefixes() = %v, want %v", out, want)
+	}
+
+	if _, err := NewIPSet(netip.Addr{}); err != ErrInvalidIPSetElement {
+		t.Errorf("NewIPSet(invalid netip.Addr) = %v, want %v", err, ErrInvalidIPSetElement)
+	}
+}
+
+func TestNetipIPAddr(t *testing.T) {
+	ap := netip.MustParseAddrPort("[fe80::1%eth0]:8080")
+	a, port := IPAddrFromNetipAddrPort(ap)
+	if a.String() != "fe80::1%eth0" || port != 8080 {
+		t.Errorf("IPAddrFromNetipAddrPort(%v) = %v, %v, want fe80::1%%eth0, 8080", ap, a, port)
+	}
+	if out, ok := a.NetipAddrPort(port); !ok || out != ap {
+		t.Errorf("IPAddr.NetipAddrPort(%v, %v) = %v, %v, want %v", a, port, out, ok, ap)
+	}
+
+	a = &IPAddr{IP: IPv4(192, 0, 2, 1)}
+	if out, ok := a.NetipAddr(); !ok || out != netip.MustParseAddr("192.0.2.1") {
+		t.Errorf("IPAddr.NetipAddr(%v) = %v, %v, want 192.0.2.1", a, out, ok)
+	}
+	if out, ok := (&IPAddr{}).NetipAddr(); ok || out.IsValid() {
+		t.Errorf("IPAddr.NetipAddr(<nil>) = %v, %v, want invalid", out, ok)
+	}
+	if a := IPAddrFromNetipAddr(netip.Addr{}); a != nil {
+		t.Errorf("IPAddrFromNetipAddr(invalid) = %v, want nil", a)
+	}
+}
@@ -0,0 +1,338 @@
+package ipx
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// IPBlock is a block of IP addresses
+// ParseAny returns an *IPNet, *IPRange or *IPSet as IPBlock
+type IPBlock interface {
+	Contains(ip IP) bool
+	String() string
+}
+
+// maxPatternSpans is the maximum number of ranges
+// an octet pattern can expand to
+const maxPatternSpans = 1 << 16
+
+// ParseAny parses s as a block of IP addresses in one of the forms
+//
+//	"10.0.0.1"                    single address
+//	"10.0.0.0/24"                 CIDR notation
+//	"10.0.0.0/255.255.255.0"      address and dotted decimal mask
+//	"10.0.0.1-10.0.0.50"          range of addresses
+//	"2001:db8::1 - 2001:db8::ff"  range of addresses
+//	"10.0.0.1-50"                 range whose upper boundary replaces
+//	                              the trailing octets of the lower one
+//	"2001:db8::1-ff"              range whose upper boundary replaces
+//	                              the last hextet of the lower one
+//	"10.0.0.*", "10.0.0-3.0-255"  IPv4 octet pattern
+//
+// Single addresses and networks are returned as *IPNet and ranges as
+// *IPRange whose both boundaries are included
+// Octet patterns are returned as *IPNet if they form a single network,
+// as *IPRange if they form a single range and as *IPSet otherwise
+// If s is not valid, it returns a *ParseError whose Column points at
+// the offending character
+func ParseAny(s string) (IPBlock, error) {
+	p := blockParser{s}
+
+	lo, hi := 0, len(s)
+	for lo < hi && s[lo] == ' ' {
+		lo++
+	}
+	for hi > lo && s[hi-1] == ' ' {
+		hi--
+	}
+	if lo == hi {
+		return nil, p.errorAt(0)
+	}
+
+	body := s[lo:hi]
+	if ip := net.ParseIP(body); ip != nil {
+		return hostIPNet(IP{ip}), nil
+	}
+
+	switch {
+	case strings.IndexByte(body, '/') >= 0:
+		return p.parseCIDR(lo, lo+strings.IndexByte(body, '/'), hi)
+	case isOctetPattern(body):
+		return p.parsePattern(lo, hi)
+	case strings.IndexByte(body, '-') >= 0:
+		return p.parseRange(lo, lo+strings.IndexByte(body, '-'), hi)
+	}
+
+	_, err := p.parseIP(lo, hi)
+	return nil, err
+}
+
+// MustParseAny parses s as a block of IP addresses
+// if an error ocurred, it throws a panic
+func MustParseAny(s string) IPBlock {
+	block, err := ParseAny(s)
+	if err != nil {
+		panic(err)
+	}
+	return block
+}
+
+// blockParser parses the forms ParseAny accepts
+// Positions are byte offsets in s
+type blockParser struct {
+	s string
+}
+
+// errorAt returns the ParseError pointing at the i'th byte of s
+func (p blockParser) errorAt(i int) error {
+	return &ParseError{Type: "IP address block", Text: p.s, Column: i + 1}
+}
+
+// trim returns the positions of s[lo:hi] without surrounding spaces
+func (p blockParser) trim(lo, hi int) (int, int) {
+	for lo < hi && p.s[lo] == ' ' {
+		lo++
+	}
+	for hi > lo && p.s[hi-1] == ' ' {
+		hi--
+	}
+	return lo, hi
+}
+
+// parseIP parses s[lo:hi] as an IP address
+// For malformed IPv4 addresses, the error points at the offending octet
+func (p blockParser) parseIP(lo, hi int) (IP, error) {
+	lo, hi = p.trim(lo, hi)
+	if ip := net.ParseIP(p.s[lo:hi]); ip != nil {
+		return IP{ip}, nil
+	}
+	if strings.IndexByte(p.s[lo:hi], ':') >= 0 {
+		return IP{}, p.errorAt(lo)
+	}
+
+	// find the malformed octet
+	start := lo
+	for n := 0; ; n++ {
+		end := start
+		for end < hi && p.s[end] != '.' {
+			end++
+		}
+		if n > 3 || !isOctet(p.s[start:end]) {
+			return IP{}, p.errorAt(start)
+		}
+		if end == hi {
+			return IP{}, p.errorAt(hi)
+		}
+		start = end + 1
+	}
+}
+
+// parseCIDR parses s[lo:hi] as an address and a prefix length
+// or a dotted decimal mask separated by the slash at s[slash]
+func (p blockParser) parseCIDR(lo, slash, hi int) (IPBlock, error) {
+	ip, err := p.parseIP(lo, slash)
+	if err != nil {
+		return nil, err
+	}
+
+	mlo, mhi := p.trim(slash+1, hi)
+	mask := p.s[mlo:mhi]
+	if strings.IndexByte(mask, '.') < 0 {
+		ones, err := strconv.Atoi(mask)
+		if err != nil || !isDigits(mask) || ones > addressBits(ip.IsV4()) {
+			return nil, p.errorAt(mlo)
+		}
+		a, _ := AddrFromIP(ip)
+		return PrefixFrom(a, ones).IPNet(), nil
+	}
+
+	m := net.ParseIP(mask).To4()
+	if m == nil || !ip.IsV4() || simpleMaskLength(net.IPMask(m)) == -1 {
+		return nil, p.errorAt(mlo)
+	}
+	return &IPNet{IP: IP{ip.IP.To4().Mask(net.IPMask(m))}, Mask: IPMask{net.IPMask(m)}}, nil
+}
+
+// parseRange parses s[lo:hi] as the boundaries of a range
+// separated by the hyphen at s[dash]
+func (p blockParser) parseRange(lo, dash, hi int) (IPBlock, error) {
+	lower, err := p.parseIP(lo, dash)
+	if err != nil {
+		return nil, err
+	}
+
+	ulo, uhi := p.trim(dash+1, hi)
+	upperText := p.s[ulo:uhi]
+	upper, err := ParseIP(upperText)
+	switch {
+	case err == nil:
+		if upper.IsV4() != lower.IsV4() {
+			return nil, p.errorAt(ulo)
+		}
+	case lower.IsV4():
+		upper, err = p.parseV4Suffix(lower, ulo, uhi)
+	default:
+		upper, err = p.parseV6Suffix(lower, ulo, uhi)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewIPRangeInclusive(lower, upper), nil
+}
+
+// parseV4Suffix parses s[lo:hi] as the trailing octets of an IPv4
+// address and returns lower with its trailing octets replaced
+func (p blockParser) parseV4Suffix(lower IP, lo, hi int) (IP, error) {
+	octets := strings.Split(p.s[lo:hi], ".")
+	if len(octets) > 3 {
+		return p.parseIP(lo, hi)
+	}
+
+	ip := append(net.IP(nil), lower.IP.To4()...)
+	start := lo
+	for n, octet := range octets {
+		if !isOctet(octet) {
+			return IP{}, p.errorAt(start)
+		}
+		v, _ := strconv.Atoi(octet)
+		ip[IPv4len-len(octets)+n] = byte(v)
+		start += len(octet) + 1
+	}
+	return IP{ip}, nil
+}
+
+// parseV6Suffix parses s[lo:hi] as the last hextet of an IPv6 address
+// and returns lower with its last hextet replaced
+func (p blockParser) parseV6Suffix(lower IP, lo, hi int) (IP, error) {
+	v, err := strconv.ParseUint(p.s[lo:hi], 16, 16)
+	if err != nil || hi-lo > 4 || strings.ContainsAny(p.s[lo:hi], "+-") {
+		return IP{}, p.errorAt(lo)
+	}
+
+	ip := append(net.IP(nil), lower.IP...)
+	ip[IPv6len-2], ip[IPv6len-1] = byte(v>>8), byte(v)
+	return IP{ip}, nil
+}
+
+// parsePattern parses s[lo:hi] as an IPv4 octet pattern
+// whose octets are numbers, ranges of numbers or wildcards
+func (p blockParser) parsePattern(lo, hi int) (IPBlock, error) {
+	var first, last [IPv4len]uint64
+	start := lo
+	for n, part := range strings.Split(p.s[lo:hi], ".") {
+		switch dash := strings.IndexByte(part, '-'); {
+		case part == "*":
+			first[n], last[n] = 0, 255
+		case dash >= 0:
+			if !isOctet(part[:dash]) {
+				return nil, p.errorAt(start)
+			}
+			a, _ := strconv.Atoi(part[:dash])
+			b, err := strconv.Atoi(part[dash+1:])
+			if !isOctet(part[dash+1:]) || err != nil || b < a {
+				return nil, p.errorAt(start + dash + 1)
+			}
+			first[n], last[n] = uint64(a), uint64(b)
+		default:
+			if !isOctet(part) {
+				return nil, p.errorAt(start)
+			}
+			a, _ := strconv.Atoi(part)
+			first[n], last[n] = uint64(a), uint64(a)
+		}
+		start += len(part) + 1
+	}
+
+	// octets after k cover all values, so every combination of the
+	// octets before k gives a single span
+	k := IPv4len - 1
+	for k >= 0 && first[k] == 0 && last[k] == 255 {
+		k--
+	}
+	if k < 0 {
+		return MustParseCIDR("0.0.0.0/0"), nil
+	}
+
+	count := uint64(1)
+	for n := 0; n < k; n++ {
+		count *= last[n] - first[n] + 1
+	}
+	if count > maxPatternSpans {
+		return nil, p.errorAt(lo)
+	}
+
+	shift := uint(8 * (IPv4len - 1 - k))
+	host := uint64(1)<<shift - 1
+	spans := make([]ipSpan, 0, count)
+	var walk func(n int, base uint64)
+	walk = func(n int, base uint64) {
+		if n == k {
+			spans = append(spans, ipSpan{
+				NewUint128(base | first[k]<<shift),
+				NewUint128(base | last[k]<<shift | host),
+			})
+			return
+		}
+		for v := first[n]; v <= last[n]; v++ {
+			walk(n+1, base|v<<uint(8*(IPv4len-1-n)))
+		}
+	}
+	walk(0, 0)
+
+	if len(spans) > 1 {
+		return &IPSet{v4: normalizeSpans(spans)}, nil
+	}
+	if prefixes := spans[0].prefixes(true); len(prefixes) == 1 {
+		return prefixes[0], nil
+	}
+	return spans[0].ipRange(true), nil
+}
+
+// isOctetPattern reports whether s consists of four dot separated
+// octets which are numbers, ranges of numbers or wildcards
+func isOctetPattern(s string) bool {
+	parts := strings.Split(s, ".")
+	if len(parts) != IPv4len {
+		return false
+	}
+	for _, part := range parts {
+		if part == "*" {
+			continue
+		}
+		if dash := strings.IndexByte(part, '-'); dash >= 0 {
+			if !isDigits(part[:dash]) || !isDigits(part[dash+1:]) {
+				return false
+			}
+			continue
+		}
+		if !isDigits(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// isOctet reports whether s is a decimal number between 0 and 255
+// without leading zeros
+func isOctet(s string) bool {
+	if !isDigits(s) || len(s) > 3 || len(s) > 1 && s[0] == '0' {
+		return false
+	}
+	v, _ := strconv.Atoi(s)
+	return v <= 255
+}
+
+// isDigits reports whether s is a non-empty string of decimal digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package ipx
+
+import (
+	"testing"
+)
+
+var parseAnyTests = []struct {
+	in   string
+	kind string
+	out  string
+}{
+	{"10.0.0.1", "*ipx.IPNet", "10.0.0.1/32"},
+	{" 2001:db8::1 ", "*ipx.IPNet", "2001:db8::1/128"},
+	{"10.0.0.1/24", "*ipx.IPNet", "10.0.0.0/24"},
+	{"10.0.0.0/255.255.255.0", "*ipx.IPNet", "10.0.0.0/24"},
+	{"10.0.0.0 / 255.255.0.0", "*ipx.IPNet", "10.0.0.0/16"},
+	{"2001:db8::/32", "*ipx.IPNet", "2001:db8::/32"},
+	{"10.0.0.1-10.0.0.50", "*ipx.IPRange", "10.0.0.1-10.0.0.50"},
+	{"10.0.0.50 - 10.0.0.1", "*ipx.IPRange", "10.0.0.1-10.0.0.50"},
+	{"10.0.0.1-50", "*ipx.IPRange", "10.0.0.1-10.0.0.50"},
+	{"10.0.0.1 - 50", "*ipx.IPRange", "10.0.0.1-10.0.0.50"},
+	{"10.0.0.1-1.20", "*ipx.IPRange", "10.0.0.1-10.0.1.20"},
+	{"2001:db8::1 - 2001:db8::ff", "*ipx.IPRange", "2001:db8::1-2001:db8::ff"},
+	{"2001:db8::1-ff", "*ipx.IPRange", "2001:db8::1-2001:db8::ff"},
+	{"10.0.0.*", "*ipx.IPNet", "10.0.0.0/24"},
+	{"10.*.*.*", "*ipx.IPNet", "10.0.0.0/8"},
+	{"*.*.*.*", "*ipx.IPNet", "0.0.0.0/0"},
+	{"10.0.0-3.*", "*ipx.IPNet", "10.0.0.0/22"},
+	{"10.0.0-3.0-255", "*ipx.IPNet", "10.0.0.0/22"},
+	{"10.0.1-2.*", "*ipx.IPRange", "10.0.1.0-10.0.2.255"},
+	{"10.0.0-1.5", "*ipx.IPSet", "10.0.0.5, 10.0.1.5"},
+	{"10.0-1.0.1-2", "*ipx.IPSet", "10.0.0.1-10.0.0.2, 10.1.0.1-10.1.0.2"},
+}
+
+func TestParseAny(t *testing.T) {
+	for _, tt := range parseAnyTests {
+		out, err := ParseAny(tt.in)
+		if err != nil {
+			t.Errorf("ParseAny(%q) = %v, want %v", tt.in, err, tt.out)
+			continue
+		}
+		if kind := typeName(out); kind != tt.kind || out.String() != tt.out {
+			t.Errorf("ParseAny(%q) = %v %v, want %v %v", tt.in, kind, out, tt.kind, tt.out)
+		}
+	}
+}
+
+func typeName(block IPBlock) string {
+	switch block.(type) {
+	case *IPNet:
+		return "*ipx.IPNet"
+	case *IPRange:
+		return "*ipx.IPRange"
+	case *IPSet:
+		return "*ipx.IPSet"
+	}
+	return "unknown"
+}
+
+var parseAnyErrorTests = []struct {
+	in     string
+	column int
+}{
+	{"", 1},
+	{"   ", 1},
+	{"10.0.0.256", 8},
+	{"10.0.x.1", 6},
+	{"10.0.0", 7},
+	{"10.0.0.1/33", 10},
+	{"10.0.0.1/255.0.255.0", 10},
+	{"2001:db8::/255.255.0.0", 12},
+	{"10.0.0.1-300", 10},
+	{"10.0.0.1 - 10.0.0.x", 19},
+	{"10.0.0.1-::1", 10},
+	{"2001:db8::1-fffff", 13},
+	{"10.0.0.5-3.*", 12},
+	{"10.0.0-300.*", 8},
+	{"10.0.3-1.*", 8},
+	{"1-255.1-255.1-255.1", 1},
+	{"2001:db8::x", 1},
+}
+
+func TestParseAnyError(t *testing.T) {
+	for _, tt := range parseAnyErrorTests {
+		out, err := ParseAny(tt.in)
+		perr, ok := err.(*ParseError)
+		if !ok || perr.Column != tt.column || perr.Text != tt.in {
+			t.Errorf("ParseAny(%q) = %v, %v, want error at column %v", tt.in, out, err, tt.column)
+		}
+	}
+}
+
+func TestParseErrorColumn(t *testing.T) {
+	err := &ParseError{Type: "IP address block", Text: "10.0.0.256", Column: 8}
+	if want := "invalid IP address block: 10.0.0.256 (column 8)"; err.Error() != want {
+		t.Errorf("ParseError.Error() = %v, want %v", err.Error(), want)
+	}
+	err.Column = 0
+	if want := "invalid IP address block: 10.0.0.256"; err.Error() != want {
+		t.Errorf("ParseError.Error() = %v, want %v", err.Error(), want)
+	}
+}
+
+func TestParseAnyContains(t *testing.T) {
+	block := MustParseAny("10.0.0-3.5")
+	for _, ip := range []IP{IPv4(10, 0, 0, 5), IPv4(10, 0, 3, 5)} {
+		if !block.Contains(ip) {
+			t.Errorf("IPBlock.Contains(%v, %v) = false, want true", block, ip)
+		}
+	}
+	if block.Contains(IPv4(10, 0, 1, 6)) {
+		t.Errorf("IPBlock.Contains(%v, 10.0.1.6) = true, want false", block)
+	}
+}
@@ -0,0 +1,207 @@
+package ipx
+
+import (
+	crand "crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+	"sort"
+)
+
+// Error definitions
+var (
+	ErrNothingToSample    = errors.New("no addresses to sample from")
+	ErrNotEnoughAddresses = errors.New("not enough addresses to sample from")
+)
+
+// bigOne is 1 as a *big.Int
+var bigOne = big.NewInt(1)
+
+// Address blocks excluded by the options of Sampler
+var (
+	reservedAddresses = newRegistrySet(true)
+	specialAddresses  = newRegistrySet(false).Union(MustNewIPSet(MustParseCIDR("224.0.0.0/4"), MustParseCIDR("ff00::/8")))
+)
+
+// Sampler picks IP addresses uniformly at random
+// from networks, ranges and sets of both address families
+//
+//	s := &Sampler{Rand: rand.New(rand.NewSource(seed)), ExcludeSpecial: true}
+//	ip, _ := s.IP(MustParseCIDR("2001:db8::/32"))
+type Sampler struct {
+	// Rand is the source of randomness like crypto/rand.Reader
+	// or a seeded *math/rand.Rand
+	// If nil, crypto/rand.Reader is used
+	Rand io.Reader
+
+	// ExcludeReserved excludes the blocks of the IANA Special-Purpose
+	// Address Registries that are reserved by protocol, like loopback
+	// and link-local addresses
+	ExcludeReserved bool
+
+	// ExcludeSpecial excludes all blocks of the IANA Special-Purpose
+	// Address Registries and multicast addresses
+	ExcludeSpecial bool
+
+	// Exclude holds other addresses to exclude
+	Exclude *IPSet
+}
+
+// sampleSpace is the set of addresses a Sampler picks from
+// with the addresses numbered in ascending order
+type sampleSpace struct {
+	spans []ipSpan
+	v4    int        // number of IPv4 spans, which come first
+	ends  []*big.Int // number of addresses in the spans up to each span
+	size  *big.Int
+}
+
+// IP returns a random address of given elements
+// Elements can be anything NewIPSet accepts like *IPNet, *IPRange
+// and *IPSet
+// It returns ErrNothingToSample if there are no addresses to pick
+// after the exclusions
+func (s *Sampler) IP(elems ...interface{}) (IP, error) {
+	space, err := s.space(elems)
+	if err != nil {
+		return IP{}, err
+	}
+	if space.size.Sign() == 0 {
+		return IP{}, ErrNothingToSample
+	}
+
+	i, err := randomBigInt(s.reader(), space.size)
+	if err != nil {
+		return IP{}, err
+	}
+	return space.at(i).IP(), nil
+}
+
+// UniqueIPs returns n distinct random addresses of given elements
+// in ascending order, sampled without replacement
+// Elements can be anything NewIPSet accepts
+// It returns ErrNotEnoughAddresses if there are less than n addresses
+// to pick after the exclusions
+func (s *Sampler) UniqueIPs(n int, elems ...interface{}) ([]IP, error) {
+	space, err := s.space(elems)
+	if err != nil {
+		return nil, err
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+	if space.size.Cmp(big.NewInt(int64(n))) < 0 {
+		return nil, ErrNotEnoughAddresses
+	}
+
+	// Floyd's algorithm picks n addresses with n random numbers
+	r := s.reader()
+	picked := make(map[Addr]struct{}, n)
+	addrs := make([]Addr, 0, n)
+	j := new(big.Int).Sub(space.size, big.NewInt(int64(n)))
+	for ; len(addrs) < n; j.Add(j, bigOne) {
+		i, err := randomBigInt(r, new(big.Int).Add(j, bigOne))
+		if err != nil {
+			return nil, err
+		}
+		a := space.at(i)
+		if _, ok := picked[a]; ok {
+			a = space.at(j)
+		}
+		picked[a] = struct{}{}
+		addrs = append(addrs, a)
+	}
+
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i].Less(addrs[j]) })
+	ips := make([]IP, n)
+	for i, a := range addrs {
+		ips[i] = a.IP()
+	}
+	return ips, nil
+}
+
+// reader returns the source of randomness
+func (s *Sampler) reader() io.Reader {
+	if s.Rand == nil {
+		return crand.Reader
+	}
+	return s.Rand
+}
+
+// space returns the addresses of elems without the excluded addresses
+func (s *Sampler) space(elems []interface{}) (sampleSpace, error) {
+	set, err := NewIPSet(elems...)
+	if err != nil {
+		return sampleSpace{}, err
+	}
+	if s.ExcludeReserved {
+		set = set.Difference(reservedAddresses)
+	}
+	if s.ExcludeSpecial {
+		set = set.Difference(specialAddresses)
+	}
+	if s.Exclude != nil {
+		set = set.Difference(s.Exclude)
+	}
+
+	space := sampleSpace{
+		spans: append(append([]ipSpan{}, set.v4...), set.v6...),
+		v4:    len(set.v4),
+		size:  new(big.Int),
+	}
+	for _, span := range space.spans {
+		diff, _ := span.last.Sub(span.first)
+		space.size.Add(space.size, diff.BigInt()).Add(space.size, bigOne)
+		space.ends = append(space.ends, new(big.Int).Set(space.size))
+	}
+	return space, nil
+}
+
+// at returns the i'th address of the space
+func (space sampleSpace) at(i *big.Int) Addr {
+	k := sort.Search(len(space.ends), func(k int) bool {
+		return space.ends[k].Cmp(i) > 0
+	})
+	off := new(big.Int).Set(i)
+	if k > 0 {
+		off.Sub(off, space.ends[k-1])
+	}
+	val, _ := space.spans[k].first.Add(Uint128FromBigInt(off))
+	return AddrFromUint128(val, k < space.v4)
+}
+
+// randomBigInt returns a uniformly distributed random number
+// in [0, max) read from r
+func randomBigInt(r io.Reader, max *big.Int) (*big.Int, error) {
+	bitLen := new(big.Int).Sub(max, bigOne).BitLen()
+	buf := make([]byte, (bitLen+7)/8)
+	n := new(big.Int)
+	for {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		if len(buf) > 0 {
+			top := uint(bitLen % 8)
+			if top == 0 {
+				top = 8
+			}
+			buf[0] &= byte(1<<top - 1)
+		}
+		if n.SetBytes(buf).Cmp(max) < 0 {
+			return n, nil
+		}
+	}
+}
+
+// newRegistrySet returns the set of the blocks of the IANA
+// Special-Purpose Address Registries, only of the blocks reserved
+// by protocol if reserved is true
+func newRegistrySet(reserved bool) *IPSet {
+	var blocks []interface{}
+	for _, entry := range specialPurposeRegistry {
+		if !reserved || entry.ReservedByProtocol {
+			blocks = append(blocks, entry.IPSet())
+		}
+	}
+	return MustNewIPSet(blocks...)
+}
@@ -0,0 +1,129 @@
+package ipx
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func ipStrings(ips []IP) []string {
+	var out []string
+	for _, ip := range ips {
+		out = append(out, ip.String())
+	}
+	return out
+}
+
+var samplerTests = []struct {
+	sampler Sampler
+	in      []interface{}
+	err     error
+}{
+	{Sampler{}, []interface{}{MustParseCIDR("0.0.0.0/0")}, nil},
+	{Sampler{}, []interface{}{MustParseCIDR("::/0")}, nil},
+	{Sampler{}, []interface{}{MustParseCIDR("0.0.0.0/0"), MustParseCIDR("::/0")}, nil},
+	{Sampler{}, []interface{}{MustParseCIDR("192.0.2.1/32")}, nil},
+	{Sampler{}, []interface{}{MustParseIPRange("2001:db8::fff0", "2001:db8::1:10")}, nil},
+	{Sampler{}, []interface{}{MustNewIPSet(IPv4(10, 0, 0, 1), MustParseCIDR("2001:db8::/127"))}, nil},
+	{Sampler{}, nil, ErrNothingToSample},
+	{Sampler{ExcludeReserved: true}, []interface{}{MustParseCIDR("127.0.0.0/8")}, ErrNothingToSample},
+	{Sampler{ExcludeReserved: true}, []interface{}{MustParseCIDR("10.0.0.0/8")}, nil},
+	{Sampler{ExcludeSpecial: true}, []interface{}{MustParseCIDR("10.0.0.0/8"), MustParseCIDR("ff02::/16")}, ErrNothingToSample},
+	{Sampler{ExcludeSpecial: true}, []interface{}{MustParseCIDR("0.0.0.0/0"), MustParseCIDR("::/0")}, nil},
+	{Sampler{Exclude: MustNewIPSet(MustParseCIDR("198.51.100.0/25"))}, []interface{}{MustParseCIDR("198.51.100.0/24")}, nil},
+}
+
+func TestSamplerIP(t *testing.T) {
+	for _, tt := range samplerTests {
+		set := MustNewIPSet(tt.in...)
+		for i := 0; i < 100; i++ {
+			out, err := tt.sampler.IP(tt.in...)
+			if err != tt.err {
+				t.Errorf("Sampler.IP(%v) error = %v, want %v", set, err, tt.err)
+				break
+			}
+			if err != nil {
+				break
+			}
+			if !set.Contains(out) {
+				t.Errorf("Sampler.IP(%v) = %v is not in the set", set, out)
+			}
+			excluded := tt.sampler.Exclude != nil && tt.sampler.Exclude.Contains(out) ||
+				tt.sampler.ExcludeReserved && reservedAddresses.Contains(out) ||
+				tt.sampler.ExcludeSpecial && specialAddresses.Contains(out)
+			if excluded {
+				t.Errorf("Sampler.IP(%v) = %v is excluded", set, out)
+			}
+		}
+	}
+}
+
+func TestSamplerUniform(t *testing.T) {
+	s := Sampler{Rand: rand.New(rand.NewSource(1))}
+	set := MustNewIPSet(MustParseIPRangeInclusive("10.0.0.0", "10.0.0.2"), MustParseIPRangeInclusive("2001:db8::", "2001:db8::1"))
+
+	counts := map[string]int{}
+	const n = 50000
+	for i := 0; i < n; i++ {
+		ip, err := s.IP(set)
+		if err != nil {
+			t.Fatalf("Sampler.IP(%v) error = %v", set, err)
+		}
+		counts[ip.String()]++
+	}
+	if len(counts) != 5 {
+		t.Fatalf("Sampler.IP(%v) picked %v", set, counts)
+	}
+	for ip, c := range counts {
+		if c < n/5*9/10 || c > n/5*11/10 {
+			t.Errorf("Sampler.IP(%v) picked %v %d times out of %d", set, ip, c, n)
+		}
+	}
+}
+
+func TestSamplerSeeded(t *testing.T) {
+	in := MustParseCIDR("2001:db8::/32")
+	a, _ := (&Sampler{Rand: rand.New(rand.NewSource(42))}).UniqueIPs(10, in)
+	b, _ := (&Sampler{Rand: rand.New(rand.NewSource(42))}).UniqueIPs(10, in)
+	if !reflect.DeepEqual(ipStrings(a), ipStrings(b)) {
+		t.Errorf("Sampler.UniqueIPs() with the same seed = %v and %v", a, b)
+	}
+
+	// the source of randomness is exhausted
+	s := Sampler{Rand: bytes.NewReader([]byte{1, 2, 3})}
+	if _, err := s.IP(in); err == nil {
+		t.Errorf("Sampler.IP() with an exhausted source error = nil")
+	}
+}
+
+func TestSamplerUniqueIPs(t *testing.T) {
+	s := Sampler{Rand: rand.New(rand.NewSource(1)), ExcludeReserved: true}
+	in := []interface{}{MustParseIPRangeInclusive("0.0.0.0", "0.0.0.3"), MustParseCIDR("1.0.0.0/30")}
+
+	// all addresses are picked
+	out, err := s.UniqueIPs(4, in...)
+	want := []string{"1.0.0.0", "1.0.0.1", "1.0.0.2", "1.0.0.3"}
+	if err != nil || !reflect.DeepEqual(ipStrings(out), want) {
+		t.Errorf("Sampler.UniqueIPs(4) = %v, %v, want %v", out, err, want)
+	}
+
+	if _, err := s.UniqueIPs(5, in...); err != ErrNotEnoughAddresses {
+		t.Errorf("Sampler.UniqueIPs(5) error = %v, want %v", err, ErrNotEnoughAddresses)
+	}
+	if out, err := s.UniqueIPs(0, in...); err != nil || len(out) != 0 {
+		t.Errorf("Sampler.UniqueIPs(0) = %v, %v", out, err)
+	}
+
+	out, err = s.UniqueIPs(1000, MustParseCIDR("10.0.0.0/22"), MustParseCIDR("2001:db8::/120"))
+	if err != nil || len(out) != 1000 {
+		t.Fatalf("Sampler.UniqueIPs(1000) = %d addresses, %v", len(out), err)
+	}
+	seen := map[string]bool{}
+	for _, ip := range out {
+		if seen[ip.String()] {
+			t.Errorf("Sampler.UniqueIPs(1000) picked %v twice", ip)
+		}
+		seen[ip.String()] = true
+	}
+}
@@ -0,0 +1,240 @@
+// Package realip extracts the address of the client of an HTTP request
+// that passed through trusted reverse proxies
+package realip
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"github.com/hakansa/ipx"
+)
+
+// Header names
+const (
+	HeaderForwarded     = "Forwarded"
+	HeaderXForwardedFor = "X-Forwarded-For"
+	HeaderXRealIP       = "X-Real-IP"
+)
+
+// DefaultHeaders is the list of headers Extractor reads by default
+var DefaultHeaders = []string{HeaderXForwardedFor}
+
+// Extractor extracts the address of the client of a request
+//
+// If the peer of a request is a trusted proxy, the addresses the proxies
+// recorded in the first of Headers the request has are walked from the
+// right, and the first address that is not a trusted proxy is the client
+// Addresses on the left of it are set by the client and are ignored, so
+// they can not be spoofed
+// If the peer is not a trusted proxy, it is the client and the headers
+// are ignored
+//
+// Headers must list only the headers the trusted proxies set or
+// overwrite, since a client can send the others through the proxies
+// For example, if the proxies set X-Forwarded-For and Headers lists
+// Forwarded first, a client can choose its address by sending Forwarded
+type Extractor struct {
+	// TrustedProxies is the list of networks of the trusted proxies
+	TrustedProxies []*ipx.IPNet
+
+	// Headers is the list of headers to read in order of preference
+	// Forwarded and X-Forwarded-For hold the list of addresses the
+	// request passed through and other headers hold a single address
+	// If nil, DefaultHeaders is used
+	Headers []string
+}
+
+// New creates a new Extractor with the trusted proxy networks
+// and DefaultHeaders
+func New(trustedProxies ...*ipx.IPNet) *Extractor {
+	return &Extractor{TrustedProxies: trustedProxies}
+}
+
+// ClientIP returns the address of the client of r
+// It returns a *ipx.ParseError if r.RemoteAddr is not a valid address
+func (e *Extractor) ClientIP(r *http.Request) (ipx.IPAddr, error) {
+	peer, ok := parseNode(r.RemoteAddr)
+	if !ok {
+		return ipx.IPAddr{}, &ipx.ParseError{Type: "remote address", Text: r.RemoteAddr}
+	}
+	if !e.trusted(peer) {
+		return peer, nil
+	}
+
+	headers := e.Headers
+	if headers == nil {
+		headers = DefaultHeaders
+	}
+	for _, header := range headers {
+		values := r.Header.Values(header)
+		if len(values) == 0 {
+			continue
+		}
+
+		switch textproto.CanonicalMIMEHeaderKey(header) {
+		case HeaderForwarded:
+			return e.walk(peer, forwardedNodes(values)), nil
+		case HeaderXForwardedFor:
+			return e.walk(peer, listNodes(values)), nil
+		}
+
+		// a single address header is only used when it is set once
+		if addr, ok := parseNode(values[0]); ok && len(values) == 1 {
+			return addr, nil
+		}
+		return peer, nil
+	}
+	return peer, nil
+}
+
+// walk returns the right-most address in nodes that is not a trusted
+// proxy, where peer is the address that sent the request
+// The walk stops at the first invalid or obfuscated node and returns the
+// last trusted address on its right
+// If every address is trusted, the left-most address is returned
+func (e *Extractor) walk(peer ipx.IPAddr, nodes []string) ipx.IPAddr {
+	client := peer
+	for i := len(nodes) - 1; i >= 0; i-- {
+		addr, ok := parseNode(nodes[i])
+		if !ok {
+			return client
+		}
+		client = addr
+		if !e.trusted(addr) {
+			return addr
+		}
+	}
+	return client
+}
+
+// trusted reports whether addr is in a trusted proxy network
+func (e *Extractor) trusted(addr ipx.IPAddr) bool {
+	for _, n := range e.TrustedProxies {
+		if n.Contains(addr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler returns a handler that stores the address of the client
+// of a request in its context and calls h
+// Use FromContext to get the address
+// Requests whose remote address is not valid are passed to h unchanged
+func (e *Extractor) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if addr, err := e.ClientIP(r); err == nil {
+			r = r.WithContext(NewContext(r.Context(), addr))
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// contextKey is the type of the context key of the client address
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries addr
+func NewContext(ctx context.Context, addr ipx.IPAddr) context.Context {
+	return context.WithValue(ctx, contextKey{}, addr)
+}
+
+// FromContext returns the client address stored in ctx by Handler
+func FromContext(ctx context.Context) (ipx.IPAddr, bool) {
+	addr, ok := ctx.Value(contextKey{}).(ipx.IPAddr)
+	return addr, ok
+}
+
+// listNodes splits the comma separated lists of values into nodes
+// Multiple header lines are joined in order
+func listNodes(values []string) []string {
+	var nodes []string
+	for _, value := range values {
+		nodes = append(nodes, strings.Split(value, ",")...)
+	}
+	return nodes
+}
+
+// forwardedNodes returns the for parameters of the elements
+// of the Forwarded header values as described in RFC 7239
+// Elements without a for parameter are returned as empty nodes
+func forwardedNodes(values []string) []string {
+	var nodes []string
+	for _, value := range values {
+		for _, element := range splitQuoted(value, ',') {
+			node := ""
+			for _, pair := range splitQuoted(element, ';') {
+				eq := strings.IndexByte(pair, '=')
+				if eq >= 0 && strings.EqualFold(strings.TrimSpace(pair[:eq]), "for") {
+					node = unquote(strings.TrimSpace(pair[eq+1:]))
+				}
+			}
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// splitQuoted splits s at the occurrences of sep outside quoted strings
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted, escaped, start := false, false, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case c == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquote returns the content of the quoted string s
+// s is returned unchanged if it is not quoted
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	var b strings.Builder
+	for i := 1; i < len(s)-1; i++ {
+		if s[i] == '\\' && i+1 < len(s)-1 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// parseNode parses s as an address with an optional port like
+// "192.0.2.1", "192.0.2.1:80", "2001:db8::1", "[2001:db8::1]:80"
+// or "[fe80::1%eth0]:80"
+// Zones can be percent-encoded as in URIs like "fe80::1%25eth0"
+// IPv4-mapped IPv6 addresses are returned as IPv4 addresses
+func parseNode(s string) (ipx.IPAddr, bool) {
+	s = strings.TrimSpace(s)
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	} else if len(s) > 2 && s[0] == '[' && s[len(s)-1] == ']' {
+		s = s[1 : len(s)-1]
+	}
+	if zone := strings.Index(s, "%25"); zone >= 0 {
+		s = s[:zone+1] + s[zone+3:]
+	}
+
+	addr, err := ipx.ParseAddr(s)
+	if err != nil {
+		return ipx.IPAddr{}, false
+	}
+	if addr.Is4In6() {
+		addr = addr.Unmap()
+	}
+	return *addr.IPAddr(), true
+}
@@ -0,0 +1,101 @@
+package realip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hakansa/ipx"
+)
+
+var trustedProxies = []*ipx.IPNet{
+	ipx.MustParseCIDR("10.0.0.0/8"),
+	ipx.MustParseCIDR("fd00::/8"),
+}
+
+var clientIPTests = []struct {
+	name       string
+	remoteAddr string
+	headers    []string
+	header     map[string][]string
+	out        string
+}{
+	{"untrusted peer", "203.0.113.7:4711", nil, nil, "203.0.113.7"},
+	{"untrusted peer ignores headers", "203.0.113.7:4711", nil, map[string][]string{"X-Forwarded-For": {"198.51.100.1"}}, "203.0.113.7"},
+	{"trusted peer without headers", "10.0.0.1:4711", nil, nil, "10.0.0.1"},
+	{"single proxy", "10.0.0.1:4711", nil, map[string][]string{"X-Forwarded-For": {"198.51.100.1"}}, "198.51.100.1"},
+	{"proxy chain", "10.0.0.1:4711", nil, map[string][]string{"X-Forwarded-For": {"198.51.100.1, 10.0.0.3, 10.0.0.2"}}, "198.51.100.1"},
+	{"spoofed left entries", "10.0.0.1:4711", nil, map[string][]string{"X-Forwarded-For": {"127.0.0.1, 1.2.3.4, 198.51.100.1"}}, "198.51.100.1"},
+	{"spoofed trusted entry", "10.0.0.1:4711", nil, map[string][]string{"X-Forwarded-For": {"10.0.0.9, 198.51.100.1"}}, "198.51.100.1"},
+	{"multiple header lines", "10.0.0.1:4711", nil, map[string][]string{"X-Forwarded-For": {"1.2.3.4", "198.51.100.1, 10.0.0.2"}}, "198.51.100.1"},
+	{"all trusted", "10.0.0.1:4711", nil, map[string][]string{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}}, "10.0.0.3"},
+	{"invalid entry", "10.0.0.1:4711", nil, map[string][]string{"X-Forwarded-For": {"198.51.100.1, evil, 10.0.0.2"}}, "10.0.0.2"},
+	{"invalid last entry", "10.0.0.1:4711", nil, map[string][]string{"X-Forwarded-For": {"198.51.100.1, "}}, "10.0.0.1"},
+	{"entry with port", "10.0.0.1:4711", nil, map[string][]string{"X-Forwarded-For": {"198.51.100.1:1234"}}, "198.51.100.1"},
+	{"ipv6 entries", "[fd00::1]:4711", nil, map[string][]string{"X-Forwarded-For": {"2001:db8::1, [fd00::2]:80"}}, "2001:db8::1"},
+	{"ipv6 zone", "[fd00::1]:4711", nil, map[string][]string{"X-Forwarded-For": {"fe80::1%eth0"}}, "fe80::1%eth0"},
+	{"ipv4-mapped peer", "[::ffff:10.0.0.1]:4711", nil, map[string][]string{"X-Forwarded-For": {"::ffff:198.51.100.1"}}, "198.51.100.1"},
+	{"forwarded not read by default", "10.0.0.1:4711", nil, map[string][]string{"Forwarded": {"for=1.2.3.4"}, "X-Forwarded-For": {"198.51.100.1"}}, "198.51.100.1"},
+	{"x-real-ip not read by default", "10.0.0.1:4711", nil, map[string][]string{"X-Real-Ip": {"1.2.3.4"}}, "10.0.0.1"},
+	{"forwarded", "10.0.0.1:4711", []string{HeaderForwarded}, map[string][]string{"Forwarded": {`for=198.51.100.1;proto=https;by=10.0.0.1, for="10.0.0.2"`}}, "198.51.100.1"},
+	{"forwarded ipv6", "10.0.0.1:4711", []string{HeaderForwarded}, map[string][]string{"Forwarded": {`For="[2001:db8:cafe::17]:4711"`}}, "2001:db8:cafe::17"},
+	{"forwarded encoded zone", "10.0.0.1:4711", []string{HeaderForwarded}, map[string][]string{"Forwarded": {`for="[fe80::1%25eth0]"`}}, "fe80::1%eth0"},
+	{"forwarded spoofed", "10.0.0.1:4711", []string{HeaderForwarded}, map[string][]string{"Forwarded": {"for=127.0.0.1, for=198.51.100.1"}}, "198.51.100.1"},
+	{"forwarded quoted comma", "10.0.0.1:4711", []string{HeaderForwarded}, map[string][]string{"Forwarded": {`for=198.51.100.1;ext="a,for=10.0.0.5"`}}, "198.51.100.1"},
+	{"forwarded unknown", "10.0.0.1:4711", []string{HeaderForwarded}, map[string][]string{"Forwarded": {"for=198.51.100.1, for=unknown, for=10.0.0.2"}}, "10.0.0.2"},
+	{"forwarded obfuscated", "10.0.0.1:4711", []string{HeaderForwarded}, map[string][]string{"Forwarded": {"for=_hidden"}}, "10.0.0.1"},
+	{"forwarded without for", "10.0.0.1:4711", []string{HeaderForwarded}, map[string][]string{"Forwarded": {"for=198.51.100.1, proto=https"}}, "10.0.0.1"},
+	{"header preference", "10.0.0.1:4711", []string{HeaderForwarded, HeaderXForwardedFor}, map[string][]string{"X-Forwarded-For": {"198.51.100.2"}}, "198.51.100.2"},
+	{"x-real-ip", "10.0.0.1:4711", []string{HeaderXRealIP}, map[string][]string{"X-Real-Ip": {"198.51.100.1"}}, "198.51.100.1"},
+	{"x-real-ip repeated", "10.0.0.1:4711", []string{HeaderXRealIP}, map[string][]string{"X-Real-Ip": {"1.2.3.4", "198.51.100.1"}}, "10.0.0.1"},
+	{"x-real-ip invalid", "10.0.0.1:4711", []string{HeaderXRealIP}, map[string][]string{"X-Real-Ip": {"1.2.3.4, 5.6.7.8"}}, "10.0.0.1"},
+	{"x-real-ip from untrusted peer", "203.0.113.7:4711", []string{HeaderXRealIP}, map[string][]string{"X-Real-Ip": {"198.51.100.1"}}, "203.0.113.7"},
+}
+
+func TestClientIP(t *testing.T) {
+	for _, tt := range clientIPTests {
+		e := &Extractor{TrustedProxies: trustedProxies, Headers: tt.headers}
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remoteAddr
+		for key, values := range tt.header {
+			for _, value := range values {
+				r.Header.Add(key, value)
+			}
+		}
+
+		out, err := e.ClientIP(r)
+		if err != nil || out.String() != tt.out {
+			t.Errorf("%s: Extractor.ClientIP() = %v, %v, want %v", tt.name, out.String(), err, tt.out)
+		}
+	}
+}
+
+func TestClientIPInvalidRemoteAddr(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "@"
+	if _, err := New(trustedProxies...).ClientIP(r); err == nil {
+		t.Errorf("Extractor.ClientIP() = nil, want error")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var out ipx.IPAddr
+	var ok bool
+	h := New(trustedProxies...).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out, ok = FromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.1:4711"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if !ok || out.String() != "198.51.100.1" {
+		t.Errorf("FromContext() = %v, %v, want 198.51.100.1, true", out.String(), ok)
+	}
+
+	r.RemoteAddr = "@"
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if ok {
+		t.Errorf("FromContext() = %v, %v, want false", out.String(), ok)
+	}
+}
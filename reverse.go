@@ -0,0 +1,185 @@
+package ipx
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Reverse DNS domains
+const (
+	reverseV4Domain = "in-addr.arpa."
+	reverseV6Domain = "ip6.arpa."
+)
+
+// ReverseName returns the reverse DNS name of i
+// like "1.2.0.192.in-addr.arpa." for IPv4 addresses and the nibble form
+// like "1.0.0.0. ... .8.b.d.0.1.0.0.2.ip6.arpa." for IPv6 addresses
+// It returns an empty string if i is not a valid IP address
+func (i IP) ReverseName() string {
+	if v4 := i.IP.To4(); v4 != nil {
+		return reverseLabels(v4, 10) + reverseV4Domain
+	}
+	if len(i.IP) != IPv6len {
+		return ""
+	}
+	return reverseLabels(nibbles(i.IP), 16) + reverseV6Domain
+}
+
+// ReverseZones returns the reverse DNS zones the addresses of n are
+// delegated in
+// Zones are cut at octet boundaries for IPv4 and at nibble boundaries for
+// IPv6, so a network whose prefix length is not on a boundary spans
+// multiple zones, like 172.16.0.0/12 which spans the zones from
+// "16.172.in-addr.arpa." to "31.172.in-addr.arpa."
+// IPv4 networks with prefix lengths between 25 and 31 are delegated with
+// RFC 2317 classless names like "128/25.2.0.192.in-addr.arpa."
+// It returns nil if n does not have a canonical mask
+func (n *IPNet) ReverseZones() []string {
+	ip, m := networkNumberAndMask(n)
+	ones := simpleMaskLength(m)
+	if ip == nil || ones == -1 {
+		return nil
+	}
+
+	network := ip.Mask(m)
+	if len(network) == IPv4len {
+		if ones > 24 && ones < 32 {
+			return []string{strconv.Itoa(int(network[3])) + "/" + strconv.Itoa(ones) + "." +
+				reverseLabels(network[:3], 10) + reverseV4Domain}
+		}
+		return reverseZones(network, ones, 8, 10, reverseV4Domain)
+	}
+	return reverseZones(nibbles(network), ones, 4, 16, reverseV6Domain)
+}
+
+// reverseZones returns the zones covering the network with given prefix
+// length whose parts of partBits bits each are labels in given base
+// The prefix length is extended to the next part boundary
+func reverseZones(parts []byte, ones, partBits, base int, domain string) []string {
+	n := (ones + partBits - 1) / partBits
+	count := 1 << uint(n*partBits-ones)
+
+	zones := make([]string, 0, count)
+	zone := append([]byte(nil), parts[:n]...)
+	for k := 0; k < count; k++ {
+		// the extended bits of the last part are zero in network
+		if n > 0 {
+			zone[n-1] = parts[n-1] + byte(k)
+		}
+		zones = append(zones, reverseLabels(zone, base)+domain)
+	}
+	return zones
+}
+
+// nibbles returns the 4-bit nibbles of ip from the most significant one
+func nibbles(ip net.IP) []byte {
+	n := make([]byte, 0, 2*len(ip))
+	for _, b := range ip {
+		n = append(n, b>>4, b&0xf)
+	}
+	return n
+}
+
+// reverseLabels formats parts in given base as labels in reverse order,
+// each followed by a dot
+func reverseLabels(parts []byte, base int) string {
+	var b strings.Builder
+	for k := len(parts) - 1; k >= 0; k-- {
+		b.WriteString(strconv.FormatUint(uint64(parts[k]), base))
+		b.WriteByte('.')
+	}
+	return b.String()
+}
+
+// ParseReverseName parses s as a reverse DNS name under in-addr.arpa or
+// ip6.arpa and returns the network it names
+// The name of an address like "1.2.0.192.in-addr.arpa." returns a single
+// address network, and the name of a zone like "2.0.192.in-addr.arpa."
+// or the RFC 2317 classless name like "128/25.2.0.192.in-addr.arpa."
+// returns the network delegated in the zone
+// The trailing dot is optional and names are case-insensitive
+func ParseReverseName(s string) (*IPNet, error) {
+	name := strings.ToLower(strings.TrimSuffix(s, "."))
+	switch {
+	case name == strings.TrimSuffix(reverseV4Domain, "."):
+		return newIPNet(Uint128{}, 0, true), nil
+	case name == strings.TrimSuffix(reverseV6Domain, "."):
+		return newIPNet(Uint128{}, 0, false), nil
+	case strings.HasSuffix(name, "."+reverseV4Domain[:len(reverseV4Domain)-1]):
+		if ipNet, ok := parseReverseV4(name[:len(name)-len(reverseV4Domain)]); ok {
+			return ipNet, nil
+		}
+	case strings.HasSuffix(name, "."+reverseV6Domain[:len(reverseV6Domain)-1]):
+		if ipNet, ok := parseReverseV6(name[:len(name)-len(reverseV6Domain)]); ok {
+			return ipNet, nil
+		}
+	}
+	return nil, &ParseError{Type: "reverse DNS name", Text: s}
+}
+
+// MustParseReverseName parses s as a reverse DNS name
+// if an error ocurred, it throws a panic
+func MustParseReverseName(s string) *IPNet {
+	ipNet, err := ParseReverseName(s)
+	if err != nil {
+		panic(err)
+	}
+	return ipNet
+}
+
+// parseReverseV4 parses the labels of a name under in-addr.arpa
+func parseReverseV4(s string) (*IPNet, bool) {
+	labels := strings.Split(s, ".")
+	if len(labels) > IPv4len {
+		return nil, false
+	}
+
+	// RFC 2317 classless delegation
+	ones := 8 * len(labels)
+	if slash := strings.IndexByte(labels[0], '/'); slash >= 0 && len(labels) == IPv4len {
+		l, err := strconv.Atoi(labels[0][slash+1:])
+		if err != nil || !isDigits(labels[0][slash+1:]) || l < 25 || l > 31 {
+			return nil, false
+		}
+		labels[0], ones = labels[0][:slash], l
+	}
+
+	var val uint64
+	for k := len(labels) - 1; k >= 0; k-- {
+		if !isOctet(labels[k]) {
+			return nil, false
+		}
+		v, _ := strconv.Atoi(labels[k])
+		val = val<<8 | uint64(v)
+	}
+	val <<= uint(8 * (IPv4len - len(labels)))
+	if val&(1<<uint(32-ones)-1) != 0 {
+		return nil, false
+	}
+	return newIPNet(NewUint128(val), ones, true), true
+}
+
+// parseReverseV6 parses the labels of a name under ip6.arpa
+func parseReverseV6(s string) (*IPNet, bool) {
+	labels := strings.Split(s, ".")
+	if len(labels) > 2*IPv6len {
+		return nil, false
+	}
+
+	var b [IPv6len]byte
+	for k, label := range labels {
+		if len(label) != 1 {
+			return nil, false
+		}
+		v, err := strconv.ParseUint(label, 16, 4)
+		if err != nil {
+			return nil, false
+		}
+		n := len(labels) - 1 - k
+		b[n/2] |= byte(v) << uint(4*(1-n%2))
+	}
+
+	ones := 4 * len(labels)
+	return &IPNet{IP: IP{net.IP(b[:])}, Mask: CIDRMask(ones, 8*IPv6len)}, true
+}
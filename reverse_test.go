@@ -0,0 +1,114 @@
+package ipx
+
+import (
+	"reflect"
+	"testing"
+)
+
+var reverseNameTests = []struct {
+	in  IP
+	out string
+}{
+	{IPv4(192, 0, 2, 1), "1.2.0.192.in-addr.arpa."},
+	{MustParseIP("::ffff:10.0.0.1"), "1.0.0.10.in-addr.arpa."},
+	{MustParseIP("2001:db8::567:89ab"), "b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."},
+	{IPv6loopback, "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.ip6.arpa."},
+	{IP{}, ""},
+}
+
+func TestIPReverseName(t *testing.T) {
+	for _, tt := range reverseNameTests {
+		if out := tt.in.ReverseName(); out != tt.out {
+			t.Errorf("IP.ReverseName(%v) = %q, want %q", tt.in, out, tt.out)
+		}
+	}
+}
+
+var reverseZonesTests = []struct {
+	in  *IPNet
+	out []string
+}{
+	{MustParseCIDR("0.0.0.0/0"), []string{"in-addr.arpa."}},
+	{MustParseCIDR("10.0.0.0/8"), []string{"10.in-addr.arpa."}},
+	{MustParseCIDR("192.168.1.0/24"), []string{"1.168.192.in-addr.arpa."}},
+	{MustParseCIDR("192.168.1.7/24"), []string{"1.168.192.in-addr.arpa."}},
+	{MustParseCIDR("192.0.2.1/32"), []string{"1.2.0.192.in-addr.arpa."}},
+	{MustParseCIDR("192.168.4.0/22"), []string{"4.168.192.in-addr.arpa.", "5.168.192.in-addr.arpa.", "6.168.192.in-addr.arpa.", "7.168.192.in-addr.arpa."}},
+	{MustParseCIDR("128.0.0.0/7"), []string{"128.in-addr.arpa.", "129.in-addr.arpa."}},
+	{MustParseCIDR("192.0.2.128/25"), []string{"128/25.2.0.192.in-addr.arpa."}},
+	{MustParseCIDR("192.0.2.64/26"), []string{"64/26.2.0.192.in-addr.arpa."}},
+	{MustParseCIDR("192.0.2.254/31"), []string{"254/31.2.0.192.in-addr.arpa."}},
+	{MustParseCIDR("::/0"), []string{"ip6.arpa."}},
+	{MustParseCIDR("2001:db8::/32"), []string{"8.b.d.0.1.0.0.2.ip6.arpa."}},
+	{MustParseCIDR("2001:db8::/31"), []string{"8.b.d.0.1.0.0.2.ip6.arpa.", "9.b.d.0.1.0.0.2.ip6.arpa."}},
+	{MustParseCIDR("2001:db8:f0::/46"), []string{"0.f.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "1.f.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "2.f.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "3.f.0.0.8.b.d.0.1.0.0.2.ip6.arpa."}},
+	{MustParseCIDR("2001:db8::1/128"), []string{"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."}},
+	{&IPNet{IP: IPv4(10, 0, 0, 0), Mask: IPMask{[]byte{255, 0, 255, 0}}}, nil},
+	{&IPNet{}, nil},
+}
+
+func TestIPNetReverseZones(t *testing.T) {
+	for _, tt := range reverseZonesTests {
+		if out := tt.in.ReverseZones(); !reflect.DeepEqual(out, tt.out) {
+			t.Errorf("IPNet.ReverseZones(%v) = %q, want %q", tt.in, out, tt.out)
+		}
+	}
+}
+
+var parseReverseNameTests = []struct {
+	in  string
+	out string
+	ok  bool
+}{
+	{"1.2.0.192.in-addr.arpa.", "192.0.2.1/32", true},
+	{"1.2.0.192.IN-ADDR.ARPA", "192.0.2.1/32", true},
+	{"2.0.192.in-addr.arpa.", "192.0.2.0/24", true},
+	{"10.in-addr.arpa.", "10.0.0.0/8", true},
+	{"in-addr.arpa.", "0.0.0.0/0", true},
+	{"128/25.2.0.192.in-addr.arpa.", "192.0.2.128/25", true},
+	{"b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "2001:db8::567:89ab/128", true},
+	{"8.b.d.0.1.0.0.2.ip6.arpa.", "2001:db8::/32", true},
+	{"8.B.D.0.1.0.0.2.IP6.ARPA", "2001:db8::/32", true},
+	{"ip6.arpa", "::/0", true},
+	{"1.1.2.0.192.in-addr.arpa.", "", false},
+	{"256.2.0.192.in-addr.arpa.", "", false},
+	{"01.2.0.192.in-addr.arpa.", "", false},
+	{"1..0.192.in-addr.arpa.", "", false},
+	{"127/25.2.0.192.in-addr.arpa.", "", false},
+	{"128/24.2.0.192.in-addr.arpa.", "", false},
+	{"128/25.0.192.in-addr.arpa.", "", false},
+	{"10.ip6.arpa.", "", false},
+	{"g.ip6.arpa.", "", false},
+	{"1.2.0.192.example.com.", "", false},
+	{"", "", false},
+}
+
+func TestParseReverseName(t *testing.T) {
+	for _, tt := range parseReverseNameTests {
+		out, err := ParseReverseName(tt.in)
+		if (err == nil) != tt.ok || err == nil && out.String() != tt.out {
+			t.Errorf("ParseReverseName(%q) = %v, %v, want %v", tt.in, out, err, tt.out)
+		}
+	}
+}
+
+func TestReverseNameRoundTrip(t *testing.T) {
+	for _, s := range []string{"192.0.2.1", "2001:db8::1", "fe80::abcd:1"} {
+		ip := MustParseIP(s)
+		ipNet, err := ParseReverseName(ip.ReverseName())
+		if err != nil || ipNet.String() != hostIPNet(ip).String() {
+			t.Errorf("ParseReverseName(%q) = %v, %v, want %v", ip.ReverseName(), ipNet, err, ip)
+		}
+	}
+
+	for _, tt := range reverseZonesTests[:len(reverseZonesTests)-2] {
+		zones := tt.in.ReverseZones()
+		if len(zones) != 1 {
+			continue
+		}
+		ipNet, err := ParseReverseName(zones[0])
+		if err != nil || ipNet.String() != tt.in.Range().Prefixes()[0].String() {
+			t.Errorf("ParseReverseName(%q) = %v, %v, want %v", zones[0], ipNet, err, tt.in)
+		}
+	}
+}
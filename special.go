@@ -0,0 +1,222 @@
+package ipx
+
+// SpecialPurpose represents an entry of the IANA IPv4 and IPv6
+// Special-Purpose Address Registries
+// See https://www.iana.org/assignments/iana-ipv4-special-registry
+// and https://www.iana.org/assignments/iana-ipv6-special-registry
+type SpecialPurpose struct {
+	Prefix *IPNet // address block
+	Name   string
+	RFC    string
+
+	Source             bool // valid as a source address
+	Destination        bool // valid as a destination address
+	Forwardable        bool // forwardable by routers
+	GloballyReachable  bool // globally reachable, false if the registry says N/A
+	ReservedByProtocol bool // reserved by protocol
+}
+
+// specialPurposeRegistry holds the IANA Special-Purpose Address Registry entries
+var specialPurposeRegistry = []*SpecialPurpose{
+	// IPv4
+	newSpecialPurpose("0.0.0.0/8", "This network", "RFC 791, Section 3.2", true, false, false, false, true),
+	newSpecialPurpose("0.0.0.0/32", "This host on this network", "RFC 1122, Section 3.2.1.3", true, false, false, false, true),
+	newSpecialPurpose("10.0.0.0/8", "Private-Use", "RFC 1918", true, true, true, false, false),
+	newSpecialPurpose("100.64.0.0/10", "Shared Address Space", "RFC 6598", true, true, true, false, false),
+	newSpecialPurpose("127.0.0.0/8", "Loopback", "RFC 1122, Section 3.2.1.3", false, false, false, false, true),
+	newSpecialPurpose("169.254.0.0/16", "Link Local", "RFC 3927", true, true, false, false, true),
+	newSpecialPurpose("172.16.0.0/12", "Private-Use", "RFC 1918", true, true, true, false, false),
+	newSpecialPurpose("192.0.0.0/24", "IETF Protocol Assignments", "RFC 6890, Section 2.1", false, false, false, false, false),
+	newSpecialPurpose("192.0.0.0/29", "IPv4 Service Continuity Prefix", "RFC 7335", true, true, true, false, false),
+	newSpecialPurpose("192.0.0.8/32", "IPv4 dummy address", "RFC 7600", true, false, false, false, false),
+	newSpecialPurpose("192.0.0.9/32", "Port Control Protocol Anycast", "RFC 7723", true, true, true, true, false),
+	newSpecialPurpose("192.0.0.10/32", "Traversal Using Relays around NAT Anycast", "RFC 8155", true, true, true, true, false),
+	newSpecialPurpose("192.0.0.170/32", "NAT64/DNS64 Discovery", "RFC 8880, RFC 7050, Section 2.2", false, false, false, false, true),
+	newSpecialPurpose("192.0.0.171/32", "NAT64/DNS64 Discovery", "RFC 8880, RFC 7050, Section 2.2", false, false, false, false, true),
+	newSpecialPurpose("192.0.2.0/24", "Documentation (TEST-NET-1)", "RFC 5737", false, false, false, false, false),
+	newSpecialPurpose("192.31.196.0/24", "AS112-v4", "RFC 7535", true, true, true, true, false),
+	newSpecialPurpose("192.52.193.0/24", "AMT", "RFC 7450", true, true, true, true, false),
+	newSpecialPurpose("192.88.99.0/24", "Deprecated (6to4 Relay Anycast)", "RFC 7526", false, false, false, false, false),
+	newSpecialPurpose("192.88.99.2/32", "6a44-relay anycast address", "RFC 6751", true, true, true, false, false),
+	newSpecialPurpose("192.168.0.0/16", "Private-Use", "RFC 1918", true, true, true, false, false),
+	newSpecialPurpose("192.175.48.0/24", "Direct Delegation AS112 Service", "RFC 7534", true, true, true, true, false),
+	newSpecialPurpose("198.18.0.0/15", "Benchmarking", "RFC 2544", true, true, true, false, false),
+	newSpecialPurpose("198.51.100.0/24", "Documentation (TEST-NET-2)", "RFC 5737", false, false, false, false, false),
+	newSpecialPurpose("203.0.113.0/24", "Documentation (TEST-NET-3)", "RFC 5737", false, false, false, false, false),
+	newSpecialPurpose("240.0.0.0/4", "Reserved", "RFC 1112, Section 4", false, false, false, false, true),
+	newSpecialPurpose("255.255.255.255/32", "Limited Broadcast", "RFC 8190, RFC 919, Section 7", false, true, false, false, true),
+
+	// IPv6
+	newSpecialPurpose("::1/128", "Loopback Address", "RFC 4291", false, false, false, false, true),
+	newSpecialPurpose("::/128", "Unspecified Address", "RFC 4291", true, false, false, false, true),
+	newSpecialPurpose("::ffff:0:0/96", "IPv4-mapped Address", "RFC 4291", false, false, false, false, true),
+	newSpecialPurpose("64:ff9b::/96", "IPv4-IPv6 Translat.", "RFC 6052", true, true, true, true, false),
+	newSpecialPurpose("64:ff9b:1::/48", "IPv4-IPv6 Translat.", "RFC 8215", true, true, true, false, false),
+	newSpecialPurpose("100::/64", "Discard-Only Address Block", "RFC 6666", true, true, true, false, false),
+	newSpecialPurpose("100:0:0:1::/64", "Dummy IPv6 Prefix", "RFC 9780", true, false, false, false, false),
+	newSpecialPurpose("2001::/23", "IETF Protocol Assignments", "RFC 2928", false, false, false, false, false),
+	newSpecialPurpose("2001::/32", "TEREDO", "RFC 4380, RFC 8190", true, true, true, false, false),
+	newSpecialPurpose("2001:1::1/128", "Port Control Protocol Anycast", "RFC 7723", true, true, true, true, false),
+	newSpecialPurpose("2001:1::2/128", "Traversal Using Relays around NAT Anycast", "RFC 8155", true, true, true, true, false),
+	newSpecialPurpose("2001:1::3/128", "DNS-SD Service Registration Protocol Anycast", "RFC 9665", true, true, true, true, false),
+	newSpecialPurpose("2001:2::/48", "Benchmarking", "RFC 5180", true, true, true, false, false),
+	newSpecialPurpose("2001:3::/32", "AMT", "RFC 7450", true, true, true, true, false),
+	newSpecialPurpose("2001:4:112::/48", "AS112-v6", "RFC 7535", true, true, true, true, false),
+	newSpecialPurpose("2001:10::/28", "Deprecated (previously ORCHID)", "RFC 4843", false, false, false, false, false),
+	newSpecialPurpose("2001:20::/28", "ORCHIDv2", "RFC 7343", true, true, true, true, false),
+	newSpecialPurpose("2001:30::/28", "Drone Remote ID Protocol Entity Tags (DETs) Prefix", "RFC 9374", true, true, true, true, false),
+	newSpecialPurpose("2001:db8::/32", "Documentation", "RFC 3849", false, false, false, false, false),
+	newSpecialPurpose("2002::/16", "6to4", "RFC 3056", true, true, true, false, false),
+	newSpecialPurpose("2620:4f:8000::/48", "Direct Delegation AS112 Service", "RFC 7534", true, true, true, true, false),
+	newSpecialPurpose("3fff::/20", "Documentation", "RFC 9637", false, false, false, false, false),
+	newSpecialPurpose("5f00::/16", "Segment Routing (SRv6) SIDs", "RFC 9602", true, true, true, false, false),
+	newSpecialPurpose("fc00::/7", "Unique-Local", "RFC 4193, RFC 8190", true, true, true, false, false),
+	newSpecialPurpose("fe80::/10", "Link-Local Unicast", "RFC 4291", true, true, false, false, true),
+}
+
+// Special-purpose address blocks used by the predicates
+var (
+	sharedAddressSpace = MustParseCIDR("100.64.0.0/10")
+	benchmarkingV4     = MustParseCIDR("198.18.0.0/15")
+	benchmarkingV6     = MustParseCIDR("2001:2::/48")
+	documentation      = []*IPNet{
+		MustParseCIDR("192.0.2.0/24"),
+		MustParseCIDR("198.51.100.0/24"),
+		MustParseCIDR("203.0.113.0/24"),
+		MustParseCIDR("2001:db8::/32"),
+		MustParseCIDR("3fff::/20"),
+	}
+)
+
+// SpecialPurposeRegistry returns all entries of the IANA IPv4 and IPv6
+// Special-Purpose Address Registries
+func SpecialPurposeRegistry() []SpecialPurpose {
+	entries := make([]SpecialPurpose, 0, len(specialPurposeRegistry))
+	for _, entry := range specialPurposeRegistry {
+		entries = append(entries, *entry)
+	}
+	return entries
+}
+
+// IPSet returns the addresses of the entry
+// The Prefix of the IPv4-mapped Address entry contains all IPv4
+// addresses, as IPNets in IPv4-mapped form do, while its IPSet holds
+// the IPv6 addresses of the block
+func (e SpecialPurpose) IPSet() *IPSet {
+	if p, ok := mappedPrefix(e.Prefix); ok {
+		return MustNewIPSet(p)
+	}
+	return MustNewIPSet(e.Prefix)
+}
+
+// SpecialPurpose returns the most specific entry of the IANA
+// Special-Purpose Address Registries that contains i
+// It returns false if i is not a special-purpose address
+// IP does not tell IPv4-mapped addresses from IPv4 addresses, so they
+// are classified by their IPv4 address, use Addr.SpecialPurpose to
+// classify them as IPv4-mapped addresses
+func (i IP) SpecialPurpose() (SpecialPurpose, bool) {
+	var match *SpecialPurpose
+	for _, entry := range specialPurposeRegistry {
+		if _, ok := mappedPrefix(entry.Prefix); ok {
+			continue
+		}
+		if entry.Prefix.Contains(i) && (match == nil || entry.Prefix.NetworkSize() > match.Prefix.NetworkSize()) {
+			match = entry
+		}
+	}
+
+	if match == nil {
+		return SpecialPurpose{}, false
+	}
+	return *match, true
+}
+
+// IsDocumentation returns whether i is reserved for documentation
+// (RFC 5737, RFC 3849 and RFC 9637)
+func (i IP) IsDocumentation() bool {
+	for _, n := range documentation {
+		if n.Contains(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSharedAddressSpace returns whether i is in the Shared Address Space
+// used by Carrier-Grade NAT (RFC 6598)
+func (i IP) IsSharedAddressSpace() bool {
+	return sharedAddressSpace.Contains(i)
+}
+
+// IsBenchmarking returns whether i is reserved for benchmarking
+// (RFC 2544 and RFC 5180)
+func (i IP) IsBenchmarking() bool {
+	return benchmarkingV4.Contains(i) || benchmarkingV6.Contains(i)
+}
+
+// IsGloballyReachable returns whether i is globally reachable
+// according to the IANA Special-Purpose Address Registries
+// Addresses that are not in the registries are globally reachable
+// if they are global unicast addresses
+func (i IP) IsGloballyReachable() bool {
+	if entry, ok := i.SpecialPurpose(); ok {
+		return entry.GloballyReachable
+	}
+	return i.IsGlobalUnicast()
+}
+
+// SpecialPurpose returns the most specific entry of the IANA
+// Special-Purpose Address Registries that contains a
+// Unlike IP, Addr keeps IPv4-mapped IPv6 addresses apart from IPv4
+// addresses, so they are classified as IPv4-mapped addresses
+// It returns false if a is not a special-purpose address
+func (a Addr) SpecialPurpose() (SpecialPurpose, bool) {
+	if a.Is4In6() {
+		for _, entry := range specialPurposeRegistry {
+			if p, ok := mappedPrefix(entry.Prefix); ok && p.Contains(a) {
+				return *entry, true
+			}
+		}
+	}
+	if !a.IsValid() {
+		return SpecialPurpose{}, false
+	}
+	return a.IP().SpecialPurpose()
+}
+
+// IsGloballyReachable returns whether a is globally reachable
+// according to the IANA Special-Purpose Address Registries
+// IPv4-mapped IPv6 addresses are not
+func (a Addr) IsGloballyReachable() bool {
+	if entry, ok := a.SpecialPurpose(); ok {
+		return entry.GloballyReachable
+	}
+	return a.IsValid() && a.IP().IsGlobalUnicast()
+}
+
+// mappedPrefix returns n as an IPv6 Prefix if n is in IPv4-mapped form
+// like ::ffff:0:0/96, which IPNet treats as an IPv4 network
+func mappedPrefix(n *IPNet) (Prefix, bool) {
+	if len(n.IP.IP) != IPv6len || len(n.Mask.IPMask) != IPv6len || n.IP.To4().IP == nil {
+		return Prefix{}, false
+	}
+	ones, _ := n.Mask.IPMask.Size()
+	var b [16]byte
+	copy(b[:], n.IP.IP)
+	return PrefixFrom(AddrFrom16(b), ones), true
+}
+
+// newSpecialPurpose creates a new registry entry
+func newSpecialPurpose(cidr, name, rfc string, source, destination, forwardable, global, reserved bool) *SpecialPurpose {
+	return &SpecialPurpose{
+		Prefix:             MustParseCIDR(cidr),
+		Name:               name,
+		RFC:                rfc,
+		Source:             source,
+		Destination:        destination,
+		Forwardable:        forwardable,
+		GloballyReachable:  global,
+		ReservedByProtocol: reserved,
+	}
+}
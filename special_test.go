@@ -0,0 +1,111 @@
+package ipx
+
+import "testing"
+
+var specialPurposeTests = []struct {
+	in     IP
+	name   string
+	global bool
+}{
+	{IPv4(0, 0, 0, 0), "This host on this network", false},
+	{IPv4(0, 1, 2, 3), "This network", false},
+	{IPv4(10, 1, 2, 3), "Private-Use", false},
+	{IPv4(100, 64, 0, 1), "Shared Address Space", false},
+	{IPv4(127, 0, 0, 1), "Loopback", false},
+	{IPv4(192, 0, 0, 9), "Port Control Protocol Anycast", true},
+	{IPv4(192, 0, 0, 100), "IETF Protocol Assignments", false},
+	{IPv4(198, 19, 1, 1), "Benchmarking", false},
+	{IPv4(203, 0, 113, 7), "Documentation (TEST-NET-3)", false},
+	{IPv4(255, 255, 255, 255), "Limited Broadcast", false},
+	{IPv4(8, 8, 8, 8), "", true},
+	{IPv4(224, 0, 0, 1), "", false},
+	{MustParseIP("::1"), "Loopback Address", false},
+	{MustParseIP("64:ff9b::808:808"), "IPv4-IPv6 Translat.", true},
+	{MustParseIP("2001:1::1"), "Port Control Protocol Anycast", true},
+	{MustParseIP("2001:1::5"), "IETF Protocol Assignments", false},
+	{MustParseIP("2001:0::1"), "TEREDO", false},
+	{MustParseIP("2001:db8::1"), "Documentation", false},
+	{MustParseIP("fe80::1"), "Link-Local Unicast", false},
+	{MustParseIP("2606:4700::1111"), "", true},
+	{MustParseIP("ff02::1"), "", false},
+}
+
+func TestSpecialPurpose(t *testing.T) {
+	for _, tt := range specialPurposeTests {
+		entry, ok := tt.in.SpecialPurpose()
+		if ok != (tt.name != "") || entry.Name != tt.name {
+			t.Errorf("IP.SpecialPurpose(%v) = %v, %v, want %v", tt.in, entry.Name, ok, tt.name)
+		}
+		if out := tt.in.IsGloballyReachable(); out != tt.global {
+			t.Errorf("IP.IsGloballyReachable(%v) = %v, want %v", tt.in, out, tt.global)
+		}
+	}
+}
+
+var addrSpecialPurposeTests = []struct {
+	in     Addr
+	name   string
+	global bool
+}{
+	{AddrFrom16([16]byte{10: 0xff, 11: 0xff, 12: 8, 13: 8, 14: 8, 15: 8}), "IPv4-mapped Address", false},
+	{AddrFrom16([16]byte{10: 0xff, 11: 0xff, 12: 127, 15: 1}), "IPv4-mapped Address", false},
+	{MustParseAddr("::ffff:8.8.8.8"), "", true},
+	{MustParseAddr("127.0.0.1"), "Loopback", false},
+	{MustParseAddr("2001:db8::1"), "Documentation", false},
+	{MustParseAddr("2606:4700::1111"), "", true},
+	{Addr{}, "", false},
+}
+
+func TestAddrSpecialPurpose(t *testing.T) {
+	for _, tt := range addrSpecialPurposeTests {
+		entry, ok := tt.in.SpecialPurpose()
+		if ok != (tt.name != "") || entry.Name != tt.name {
+			t.Errorf("Addr.SpecialPurpose(%v) = %v, %v, want %v", tt.in, entry.Name, ok, tt.name)
+		}
+		if out := tt.in.IsGloballyReachable(); out != tt.global {
+			t.Errorf("Addr.IsGloballyReachable(%v) = %v, want %v", tt.in, out, tt.global)
+		}
+	}
+
+	// the IPv4-mapped block does not take over IPv4 addresses
+	want := ipSpan{uint128FromBytes(MustParseIP("::ffff:0:0").IP), uint128FromBytes(MustParseIP("::ffff:ffff:ffff").IP)}
+	for _, entry := range SpecialPurposeRegistry() {
+		if entry.Name != "IPv4-mapped Address" {
+			continue
+		}
+		if set := entry.IPSet(); len(set.v4) != 0 || len(set.v6) != 1 || set.v6[0] != want {
+			t.Errorf("SpecialPurpose.IPSet() = %v, want ::ffff:0:0/96", set)
+		}
+	}
+}
+
+var specialPredicateTests = []struct {
+	in            IP
+	documentation bool
+	shared        bool
+	benchmarking  bool
+}{
+	{IPv4(192, 0, 2, 1), true, false, false},
+	{IPv4(198, 51, 100, 1), true, false, false},
+	{MustParseIP("3fff::1"), true, false, false},
+	{IPv4(100, 127, 255, 255), false, true, false},
+	{IPv4(100, 128, 0, 0), false, false, false},
+	{IPv4(198, 18, 0, 1), false, false, true},
+	{IPv4(192, 18, 0, 1), false, false, false},
+	{MustParseIP("2001:2::1"), false, false, true},
+	{IPv4(8, 8, 8, 8), false, false, false},
+}
+
+func TestSpecialPredicates(t *testing.T) {
+	for _, tt := range specialPredicateTests {
+		if out := tt.in.IsDocumentation(); out != tt.documentation {
+			t.Errorf("IP.IsDocumentation(%v) = %v, want %v", tt.in, out, tt.documentation)
+		}
+		if out := tt.in.IsSharedAddressSpace(); out != tt.shared {
+			t.Errorf("IP.IsSharedAddressSpace(%v) = %v, want %v", tt.in, out, tt.shared)
+		}
+		if out := tt.in.IsBenchmarking(); out != tt.benchmarking {
+			t.Errorf("IP.IsBenchmarking(%v) = %v, want %v", tt.in, out, tt.benchmarking)
+		}
+	}
+}
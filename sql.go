@@ -0,0 +1,214 @@
+package ipx
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+)
+
+// Error definitions
+var (
+	ErrInvalidScanType = errors.New("unsupported scan type")
+)
+
+// Scan implements the sql.Scanner interface
+// It accepts the textual form as string or []byte like PostgreSQL inet
+// columns return, where the prefix length of the host address is ignored,
+// and the 4 or 16-byte binary form like MySQL VARBINARY(16) columns
+// return
+// []byte inputs are parsed as text first and read as binary only if
+// they are not a textual address, which binary addresses rarely are
+// as their bytes would all have to be printable
+// NULL is scanned as the zero IP
+func (i *IP) Scan(src interface{}) error {
+	if src == nil {
+		*i = IP{}
+		return nil
+	}
+	text, b, err := scanSource(src)
+	if err != nil {
+		return err
+	}
+
+	if text != "" {
+		if slash := strings.IndexByte(text, '/'); slash >= 0 {
+			text = text[:slash]
+		}
+		if ip := net.ParseIP(text); ip != nil {
+			*i = IP{ip}
+			return nil
+		}
+	}
+
+	if len(b) != IPv4len && len(b) != IPv6len {
+		return &ParseError{Type: "IP address", Text: text}
+	}
+	*i = IP{append(net.IP(nil), b...)}
+	return nil
+}
+
+// Value implements the driver.Valuer interface
+// The value is the textual form of i which can be stored in PostgreSQL
+// inet columns, or NULL for the zero IP
+// Use i.To16().IP as the value for binary columns like VARBINARY(16)
+func (i IP) Value() (driver.Value, error) {
+	if i.IP == nil {
+		return nil, nil
+	}
+	return i.String(), nil
+}
+
+// Scan implements the sql.Scanner interface
+// It accepts the textual form as string or []byte like PostgreSQL cidr
+// and inet columns return, where a host address without a prefix length
+// is scanned as a single address network, the 4 or 16-byte binary form
+// of a host address and the binary form MarshalBinary returns
+// NULL is scanned as the zero IPNet
+func (n *IPNet) Scan(src interface{}) error {
+	if src == nil {
+		*n = IPNet{}
+		return nil
+	}
+	text, b, err := scanSource(src)
+	if err != nil {
+		return err
+	}
+
+	if !strings.Contains(text, "/") {
+		if ip := net.ParseIP(text); ip != nil {
+			*n = *hostIPNet(IP{ip})
+			return nil
+		}
+	} else if _, ipNet, err := ParseCIDR(text); err == nil {
+		*n = *ipNet
+		return nil
+	}
+
+	switch len(b) {
+	case IPv4len, IPv6len:
+		*n = *hostIPNet(IP{append(net.IP(nil), b...)})
+		return nil
+	case IPv4len + 1, IPv6len + 1:
+		return n.UnmarshalBinary(b)
+	}
+	return &ParseError{Type: "CIDR address", Text: text}
+}
+
+// Value implements the driver.Valuer interface
+// The value is the CIDR notation of n which can be stored in PostgreSQL
+// cidr and inet columns, or NULL for the zero IPNet
+func (n IPNet) Value() (driver.Value, error) {
+	if n.IP.IP == nil && n.Mask.IPMask == nil {
+		return nil, nil
+	}
+	text, err := n.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return string(text), nil
+}
+
+// Scan implements the sql.Scanner interface
+// It accepts PostgreSQL range literals like "[10.0.0.1,10.0.0.9]",
+// "[10.0.0.1,10.0.0.10)" or "empty", the textual form MarshalText returns
+// like "10.0.0.1-10.0.0.9" and the binary form of a pair of 4 or 16-byte
+// addresses like MarshalBinary returns
+// NULL is scanned as the zero IPRange
+func (i *IPRange) Scan(src interface{}) error {
+	if src == nil {
+		*i = IPRange{}
+		return nil
+	}
+	text, b, err := scanSource(src)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case text == "empty":
+		*i = IPRange{Lower: IPv4zero, Upper: IPv4zero}
+		return nil
+	case strings.HasPrefix(text, "[") || strings.HasPrefix(text, "("):
+		return i.scanRangeLiteral(text)
+	case text != "":
+		if err := i.UnmarshalText([]byte(text)); err == nil {
+			return nil
+		}
+	}
+
+	if len(b) != 2*IPv4len && len(b) != 2*IPv6len {
+		return &ParseError{Type: "IP range", Text: text}
+	}
+	return i.UnmarshalBinary(b)
+}
+
+// scanRangeLiteral parses s as a PostgreSQL range literal
+// Unbounded ranges are not supported
+func (i *IPRange) scanRangeLiteral(s string) error {
+	comma := strings.IndexByte(s, ',')
+	last := len(s) - 1
+	if comma < 0 || last < comma || s[last] != ']' && s[last] != ')' {
+		return &ParseError{Type: "IP range", Text: s}
+	}
+
+	lower, upper := strings.Trim(s[1:comma], `" `), strings.Trim(s[comma+1:last], `" `)
+	x, y, err := parseIPRangeBoundaries(lower, upper)
+	if err != nil || y.Before(x) {
+		return &ParseError{Type: "IP range", Text: s}
+	}
+	if s[0] == '(' {
+		next := x.GetNext()
+		if next.Before(x) {
+			// there is no address after the last address of the family
+			*i = IPRange{Lower: y, Upper: y}
+			return nil
+		}
+		x = next
+	}
+	if y.Before(x) {
+		*i = IPRange{Lower: y, Upper: y}
+		return nil
+	}
+
+	if s[last] == ']' {
+		*i = *NewIPRangeInclusive(x, y)
+	} else {
+		*i = *NewIPRange(x, y)
+	}
+	return nil
+}
+
+// Value implements the driver.Valuer interface
+// The value is the PostgreSQL range literal like "[10.0.0.1,10.0.0.9]"
+// which can be stored in a range type over inet, "empty" for an empty
+// IPRange, or NULL for the zero IPRange
+// Use MarshalBinary to store the range as a pair of binary addresses
+func (i IPRange) Value() (driver.Value, error) {
+	if i.Lower.IP == nil && i.Upper.IP == nil {
+		return nil, nil
+	}
+	r, ok := AddrRangeFromIPRange(&i)
+	if !ok {
+		return "empty", nil
+	}
+	return "[" + r.first.String() + "," + r.last.String() + "]", nil
+}
+
+// scanSource returns src as text if it is a string or []byte
+// and the raw bytes of src if it is a []byte
+func scanSource(src interface{}) (text string, b []byte, err error) {
+	switch s := src.(type) {
+	case string:
+		return strings.TrimSpace(s), nil, nil
+	case []byte:
+		return strings.TrimSpace(string(s)), s, nil
+	}
+	return "", nil, ErrInvalidScanType
+}
+
+// hostIPNet returns the single address network of ip
+func hostIPNet(ip IP) *IPNet {
+	a, _ := AddrFromIP(ip)
+	return newIPNet(a.u, a.BitLen(), a.Is4())
+}
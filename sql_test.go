@@ -0,0 +1,211 @@
+package ipx
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// fakeDriver is a database/sql driver with a single table
+// INSERT statements append their arguments as a row and
+// any other query returns all rows
+type fakeDriver struct {
+	rows [][]driver.Value
+}
+
+var testDriver = &fakeDriver{}
+
+func init() {
+	sql.Register("ipxfake", testDriver)
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.d, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.rows = append(s.d.rows, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{rows: s.d.rows}, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+	idx  int
+}
+
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+	return make([]string, len(r.rows[0]))
+}
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.idx >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.idx])
+	r.idx++
+	return nil
+}
+
+func openFakeDB(t *testing.T, rows ...[]driver.Value) *sql.DB {
+	testDriver.rows = rows
+	db, err := sql.Open("ipxfake", "")
+	if err != nil {
+		t.Fatalf("sql.Open() = %v", err)
+	}
+	return db
+}
+
+func TestSQLRoundTrip(t *testing.T) {
+	db := openFakeDB(t)
+	defer db.Close()
+
+	ip, ipNet, ipRange := MustParseIP("2001:db8::1"), *MustParseCIDR("10.0.0.0/8"), *MustParseIPRange("10.0.0.1", "10.0.0.10")
+	if _, err := db.Exec("INSERT", ip, ipNet, ipRange); err != nil {
+		t.Fatalf("DB.Exec() = %v", err)
+	}
+	if _, err := db.Exec("INSERT", IP{}, IPNet{}, (*IPRange)(nil)); err != nil {
+		t.Fatalf("DB.Exec() = %v", err)
+	}
+
+	want := [][]driver.Value{
+		{"2001:db8::1", "10.0.0.0/8", "[10.0.0.1,10.0.0.9]"},
+		{nil, nil, nil},
+	}
+	if !reflect.DeepEqual(testDriver.rows, want) {
+		t.Errorf("DB.Exec() stored %v, want %v", testDriver.rows, want)
+	}
+
+	rows, err := db.Query("SELECT")
+	if err != nil {
+		t.Fatalf("DB.Query() = %v", err)
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var ip IP
+		var ipNet IPNet
+		var ipRange IPRange
+		if err := rows.Scan(&ip, &ipNet, &ipRange); err != nil {
+			t.Fatalf("Rows.Scan() = %v", err)
+		}
+		out = append(out, ipEmptyString(ip.IP), ipNet.String(), ipRange.String())
+	}
+	if w := []string{"2001:db8::1", "10.0.0.0/8", "10.0.0.1-10.0.0.9", "", "<nil>", "<nil>"}; !reflect.DeepEqual(out, w) {
+		t.Errorf("Rows.Scan() = %v, want %v", out, w)
+	}
+}
+
+var ipScanTests = []struct {
+	in  interface{}
+	out string
+	err bool
+}{
+	{"192.0.2.1", "192.0.2.1", false},
+	{[]byte("192.0.2.1/24"), "192.0.2.1", false},
+	{"2001:db8::1/128", "2001:db8::1", false},
+	{[]byte{192, 0, 2, 1}, "192.0.2.1", false},
+	{[]byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}, "2001:db8::1", false},
+	{"abcd", "", true},
+	{[]byte{192, 0, 2}, "", true},
+	{[]byte("1::2"), "1::2", false},
+	{[]byte("2001:db8::1:2:34"), "2001:db8::1:2:34", false},
+	{[]byte("192.168.10.20/24"), "192.168.10.20", false},
+	{int64(1), "", true},
+}
+
+func TestIPScan(t *testing.T) {
+	for _, tt := range ipScanTests {
+		var out IP
+		err := out.Scan(tt.in)
+		if (err != nil) != tt.err || ipEmptyString(out.IP) != tt.out {
+			t.Errorf("IP.Scan(%v) = %v, %v, want %v", tt.in, out, err, tt.out)
+		}
+	}
+}
+
+var ipNetScanTests = []struct {
+	in  interface{}
+	out string
+	err bool
+}{
+	{"10.0.0.0/8", "10.0.0.0/8", false},
+	{[]byte("10.1.2.3/8"), "10.0.0.0/8", false},
+	{"192.0.2.1", "192.0.2.1/32", false},
+	{"2001:db8::1", "2001:db8::1/128", false},
+	{[]byte{192, 0, 2, 1}, "192.0.2.1/32", false},
+	{[]byte{10, 0, 0, 0, 8}, "10.0.0.0/8", false},
+	{"10.0.0.0/33", "<nil>", true},
+	{[]byte{10, 0, 0, 0, 8, 0}, "<nil>", true},
+}
+
+func TestIPNetScan(t *testing.T) {
+	for _, tt := range ipNetScanTests {
+		var out IPNet
+		err := out.Scan(tt.in)
+		if (err != nil) != tt.err || out.String() != tt.out {
+			t.Errorf("IPNet.Scan(%v) = %v, %v, want %v", tt.in, out.String(), err, tt.out)
+		}
+	}
+}
+
+var ipRangeScanTests = []struct {
+	in    interface{}
+	out   string
+	value driver.Value
+	err   bool
+}{
+	{"[10.0.0.1,10.0.0.9]", "10.0.0.1-10.0.0.9", "[10.0.0.1,10.0.0.9]", false},
+	{"[10.0.0.1,10.0.0.10)", "10.0.0.1-10.0.0.9", "[10.0.0.1,10.0.0.9]", false},
+	{`("10.0.0.0","10.0.0.9"]`, "10.0.0.1-10.0.0.9", "[10.0.0.1,10.0.0.9]", false},
+	{"(10.0.0.1,10.0.0.2)", "<nil>", "empty", false},
+	{"(10.0.0.1,10.0.0.1]", "<nil>", "empty", false},
+	{"(255.255.255.255,255.255.255.255]", "<nil>", "empty", false},
+	{"(ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff,ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff]", "<nil>", "empty", false},
+	{"(255.255.255.254,255.255.255.255]", "255.255.255.255-255.255.255.255", "[255.255.255.255,255.255.255.255]", false},
+	{"empty", "<nil>", "empty", false},
+	{[]byte("[2001:db8::,2001:db8::ff]"), "2001:db8::-2001:db8::ff", "[2001:db8::,2001:db8::ff]", false},
+	{"10.0.0.1-10.0.0.9", "10.0.0.1-10.0.0.9", "[10.0.0.1,10.0.0.9]", false},
+	{[]byte{10, 0, 0, 1, 10, 0, 0, 9}, "10.0.0.1-10.0.0.9", "[10.0.0.1,10.0.0.9]", false},
+	{"[10.0.0.9,10.0.0.1]", "<nil>", nil, true},
+	{"[10.0.0.1,::1]", "<nil>", nil, true},
+	{"[10.0.0.1,)", "<nil>", nil, true},
+	{"10.0.0.1", "<nil>", nil, true},
+}
+
+func TestIPRangeScan(t *testing.T) {
+	for _, tt := range ipRangeScanTests {
+		var out IPRange
+		err := out.Scan(tt.in)
+		if (err != nil) != tt.err || out.String() != tt.out {
+			t.Errorf("IPRange.Scan(%v) = %v, %v, want %v", tt.in, out.String(), err, tt.out)
+		}
+		if value, _ := out.Value(); !tt.err && value != tt.value {
+			t.Errorf("IPRange.Value(%v) = %v, want %v", out.String(), value, tt.value)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+// Package ssrf guards outgoing connections against server-side request
+// forgery by blocking destinations that are not publicly reachable
+package ssrf
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+
+	"github.com/hakansa/ipx"
+)
+
+// Error definitions
+var (
+	ErrNoAddresses       = errors.New("host has no addresses")
+	ErrUnresolvedAddress = errors.New("address is not an ip address")
+)
+
+// Resolver looks up the addresses of a host
+// *net.Resolver implements Resolver
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// BlockedError declares a connection to a blocked destination
+type BlockedError struct {
+	Host     string     // host being connected to
+	IP       ipx.IP     // blocked address Host resolved to
+	Embedded ipx.IP     // blocked IPv4 address embedded in IP, if any
+	Form     ipx.IPForm // legacy form of Host if Host is an ambiguous IPv4 address
+}
+
+// Error throws the error
+func (e *BlockedError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	if e.Form != 0 {
+		return "host " + e.Host + ": ambiguous address in " + e.Form.String() + " form is blocked"
+	}
+
+	s := "address " + e.IP.String()
+	if e.Embedded.IP != nil {
+		s += " embedding " + e.Embedded.String()
+	}
+	s += " is blocked"
+	if e.Host != "" && e.Host != e.IP.String() {
+		s = "host " + e.Host + ": " + s
+	}
+	return s
+}
+
+// Guard decides which destinations connections can be made to
+// An address is blocked if it or an IPv4 address embedded in it is in
+// Deny and not in Allow
+// The embedded addresses are IPv4-mapped and IPv4-compatible addresses,
+// NAT64 addresses, 6to4 addresses and Teredo client addresses
+// The zero Guard blocks the addresses that are not globally reachable
+type Guard struct {
+	// Deny is the set of blocked addresses
+	// If nil, NonPublic() is used
+	Deny *ipx.IPSet
+
+	// Allow is the set of addresses that are not blocked
+	// even if they are in Deny
+	Allow *ipx.IPSet
+
+	// NAT64 is the list of NAT64 prefixes whose addresses embed
+	// IPv4 addresses as described in RFC 6052
+	// Prefixes of other lengths than 32, 40, 48, 56, 64 and 96 are ignored
+	// If nil, the well-known prefix 64:ff9b::/96 is used
+	NAT64 []*ipx.IPNet
+
+	// Resolver looks up the addresses of host names
+	// If nil, net.DefaultResolver is used
+	Resolver Resolver
+
+	// Dialer is used to make connections
+	// Its Control function is replaced by the Control method of Guard
+	// If nil, the zero net.Dialer is used
+	Dialer *net.Dialer
+}
+
+// Special-purpose address blocks used by the guard
+var (
+	nonPublic      = newNonPublic()
+	wellKnownNAT64 = []*ipx.IPNet{ipx.MustParseCIDR("64:ff9b::/96")}
+	sixToFour      = ipx.MustParseCIDR("2002::/16")
+	teredo         = ipx.MustParseCIDR("2001::/32")
+	ipv4Compat     = ipx.MustParseCIDR("::/96")
+)
+
+// NonPublic returns the set of addresses that are not globally reachable
+// according to the IANA Special-Purpose Address Registries,
+// and multicast addresses
+func NonPublic() *ipx.IPSet {
+	return nonPublic
+}
+
+// newNonPublic creates the set NonPublic returns
+// Globally reachable entries of the registries are nested in entries
+// that are not, so they are taken out of the set
+func newNonPublic() *ipx.IPSet {
+	deny := ipx.MustNewIPSet(ipx.MustParseCIDR("224.0.0.0/4"), ipx.MustParseCIDR("ff00::/8"))
+	allow := ipx.MustNewIPSet()
+	for _, entry := range ipx.SpecialPurposeRegistry() {
+		if entry.GloballyReachable {
+			allow = allow.Union(entry.IPSet())
+		} else {
+			deny = deny.Union(entry.IPSet())
+		}
+	}
+	return deny.Difference(allow)
+}
+
+// Check returns a *BlockedError if ip is blocked
+func (g *Guard) Check(ip ipx.IP) error {
+	return g.check("", ip)
+}
+
+// check returns a *BlockedError for host if ip is blocked
+func (g *Guard) check(host string, ip ipx.IP) error {
+	if ip.IP == nil || g.blocked(ip) {
+		return &BlockedError{Host: host, IP: ip}
+	}
+	for _, embedded := range g.embedded(ip) {
+		if g.blocked(embedded) {
+			return &BlockedError{Host: host, IP: ip, Embedded: embedded}
+		}
+	}
+	return nil
+}
+
+// blocked reports whether ip is in Deny and not in Allow
+func (g *Guard) blocked(ip ipx.IP) bool {
+	deny := g.Deny
+	if deny == nil {
+		deny = nonPublic
+	}
+	return deny.Contains(ip) && (g.Allow == nil || !g.Allow.Contains(ip))
+}
+
+// embedded returns the IPv4 addresses embedded in the IPv6 address ip
+func (g *Guard) embedded(ip ipx.IP) []ipx.IP {
+	if ip.IsV4() {
+		// IPv4-mapped addresses are checked as IPv4 addresses
+		return nil
+	}
+	b := ip.To16().IP
+
+	var addrs []ipx.IP
+	if ipv4Compat.Contains(ip) && !ip.Equal(ipx.IPv6zero) && !ip.Equal(ipx.IPv6loopback) {
+		addrs = append(addrs, ipx.IPv4(b[12], b[13], b[14], b[15]))
+	}
+	if sixToFour.Contains(ip) {
+		addrs = append(addrs, ipx.IPv4(b[2], b[3], b[4], b[5]))
+	}
+	if teredo.Contains(ip) {
+		// the client address is stored inverted
+		addrs = append(addrs, ipx.IPv4(^b[12], ^b[13], ^b[14], ^b[15]))
+	}
+
+	nat64 := g.NAT64
+	if nat64 == nil {
+		nat64 = wellKnownNAT64
+	}
+	for _, prefix := range nat64 {
+		ones, bits := prefix.Mask.Size()
+		if bits != 8*ipx.IPv6len || ones%8 != 0 || ones < 32 || ones > 96 || ones == 72 || ones == 80 || ones == 88 || !prefix.Contains(ip) {
+			continue
+		}
+
+		// bits 64 to 71 are reserved and skipped
+		v4 := make([]byte, 0, ipx.IPv4len)
+		for i := ones / 8; len(v4) < ipx.IPv4len; i++ {
+			if i != 8 {
+				v4 = append(v4, b[i])
+			}
+		}
+		addrs = append(addrs, ipx.IPv4(v4[0], v4[1], v4[2], v4[3]))
+	}
+	return addrs
+}
+
+// Control checks the address of a connection before it is made
+// It can be used as the Control function of a net.Dialer
+// so that connections to blocked addresses fail even if
+// a host name resolves to a different address when it is dialed
+// It returns ErrUnresolvedAddress if the address is not an IP address,
+// since dialers resolve host names before Control is called
+func (g *Guard) Control(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if zone := strings.IndexByte(host, '%'); zone >= 0 {
+		host = host[:zone]
+	}
+	ip, err := ipx.ParseIP(host)
+	if err != nil {
+		return ErrUnresolvedAddress
+	}
+	return g.check("", ip)
+}
+
+// Resolve looks up the addresses of host and checks every one of them
+// It returns a *BlockedError if any address is blocked or if host is an
+// IPv4 address in a legacy form that C resolvers accept
+func (g *Guard) Resolve(ctx context.Context, host string) ([]ipx.IP, error) {
+	if zone := strings.IndexByte(host, '%'); zone >= 0 && strings.IndexByte(host, ':') >= 0 {
+		host = host[:zone]
+	}
+
+	ip, form, err := ipx.ParseIPMode(host, ipx.ParseLenient)
+	switch {
+	case err == nil && form != 0:
+		return nil, &BlockedError{Host: host, IP: ip, Form: form}
+	case err == nil:
+		if err := g.check(host, ip); err != nil {
+			return nil, err
+		}
+		return []ipx.IP{ip}, nil
+	}
+
+	resolver := g.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, &net.DNSError{Err: ErrNoAddresses.Error(), Name: host, IsNotFound: true}
+	}
+
+	ips := make([]ipx.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		ip := ipx.IP{IP: addr.IP}
+		if err := g.check(host, ip); err != nil {
+			return nil, err
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+// DialContext resolves the host of address, checks every address it
+// resolves to and connects to the first address that accepts the
+// connection
+// It has the signature of the DialContext function of http.Transport
+func (g *Guard) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := g.Resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var d net.Dialer
+	if g.Dialer != nil {
+		d = *g.Dialer
+	}
+	d.Control = g.Control
+
+	for _, ip := range ips {
+		var conn net.Conn
+		conn, err = d.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// Transport is an http.RoundTripper that makes connections
+// only to the destinations its Guard allows
+type Transport struct {
+	guard     *Guard
+	transport *http.Transport
+}
+
+// NewTransport creates a new Transport with a clone of base
+// whose connections are made by g
+// Proxies and the TLS dial functions of base are not used since they
+// would make the connections bypassing g
+// If base is nil, http.DefaultTransport is cloned
+func NewTransport(g *Guard, base *http.Transport) *Transport {
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	transport := base.Clone()
+	transport.Proxy = nil
+	transport.DialContext = g.DialContext
+	transport.DialTLSContext = nil
+	transport.DialTLS = nil
+	return &Transport{guard: g, transport: transport}
+}
+
+// RoundTrip implements the http.RoundTripper interface
+// Requests to blocked address literals fail before any connection is made
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if ip, err := ipx.ParseIP(req.URL.Hostname()); err == nil {
+		if err := t.guard.Check(ip); err != nil {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, err
+		}
+	}
+	return t.transport.RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of the Transport
+func (t *Transport) CloseIdleConnections() {
+	t.transport.CloseIdleConnections()
+}
@@ -0,0 +1,254 @@
+package ssrf
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/hakansa/ipx"
+)
+
+// fakeResolver resolves host names from a map
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, ok := r[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	var ipAddrs []net.IPAddr
+	for _, addr := range addrs {
+		ipAddrs = append(ipAddrs, net.IPAddr{IP: net.ParseIP(addr)})
+	}
+	return ipAddrs, nil
+}
+
+var guardCheckTests = []struct {
+	in      string
+	blocked bool
+}{
+	{"8.8.8.8", false},
+	{"2606:4700::1111", false},
+	{"127.0.0.1", true},
+	{"10.1.2.3", true},
+	{"172.16.0.1", true},
+	{"192.168.1.1", true},
+	{"169.254.169.254", true},
+	{"100.64.0.1", true},
+	{"0.0.0.0", true},
+	{"255.255.255.255", true},
+	{"224.0.0.1", true},
+	{"192.0.0.9", false},
+	{"::1", true},
+	{"::", true},
+	{"fe80::1", true},
+	{"fc00::1", true},
+	{"ff02::1", true},
+	{"2001:db8::1", true},
+	{"::ffff:127.0.0.1", true},
+	{"::ffff:8.8.8.8", false},
+	{"::127.0.0.1", true},
+	{"::8.8.8.8", false},
+	{"64:ff9b::7f00:1", true},
+	{"64:ff9b::a9fe:a9fe", true},
+	{"64:ff9b::808:808", false},
+	{"2002:7f00:1::", true},
+	{"2001:4:112::1", false},
+}
+
+func TestGuardCheck(t *testing.T) {
+	var g Guard
+	for _, tt := range guardCheckTests {
+		err := g.Check(ipx.MustParseIP(tt.in))
+		if (err != nil) != tt.blocked {
+			t.Errorf("Guard.Check(%v) = %v, want blocked %v", tt.in, err, tt.blocked)
+		}
+	}
+}
+
+var guardEmbeddedTests = []struct {
+	in       string
+	embedded string
+}{
+	{"10.0.0.1", ""},
+	{"::ffff:10.0.0.1", ""},
+	{"::a00:1", "10.0.0.1"},
+	{"64:ff9b::10.0.0.1", "10.0.0.1"},
+	{"2001:db8:10a:0:1::", "10.0.0.1"},
+	{"2001:db8:1:a00:1::", "10.0.1.0"},
+	{"2002:a00:1::1", "10.0.0.1"},
+	{"2001:0:4136:e378:8000:63bf:f5ff:fffe", "10.0.0.1"},
+}
+
+func TestGuardCheckEmbedded(t *testing.T) {
+	g := Guard{
+		Deny:  ipx.MustNewIPSet(ipx.MustParseCIDR("10.0.0.0/8")),
+		NAT64: []*ipx.IPNet{ipx.MustParseCIDR("64:ff9b::/96"), ipx.MustParseCIDR("2001:db8:100::/40"), ipx.MustParseCIDR("2001:db8:1::/48")},
+	}
+	for _, tt := range guardEmbeddedTests {
+		err := g.Check(ipx.MustParseIP(tt.in))
+		blocked, ok := err.(*BlockedError)
+		if !ok {
+			t.Errorf("Guard.Check(%v) = %v, want *BlockedError", tt.in, err)
+			continue
+		}
+		if tt.embedded == "" && blocked.Embedded.IP != nil || tt.embedded != "" && !blocked.Embedded.Equal(ipx.MustParseIP(tt.embedded)) {
+			t.Errorf("Guard.Check(%v).Embedded = %v, want %v", tt.in, blocked.Embedded, tt.embedded)
+		}
+	}
+
+	g.Allow = ipx.MustNewIPSet(ipx.MustParseIP("10.0.0.1"))
+	if err := g.Check(ipx.MustParseIP("2002:a00:1::1")); err != nil {
+		t.Errorf("Guard.Check(2002:a00:1::1) = %v, want nil", err)
+	}
+}
+
+var guardResolveTests = []struct {
+	in      string
+	out     []string
+	blocked bool
+}{
+	{"public.test", []string{"8.8.8.8", "2001:4860:4860::8888"}, false},
+	{"internal.test", nil, true},
+	{"mixed.test", nil, true},
+	{"mapped.test", nil, true},
+	{"8.8.4.4", []string{"8.8.4.4"}, false},
+	{"127.0.0.1", nil, true},
+	{"fe80::1%eth0", nil, true},
+	{"0x7f.1", nil, true},
+	{"2130706433", nil, true},
+	{"0x08.8.8.8", nil, true},
+}
+
+func TestGuardResolve(t *testing.T) {
+	g := Guard{Resolver: fakeResolver{
+		"public.test":   {"8.8.8.8", "2001:4860:4860::8888"},
+		"internal.test": {"10.0.0.1"},
+		"mixed.test":    {"8.8.8.8", "127.0.0.1"},
+		"mapped.test":   {"::ffff:169.254.169.254"},
+	}}
+	for _, tt := range guardResolveTests {
+		out, err := g.Resolve(context.Background(), tt.in)
+		var blocked *BlockedError
+		if errors.As(err, &blocked) != tt.blocked {
+			t.Errorf("Guard.Resolve(%q) = %v, want blocked %v", tt.in, err, tt.blocked)
+			continue
+		}
+		if len(out) != len(tt.out) {
+			t.Errorf("Guard.Resolve(%q) = %v, want %v", tt.in, out, tt.out)
+			continue
+		}
+		for i := range out {
+			if !out[i].Equal(ipx.MustParseIP(tt.out[i])) {
+				t.Errorf("Guard.Resolve(%q) = %v, want %v", tt.in, out, tt.out)
+			}
+		}
+	}
+
+	if _, err := g.Resolve(context.Background(), "unknown.test"); err == nil {
+		t.Errorf("Guard.Resolve(unknown.test) = nil, want error")
+	}
+}
+
+func TestGuardControl(t *testing.T) {
+	var g Guard
+	for _, tt := range []struct {
+		in      string
+		blocked bool
+	}{
+		{"8.8.8.8:53", false},
+		{"[2606:4700::1111]:443", false},
+		{"127.0.0.1:80", true},
+		{"[::ffff:10.0.0.1]:80", true},
+		{"[fe80::1%eth0]:80", true},
+	} {
+		err := g.Control("tcp", tt.in, nil)
+		if (err != nil) != tt.blocked {
+			t.Errorf("Guard.Control(%q) = %v, want blocked %v", tt.in, err, tt.blocked)
+		}
+	}
+
+	if err := g.Control("tcp", "localhost:80", nil); err != ErrUnresolvedAddress {
+		t.Errorf("Guard.Control(localhost:80) = %v, want %v", err, ErrUnresolvedAddress)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+
+	resolver := fakeResolver{"loopback.test": {"127.0.0.1"}}
+
+	// loopback addresses are blocked whether they are given
+	// as literals or resolved from host names
+	client := &http.Client{Transport: NewTransport(&Guard{Resolver: resolver}, nil)}
+	for _, host := range []string{u.Host, "loopback.test:" + u.Port(), "0x7f.1:" + u.Port()} {
+		_, err := client.Get("http://" + host + "/")
+		var blocked *BlockedError
+		if !errors.As(err, &blocked) {
+			t.Errorf("Get(%v) = %v, want *BlockedError", host, err)
+		}
+	}
+
+	// allowed addresses are connected to after they are resolved
+	g := &Guard{Resolver: resolver, Allow: ipx.MustNewIPSet(ipx.MustParseCIDR("127.0.0.0/8"))}
+	client = &http.Client{Transport: NewTransport(g, nil)}
+	resp, err := client.Get("http://loopback.test:" + u.Port() + "/")
+	if err != nil {
+		t.Fatalf("Get(loopback.test) = %v, want nil", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" {
+		t.Errorf("Get(loopback.test) body = %q, want %q", body, "ok")
+	}
+
+	// redirects are checked with the same guard
+	redirect := httptest.NewServer(http.RedirectHandler("http://169.254.169.254/latest/meta-data/", http.StatusFound))
+	defer redirect.Close()
+	_, err = client.Get(redirect.URL)
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) || !blocked.IP.Equal(ipx.MustParseIP("169.254.169.254")) {
+		t.Errorf("Get(redirect) = %v, want *BlockedError for 169.254.169.254", err)
+	}
+
+	// the dial functions of the base transport are not used
+	base := &http.Transport{DialTLS: func(network, addr string) (net.Conn, error) { return net.Dial(network, addr) }}
+	if tr := NewTransport(g, base); tr.transport.DialTLS != nil || tr.transport.DialTLSContext != nil {
+		t.Errorf("NewTransport() keeps the TLS dial functions of base")
+	}
+}
+
+func TestNonPublic(t *testing.T) {
+	for _, entry := range ipx.SpecialPurposeRegistry() {
+		ip := entry.Prefix.IP
+		if got, want := NonPublic().Contains(ip), !ip.IsGloballyReachable(); got != want {
+			t.Errorf("NonPublic().Contains(%v) = %v, want %v", ip, got, want)
+		}
+	}
+}
+
+func TestBlockedErrorString(t *testing.T) {
+	for _, tt := range []struct {
+		in  *BlockedError
+		out string
+	}{
+		{nil, "<nil>"},
+		{&BlockedError{IP: ipx.MustParseIP("10.0.0.1")}, "address 10.0.0.1 is blocked"},
+		{&BlockedError{Host: "internal.test", IP: ipx.MustParseIP("10.0.0.1")}, "host internal.test: address 10.0.0.1 is blocked"},
+		{&BlockedError{IP: ipx.MustParseIP("2002:a00:1::"), Embedded: ipx.MustParseIP("10.0.0.1")}, "address 2002:a00:1:: embedding 10.0.0.1 is blocked"},
+		{&BlockedError{Host: "0x7f.1", IP: ipx.MustParseIP("127.0.0.1"), Form: ipx.FormShorthand | ipx.FormHex}, "host 0x7f.1: ambiguous address in shorthand+hex form is blocked"},
+	} {
+		if out := tt.in.Error(); out != tt.out {
+			t.Errorf("BlockedError.Error() = %q, want %q", out, tt.out)
+		}
+	}
+}
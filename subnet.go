@@ -0,0 +1,192 @@
+package ipx
+
+import (
+	"errors"
+	"math/bits"
+	"sort"
+	"strconv"
+)
+
+// Error definitions
+var (
+	ErrInvalidPrefixLen = errors.New("invalid prefix length")
+)
+
+// SubnetIterator iterates over the subnets of an IPNet in ascending order
+//
+//	it, _ := ipNet.Subnets(26)
+//	for it.Next() {
+//		subnet := it.IPNet()
+//	}
+type SubnetIterator struct {
+	next Uint128 // network number of the next subnet
+	last Uint128 // network number of the last subnet
+	step Uint128 // number of addresses in a subnet
+	ones int
+	v4   bool
+	done bool
+	cur  *IPNet
+}
+
+// Next advances the iterator to the next subnet
+// It returns false when there are no more subnets
+func (it *SubnetIterator) Next() bool {
+	if it.done {
+		it.cur = nil
+		return false
+	}
+
+	it.cur = newIPNet(it.next, it.ones, it.v4)
+	if it.next == it.last {
+		it.done = true
+	} else {
+		it.next, _ = it.next.Add(it.step)
+	}
+	return true
+}
+
+// IPNet returns the current subnet
+func (it *SubnetIterator) IPNet() *IPNet {
+	return it.cur
+}
+
+// Subnets returns an iterator over the subnets of n
+// whose prefix length is prefixLen
+// It returns ErrInvalidPrefixLen if prefixLen is shorter than the
+// prefix length of n or longer than the address length
+func (n *IPNet) Subnets(prefixLen int) (*SubnetIterator, error) {
+	ones := n.NetworkSize()
+	network, host, v4 := n.uint128()
+	if ones == -1 || prefixLen < ones || prefixLen > addressBits(v4) {
+		return nil, ErrInvalidPrefixLen
+	}
+
+	hostBits := uint(addressBits(v4) - prefixLen)
+	return &SubnetIterator{
+		next: network,
+		last: network.Or(host).And(uint128Max.Lsh(hostBits)),
+		step: uint128One.Lsh(hostBits),
+		ones: prefixLen,
+		v4:   v4,
+	}, nil
+}
+
+// Supernet returns the network whose prefix length is prefixLen
+// and contains n
+// It returns ErrInvalidPrefixLen if prefixLen is negative
+// or longer than the prefix length of n
+func (n *IPNet) Supernet(prefixLen int) (*IPNet, error) {
+	ones := n.NetworkSize()
+	network, _, v4 := n.uint128()
+	if ones == -1 || prefixLen < 0 || prefixLen > ones {
+		return nil, ErrInvalidPrefixLen
+	}
+
+	hostBits := uint(addressBits(v4) - prefixLen)
+	return newIPNet(network.And(uint128Max.Lsh(hostBits)), prefixLen, v4), nil
+}
+
+// VLSMRequirement represents a named subnet requirement for PlanVLSM
+type VLSMRequirement struct {
+	Name  string
+	Hosts int // number of usable ip addresses required
+}
+
+// VLSMAllocation represents a subnet allocated by PlanVLSM
+type VLSMAllocation struct {
+	Name  string
+	Hosts int    // number of usable ip addresses required
+	IPNet *IPNet // allocated subnet
+}
+
+// VLSMError declares a requirement that can not be allocated by PlanVLSM
+type VLSMError struct {
+	Err         string
+	Requirement VLSMRequirement
+	Parent      *IPNet
+}
+
+// Error throws the error
+func (e *VLSMError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	return "requirement " + strconv.Quote(e.Requirement.Name) + " for " +
+		strconv.Itoa(e.Requirement.Hosts) + " hosts in " + e.Parent.String() + ": " + e.Err
+}
+
+// PlanVLSM allocates a subnet in parent for each requirement
+// Subnets are the smallest networks whose UsableIPNumber is at least
+// the required number of hosts, and they are allocated largest-first
+// so every subnet is aligned to its size
+// It returns the allocations in address order, which is largest-first,
+// or a *VLSMError describing the first requirement that did not fit
+func PlanVLSM(parent *IPNet, reqs []VLSMRequirement) ([]VLSMAllocation, error) {
+	ones := parent.NetworkSize()
+	network, host, v4 := parent.uint128()
+	if ones == -1 {
+		return nil, ErrInvalidPrefixLen
+	}
+
+	hostBits := make([]int, len(reqs))
+	order := make([]int, len(reqs))
+	for i, req := range reqs {
+		if req.Hosts <= 0 {
+			return nil, &VLSMError{Err: "invalid number of hosts", Requirement: req, Parent: parent}
+		}
+		hostBits[i] = vlsmHostBits(req.Hosts, v4)
+		order[i] = i
+	}
+
+	// largest-first, keeping the input order for equal sizes
+	sort.SliceStable(order, func(i, j int) bool {
+		return hostBits[order[i]] > hostBits[order[j]]
+	})
+
+	last := network.Or(host)
+	next := network
+	full := false
+	allocs := make([]VLSMAllocation, 0, len(reqs))
+	for _, i := range order {
+		req := reqs[i]
+		if full || hostBits[i] > addressBits(v4)-ones {
+			return nil, &VLSMError{Err: "does not fit", Requirement: req, Parent: parent}
+		}
+
+		end, _ := next.Add(uint128Max.Rsh(uint(128 - hostBits[i])))
+		if end.Cmp(last) > 0 || end.Cmp(next) < 0 {
+			return nil, &VLSMError{Err: "does not fit", Requirement: req, Parent: parent}
+		}
+
+		allocs = append(allocs, VLSMAllocation{
+			Name:  req.Name,
+			Hosts: req.Hosts,
+			IPNet: newIPNet(next, addressBits(v4)-hostBits[i], v4),
+		})
+
+		if end == last {
+			full = true
+		} else {
+			next, _ = end.Add(uint128One)
+		}
+	}
+
+	return allocs, nil
+}
+
+// vlsmHostBits returns the number of host bits of the smallest network
+// which has at least hosts usable ip addresses
+func vlsmHostBits(hosts int, v4 bool) int {
+	// point-to-point networks have no reserved addresses
+	if hosts <= 2 {
+		return hosts - 1
+	}
+
+	// network and broadcast addresses for ipv4,
+	// Subnet-Router anycast address for ipv6
+	reserved := uint64(1)
+	if v4 {
+		reserved = 2
+	}
+	return bits.Len64(uint64(hosts) + reserved - 1)
+}
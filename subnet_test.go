@@ -0,0 +1,164 @@
+package ipx
+
+import (
+	"reflect"
+	"testing"
+)
+
+var subnetsTests = []struct {
+	in        *IPNet
+	prefixLen int
+	out       []string
+	err       error
+}{
+	{
+		MustParseCIDR("192.168.0.0/24"),
+		26,
+		[]string{"192.168.0.0/26", "192.168.0.64/26", "192.168.0.128/26", "192.168.0.192/26"},
+		nil,
+	},
+	{
+		MustParseCIDR("192.168.0.0/24"),
+		24,
+		[]string{"192.168.0.0/24"},
+		nil,
+	},
+	{
+		MustParseCIDR("255.255.255.252/30"),
+		32,
+		[]string{"255.255.255.252/32", "255.255.255.253/32", "255.255.255.254/32", "255.255.255.255/32"},
+		nil,
+	},
+	{
+		MustParseCIDR("2001:db8::/46"),
+		48,
+		[]string{"2001:db8::/48", "2001:db8:1::/48", "2001:db8:2::/48", "2001:db8:3::/48"},
+		nil,
+	},
+	{
+		MustParseCIDR("::/0"),
+		1,
+		[]string{"::/1", "8000::/1"},
+		nil,
+	},
+	{
+		MustParseCIDR("192.168.0.0/24"),
+		23,
+		nil,
+		ErrInvalidPrefixLen,
+	},
+	{
+		MustParseCIDR("192.168.0.0/24"),
+		33,
+		nil,
+		ErrInvalidPrefixLen,
+	},
+}
+
+func TestSubnets(t *testing.T) {
+	for _, tt := range subnetsTests {
+		it, err := tt.in.Subnets(tt.prefixLen)
+		if err != tt.err {
+			t.Errorf("IPNet.Subnets(%v)(%v) = %v, want %v", tt.in, tt.prefixLen, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		var out []string
+		for it.Next() {
+			out = append(out, it.IPNet().String())
+		}
+		if !reflect.DeepEqual(out, tt.out) {
+			t.Errorf("IPNet.Subnets(%v)(%v) = %v, want %v", tt.in, tt.prefixLen, out, tt.out)
+		}
+	}
+}
+
+var supernetTests = []struct {
+	in        *IPNet
+	prefixLen int
+	out       string
+	err       error
+}{
+	{MustParseCIDR("192.168.1.64/26"), 16, "192.168.0.0/16", nil},
+	{MustParseCIDR("192.168.1.64/26"), 0, "0.0.0.0/0", nil},
+	{MustParseCIDR("2001:db8:1234::/48"), 32, "2001:db8::/32", nil},
+	{MustParseCIDR("192.168.1.64/26"), 27, "", ErrInvalidPrefixLen},
+	{MustParseCIDR("192.168.1.64/26"), -1, "", ErrInvalidPrefixLen},
+}
+
+func TestSupernet(t *testing.T) {
+	for _, tt := range supernetTests {
+		out, err := tt.in.Supernet(tt.prefixLen)
+		if err != tt.err || (err == nil && out.String() != tt.out) {
+			t.Errorf("IPNet.Supernet(%v)(%v) = %v, %v, want %v, %v", tt.in, tt.prefixLen, out, err, tt.out, tt.err)
+		}
+	}
+}
+
+var planVLSMTests = []struct {
+	parent *IPNet
+	reqs   []VLSMRequirement
+	out    []string
+	failed string
+}{
+	{
+		MustParseCIDR("192.168.0.0/24"),
+		[]VLSMRequirement{{"lab", 10}, {"office", 100}, {"link", 2}, {"dmz", 20}},
+		[]string{"office=192.168.0.0/25", "dmz=192.168.0.128/27", "lab=192.168.0.160/28", "link=192.168.0.176/31"},
+		"",
+	},
+	{
+		MustParseCIDR("192.168.0.0/24"),
+		[]VLSMRequirement{{"a", 126}, {"b", 126}},
+		[]string{"a=192.168.0.0/25", "b=192.168.0.128/25"},
+		"",
+	},
+	{
+		MustParseCIDR("192.168.0.0/24"),
+		[]VLSMRequirement{{"a", 126}, {"b", 126}, {"c", 1}},
+		nil,
+		"c",
+	},
+	{
+		MustParseCIDR("192.168.0.0/24"),
+		[]VLSMRequirement{{"a", 254}, {"b", 255}},
+		nil,
+		"b",
+	},
+	{
+		MustParseCIDR("2001:db8::/120"),
+		[]VLSMRequirement{{"a", 127}, {"b", 2}, {"c", 63}},
+		[]string{"a=2001:db8::/121", "c=2001:db8::80/122", "b=2001:db8::c0/127"},
+		"",
+	},
+	{
+		MustParseCIDR("2001:db8::/120"),
+		[]VLSMRequirement{{"a", 127}, {"b", 2}, {"c", 64}},
+		nil,
+		"b",
+	},
+}
+
+func TestPlanVLSM(t *testing.T) {
+	for _, tt := range planVLSMTests {
+		allocs, err := PlanVLSM(tt.parent, tt.reqs)
+		if tt.out == nil {
+			vlsmErr, ok := err.(*VLSMError)
+			if !ok || vlsmErr.Requirement.Name != tt.failed {
+				t.Errorf("PlanVLSM(%v, %v) = %v, %v, want error for %q", tt.parent, tt.reqs, allocs, err, tt.failed)
+			}
+			continue
+		}
+
+		var out []string
+		for _, alloc := range allocs {
+			out = append(out, alloc.Name+"="+alloc.IPNet.String())
+		}
+		if err != nil || !reflect.DeepEqual(out, tt.out) {
+			t.Errorf("PlanVLSM(%v, %v) = %v, %v, want %v", tt.parent, tt.reqs, out, err, tt.out)
+		}
+	}
+}
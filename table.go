@@ -0,0 +1,311 @@
+//go:build go1.21
+// +build go1.21
+
+package ipx
+
+// Table is a longest-prefix-match routing table keyed by IPNet
+// It is backed by a path-compressed binary trie per address family,
+// so lookups take at most one step per prefix length
+// The zero value is an empty table ready to use
+// Table is not safe for concurrent use
+type Table[V any] struct {
+	root4 *tableNode[V]
+	root6 *tableNode[V]
+	size  int
+}
+
+// TableEntry is a prefix and its value in a Table
+type TableEntry[V any] struct {
+	IPNet *IPNet
+	Value V
+}
+
+// tableNode is a node of the trie
+// key holds the prefix left-aligned in 128 bits, so IPv4 prefixes
+// use the most significant 32 bits
+// entry is nil for the intermediate nodes that have no value
+type tableNode[V any] struct {
+	key   Uint128
+	bits  int
+	child [2]*tableNode[V]
+	entry *TableEntry[V]
+}
+
+// Len returns the number of prefixes in the table
+func (t *Table[V]) Len() int {
+	return t.size
+}
+
+// Insert adds n to the table with value v
+// If n is already in the table, its value is replaced
+// It returns ErrInvalidPrefixLen if n has a non-canonical mask
+func (t *Table[V]) Insert(n *IPNet, v V) error {
+	key, ones, v4, ok := tableKey(n)
+	if !ok {
+		return ErrInvalidPrefixLen
+	}
+
+	entry := &TableEntry[V]{IPNet: newIPNet(fromTableKey(key, v4), ones, v4), Value: v}
+	root := t.root(v4)
+	var added bool
+	*root, added = (*root).insert(key, ones, entry)
+	if added {
+		t.size++
+	}
+	return nil
+}
+
+// Delete removes n from the table
+// It returns whether n was in the table
+func (t *Table[V]) Delete(n *IPNet) bool {
+	key, ones, v4, ok := tableKey(n)
+	if !ok {
+		return false
+	}
+
+	root := t.root(v4)
+	var deleted bool
+	*root, deleted = (*root).delete(key, ones)
+	if deleted {
+		t.size--
+	}
+	return deleted
+}
+
+// Get returns the value of n in the table
+// It returns false if n is not in the table
+func (t *Table[V]) Get(n *IPNet) (V, bool) {
+	var zero V
+
+	key, ones, v4, ok := tableKey(n)
+	if !ok {
+		return zero, false
+	}
+
+	for node := *t.root(v4); node != nil && node.bits <= ones && node.matches(key); node = node.child[bitAt(key, node.bits)] {
+		if node.bits == ones {
+			if node.entry == nil {
+				return zero, false
+			}
+			return node.entry.Value, true
+		}
+	}
+	return zero, false
+}
+
+// LookupIP returns the longest prefix in the table that contains ip
+// and its value
+// It returns false if no prefix contains ip
+func (t *Table[V]) LookupIP(ip IP) (*IPNet, V, bool) {
+	var zero V
+	if ip.IP == nil {
+		return nil, zero, false
+	}
+
+	val, v4 := ip.toUint128()
+	key, width := toTableKey(val, v4)
+
+	var match *TableEntry[V]
+	for node := *t.root(v4); node != nil && node.matches(key); {
+		if node.entry != nil {
+			match = node.entry
+		}
+		if node.bits == width {
+			break
+		}
+		node = node.child[bitAt(key, node.bits)]
+	}
+
+	if match == nil {
+		return nil, zero, false
+	}
+	return match.IPNet, match.Value, true
+}
+
+// Covering returns the prefixes in the table that contain n,
+// including n itself, from the shortest to the longest
+func (t *Table[V]) Covering(n *IPNet) []TableEntry[V] {
+	key, ones, v4, ok := tableKey(n)
+	if !ok {
+		return nil
+	}
+
+	var entries []TableEntry[V]
+	for node := *t.root(v4); node != nil && node.bits <= ones && node.matches(key); {
+		if node.entry != nil {
+			entries = append(entries, *node.entry)
+		}
+		if node.bits == ones {
+			break
+		}
+		node = node.child[bitAt(key, node.bits)]
+	}
+	return entries
+}
+
+// Covered returns the prefixes in the table that are contained in n,
+// including n itself, in ascending order
+func (t *Table[V]) Covered(n *IPNet) []TableEntry[V] {
+	key, ones, v4, ok := tableKey(n)
+	if !ok {
+		return nil
+	}
+
+	// find the first node in the subtree of n
+	node := *t.root(v4)
+	for node != nil && node.bits < ones {
+		if !node.matches(key) {
+			return nil
+		}
+		node = node.child[bitAt(key, node.bits)]
+	}
+	if node == nil || !prefixMatches(node.key, key, ones) {
+		return nil
+	}
+
+	var entries []TableEntry[V]
+	node.walk(func(e *TableEntry[V]) bool {
+		entries = append(entries, *e)
+		return true
+	})
+	return entries
+}
+
+// Walk calls fn for each prefix in the table in ascending order,
+// IPv4 prefixes first
+// Walk stops if fn returns false
+func (t *Table[V]) Walk(fn func(n *IPNet, v V) bool) {
+	visit := func(e *TableEntry[V]) bool {
+		return fn(e.IPNet, e.Value)
+	}
+	if t.root4.walk(visit) {
+		t.root6.walk(visit)
+	}
+}
+
+// root returns the root of given address family
+func (t *Table[V]) root(v4 bool) **tableNode[V] {
+	if v4 {
+		return &t.root4
+	}
+	return &t.root6
+}
+
+// matches reports whether key is in the prefix of the node
+func (node *tableNode[V]) matches(key Uint128) bool {
+	return prefixMatches(node.key, key, node.bits)
+}
+
+// insert adds entry with given key and prefix length to the subtree
+// and returns the new root of the subtree and whether a new prefix is added
+func (node *tableNode[V]) insert(key Uint128, bits int, entry *TableEntry[V]) (*tableNode[V], bool) {
+	if node == nil {
+		return &tableNode[V]{key: key, bits: bits, entry: entry}, true
+	}
+
+	common := commonPrefixLen(node.key, key)
+	if common > node.bits {
+		common = node.bits
+	}
+	if common > bits {
+		common = bits
+	}
+
+	switch {
+	case common == node.bits && common == bits:
+		// exact match
+		added := node.entry == nil
+		node.entry = entry
+		return node, added
+	case common == node.bits:
+		// key is in the subtree of the node
+		b := bitAt(key, node.bits)
+		var added bool
+		node.child[b], added = node.child[b].insert(key, bits, entry)
+		return node, added
+	case common == bits:
+		// the node is in the subtree of key
+		parent := &tableNode[V]{key: key, bits: bits, entry: entry}
+		parent.child[bitAt(node.key, bits)] = node
+		return parent, true
+	}
+
+	// key and the node diverge at common
+	parent := &tableNode[V]{key: key.And(prefixMask(common)), bits: common}
+	parent.child[bitAt(node.key, common)] = node
+	parent.child[bitAt(key, common)] = &tableNode[V]{key: key, bits: bits, entry: entry}
+	return parent, true
+}
+
+// delete removes the prefix with given key and prefix length from the subtree
+// and returns the new root of the subtree and whether the prefix is removed
+func (node *tableNode[V]) delete(key Uint128, bits int) (*tableNode[V], bool) {
+	if node == nil || node.bits > bits || !node.matches(key) {
+		return node, false
+	}
+
+	deleted := false
+	if node.bits == bits {
+		if node.entry == nil {
+			return node, false
+		}
+		node.entry = nil
+		deleted = true
+	} else {
+		b := bitAt(key, node.bits)
+		node.child[b], deleted = node.child[b].delete(key, bits)
+	}
+
+	// remove intermediate nodes which are not needed anymore
+	if node.entry == nil {
+		switch {
+		case node.child[0] == nil:
+			return node.child[1], deleted
+		case node.child[1] == nil:
+			return node.child[0], deleted
+		}
+	}
+	return node, deleted
+}
+
+// walk calls fn for each entry in the subtree in ascending order
+// It returns false if fn returns false
+func (node *tableNode[V]) walk(fn func(e *TableEntry[V]) bool) bool {
+	if node == nil {
+		return true
+	}
+	if node.entry != nil && !fn(node.entry) {
+		return false
+	}
+	return node.child[0].walk(fn) && node.child[1].walk(fn)
+}
+
+// tableKey returns the left-aligned key and the prefix length of n
+// ok is false if n has a non-canonical mask
+func tableKey(n *IPNet) (key Uint128, ones int, v4 bool, ok bool) {
+	if n == nil {
+		return
+	}
+	ones = n.NetworkSize()
+	if ones == -1 {
+		return
+	}
+	network, _, v4 := n.uint128()
+	key, width := toTableKey(network, v4)
+	if ones > width {
+		return
+	}
+	return key, ones, v4, true
+}
+
+// toTableKey left-aligns the address val in 128 bits
+// and returns it with the address length in bits
+func toTableKey(val Uint128, v4 bool) (Uint128, int) {
+	width := addressBits(v4)
+	return val.Lsh(uint(128 - width)), width
+}
+
+// fromTableKey returns the address of the left-aligned key
+func fromTableKey(key Uint128, v4 bool) Uint128 {
+	return key.Rsh(uint(128 - addressBits(v4)))
+}
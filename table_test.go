@@ -0,0 +1,241 @@
+//go:build go1.21
+// +build go1.21
+
+package ipx
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func tableEntryStrings(entries []TableEntry[string]) []string {
+	var out []string
+	for _, e := range entries {
+		out = append(out, e.IPNet.String()+"="+e.Value)
+	}
+	return out
+}
+
+func newTestTable() *Table[string] {
+	t := &Table[string]{}
+	for _, cidr := range []string{
+		"0.0.0.0/0",
+		"10.0.0.0/8",
+		"10.1.0.0/16",
+		"10.1.2.0/24",
+		"10.1.2.128/25",
+		"192.168.0.0/16",
+		"2001:db8::/32",
+		"2001:db8:1::/48",
+		"::/0",
+	} {
+		t.Insert(MustParseCIDR(cidr), cidr)
+	}
+	return t
+}
+
+var tableLookupIPTests = []struct {
+	ip  IP
+	out string
+}{
+	{IPv4(10, 1, 2, 200), "10.1.2.128/25"},
+	{IPv4(10, 1, 2, 3), "10.1.2.0/24"},
+	{IPv4(10, 1, 3, 3), "10.1.0.0/16"},
+	{IPv4(10, 2, 0, 1), "10.0.0.0/8"},
+	{IPv4(192, 168, 255, 255), "192.168.0.0/16"},
+	{IPv4(8, 8, 8, 8), "0.0.0.0/0"},
+	{MustParseIP("2001:db8:1::1"), "2001:db8:1::/48"},
+	{MustParseIP("2001:db8:2::1"), "2001:db8::/32"},
+	{MustParseIP("2001:db9::1"), "::/0"},
+}
+
+func TestTableLookupIP(t *testing.T) {
+	table := newTestTable()
+	for _, tt := range tableLookupIPTests {
+		n, v, ok := table.LookupIP(tt.ip)
+		if !ok || n.String() != tt.out || v != tt.out {
+			t.Errorf("Table.LookupIP(%v) = %v, %v, %v, want %v", tt.ip, n, v, ok, tt.out)
+		}
+	}
+
+	table.Delete(MustParseCIDR("0.0.0.0/0"))
+	if n, _, ok := table.LookupIP(IPv4(8, 8, 8, 8)); ok {
+		t.Errorf("Table.LookupIP(8.8.8.8) = %v, want no match", n)
+	}
+}
+
+func TestTableGetDelete(t *testing.T) {
+	table := newTestTable()
+	if table.Len() != 9 {
+		t.Errorf("Table.Len() = %v, want 9", table.Len())
+	}
+
+	// 10.1.2.0/23 is only an intermediate node
+	if v, ok := table.Get(MustParseCIDR("10.1.2.0/23")); ok {
+		t.Errorf("Table.Get(10.1.2.0/23) = %v, want no match", v)
+	}
+	if table.Delete(MustParseCIDR("10.1.2.0/23")) {
+		t.Errorf("Table.Delete(10.1.2.0/23) = true, want false")
+	}
+
+	if !table.Delete(MustParseCIDR("10.1.2.0/24")) {
+		t.Errorf("Table.Delete(10.1.2.0/24) = false, want true")
+	}
+	if _, ok := table.Get(MustParseCIDR("10.1.2.0/24")); ok {
+		t.Errorf("Table.Get(10.1.2.0/24) found a deleted prefix")
+	}
+	if v, ok := table.Get(MustParseCIDR("10.1.2.128/25")); !ok || v != "10.1.2.128/25" {
+		t.Errorf("Table.Get(10.1.2.128/25) = %v, %v, want 10.1.2.128/25", v, ok)
+	}
+	if table.Len() != 8 {
+		t.Errorf("Table.Len() = %v, want 8", table.Len())
+	}
+
+	// replacing a value does not change the length
+	table.Insert(MustParseCIDR("10.1.2.128/25"), "replaced")
+	if v, _ := table.Get(MustParseCIDR("10.1.2.128/25")); v != "replaced" || table.Len() != 8 {
+		t.Errorf("Table.Get(10.1.2.128/25) = %v with length %v, want replaced with length 8", v, table.Len())
+	}
+}
+
+var tableCoveringTests = []struct {
+	in       *IPNet
+	covering []string
+	covered  []string
+}{
+	{
+		MustParseCIDR("10.1.0.0/16"),
+		[]string{"0.0.0.0/0=0.0.0.0/0", "10.0.0.0/8=10.0.0.0/8", "10.1.0.0/16=10.1.0.0/16"},
+		[]string{"10.1.0.0/16=10.1.0.0/16", "10.1.2.0/24=10.1.2.0/24", "10.1.2.128/25=10.1.2.128/25"},
+	},
+	{
+		MustParseCIDR("10.1.2.0/23"),
+		[]string{"0.0.0.0/0=0.0.0.0/0", "10.0.0.0/8=10.0.0.0/8", "10.1.0.0/16=10.1.0.0/16"},
+		[]string{"10.1.2.0/24=10.1.2.0/24", "10.1.2.128/25=10.1.2.128/25"},
+	},
+	{
+		MustParseCIDR("172.16.0.0/12"),
+		[]string{"0.0.0.0/0=0.0.0.0/0"},
+		nil,
+	},
+	{
+		MustParseCIDR("2001:db8::/31"),
+		[]string{"::/0=::/0"},
+		[]string{"2001:db8::/32=2001:db8::/32", "2001:db8:1::/48=2001:db8:1::/48"},
+	},
+}
+
+func TestTableCovering(t *testing.T) {
+	table := newTestTable()
+	for _, tt := range tableCoveringTests {
+		if out := tableEntryStrings(table.Covering(tt.in)); !reflect.DeepEqual(out, tt.covering) {
+			t.Errorf("Table.Covering(%v) = %v, want %v", tt.in, out, tt.covering)
+		}
+		if out := tableEntryStrings(table.Covered(tt.in)); !reflect.DeepEqual(out, tt.covered) {
+			t.Errorf("Table.Covered(%v) = %v, want %v", tt.in, out, tt.covered)
+		}
+	}
+}
+
+func TestTableRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	table := &Table[int]{}
+	var nets []*IPNet
+	for i := 0; i < 2000; i++ {
+		n := newIPNet(NewUint128(uint64(r.Uint32())), r.Intn(33), true)
+		n = &IPNet{IP: n.IP.Mask(n.Mask), Mask: n.Mask}
+		nets = append(nets, n)
+		table.Insert(n, i)
+	}
+
+	// delete every third network
+	live := map[string]bool{}
+	for _, n := range nets {
+		live[n.String()] = true
+	}
+	for i, n := range nets {
+		if i%3 == 0 {
+			table.Delete(n)
+			live[n.String()] = false
+		}
+	}
+	if table.Len() != len(live)-countFalse(live) {
+		t.Errorf("Table.Len() = %v, want %v", table.Len(), len(live)-countFalse(live))
+	}
+
+	for i := 0; i < 2000; i++ {
+		ip := FromInt(r.Uint32())
+		want := -1
+		for _, n := range nets {
+			if live[n.String()] && n.Contains(ip) && n.NetworkSize() > want {
+				want = n.NetworkSize()
+			}
+		}
+
+		n, _, ok := table.LookupIP(ip)
+		if !ok && want != -1 || ok && n.NetworkSize() != want {
+			t.Fatalf("Table.LookupIP(%v) = %v, %v, want prefix length %v", ip, n, ok, want)
+		}
+	}
+}
+
+func countFalse(m map[string]bool) int {
+	n := 0
+	for _, v := range m {
+		if !v {
+			n++
+		}
+	}
+	return n
+}
+
+// newBenchmarkTable returns a table with a full BGP table sized load,
+// roughly one million IPv4 prefixes and 200 thousand IPv6 prefixes
+func newBenchmarkTable() (*Table[int], []IP) {
+	r := rand.New(rand.NewSource(1))
+	table := &Table[int]{}
+	for i := 0; i < 1000000; i++ {
+		ones := 8 + r.Intn(17)
+		table.Insert(newIPNet(NewUint128(uint64(r.Uint32())).And(prefixMask(ones).Rsh(96)), ones, true), i)
+	}
+	for i := 0; i < 200000; i++ {
+		ones := 16 + r.Intn(33)
+		table.Insert(newIPNet(Uint128{r.Uint64(), 0}.And(prefixMask(ones)), ones, false), i)
+	}
+
+	ips := make([]IP, 1024)
+	for i := range ips {
+		if i%2 == 0 {
+			ips[i] = FromInt(r.Uint32())
+		} else {
+			ips[i] = fromUint128(Uint128{r.Uint64(), r.Uint64()}, false)
+		}
+	}
+	return table, ips
+}
+
+func BenchmarkTableLookupIP(b *testing.B) {
+	table, ips := newBenchmarkTable()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		table.LookupIP(ips[i%len(ips)])
+	}
+}
+
+func BenchmarkTableInsert(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	nets := make([]*IPNet, 1024)
+	for i := range nets {
+		nets[i] = newIPNet(NewUint128(uint64(r.Uint32())).And(prefixMask(24).Rsh(96)), 24, true)
+	}
+
+	table := &Table[int]{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		table.Insert(nets[i%len(nets)], i)
+	}
+}
@@ -0,0 +1,194 @@
+package ipx
+
+import (
+	"encoding/binary"
+	"math/big"
+	"math/bits"
+	"math/rand"
+)
+
+// Uint128 represents an unsigned 128-bit integer.
+// It is used to do arithmetic on IPv6 addresses without allocation.
+type Uint128 struct {
+	Hi uint64 // most significant 64 bits
+	Lo uint64 // least significant 64 bits
+}
+
+// Well-known Uint128 values
+var (
+	uint128Zero = Uint128{}
+	uint128One  = Uint128{0, 1}
+	uint128Max  = Uint128{^uint64(0), ^uint64(0)}
+)
+
+// NewUint128 returns the Uint128 holding the 64-bit value n
+func NewUint128(n uint64) Uint128 {
+	return Uint128{0, n}
+}
+
+// Uint128FromBigInt returns the Uint128 holding n modulo 2^128.
+// Negative values are wrapped in two's complement form.
+func Uint128FromBigInt(n *big.Int) Uint128 {
+	m := new(big.Int).Mod(n, new(big.Int).Lsh(big.NewInt(1), 128))
+
+	var buf [16]byte
+	m.FillBytes(buf[:])
+	return uint128FromBytes(buf[:])
+}
+
+// uint128FromBytes returns the Uint128 holding the big-endian 16-byte slice b
+func uint128FromBytes(b []byte) Uint128 {
+	return Uint128{binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])}
+}
+
+// IsZero reports whether u is zero
+func (u Uint128) IsZero() bool {
+	return u.Hi == 0 && u.Lo == 0
+}
+
+// Cmp compares u and x and returns -1, 0 or +1
+func (u Uint128) Cmp(x Uint128) int {
+	switch {
+	case u.Hi < x.Hi:
+		return -1
+	case u.Hi > x.Hi:
+		return 1
+	case u.Lo < x.Lo:
+		return -1
+	case u.Lo > x.Lo:
+		return 1
+	}
+	return 0
+}
+
+// Add returns u+x and whether the addition overflowed 128 bits
+func (u Uint128) Add(x Uint128) (Uint128, bool) {
+	lo, carry := bits.Add64(u.Lo, x.Lo, 0)
+	hi, carry := bits.Add64(u.Hi, x.Hi, carry)
+	return Uint128{hi, lo}, carry != 0
+}
+
+// Sub returns u-x and whether the subtraction underflowed
+func (u Uint128) Sub(x Uint128) (Uint128, bool) {
+	lo, borrow := bits.Sub64(u.Lo, x.Lo, 0)
+	hi, borrow := bits.Sub64(u.Hi, x.Hi, borrow)
+	return Uint128{hi, lo}, borrow != 0
+}
+
+// And returns u&x
+func (u Uint128) And(x Uint128) Uint128 {
+	return Uint128{u.Hi & x.Hi, u.Lo & x.Lo}
+}
+
+// Or returns u|x
+func (u Uint128) Or(x Uint128) Uint128 {
+	return Uint128{u.Hi | x.Hi, u.Lo | x.Lo}
+}
+
+// Not returns ^u
+func (u Uint128) Not() Uint128 {
+	return Uint128{^u.Hi, ^u.Lo}
+}
+
+// Lsh returns u<<n
+func (u Uint128) Lsh(n uint) Uint128 {
+	switch {
+	case n >= 128:
+		return uint128Zero
+	case n >= 64:
+		return Uint128{u.Lo << (n - 64), 0}
+	case n == 0:
+		return u
+	}
+	return Uint128{u.Hi<<n | u.Lo>>(64-n), u.Lo << n}
+}
+
+// Rsh returns u>>n
+func (u Uint128) Rsh(n uint) Uint128 {
+	switch {
+	case n >= 128:
+		return uint128Zero
+	case n >= 64:
+		return Uint128{0, u.Hi >> (n - 64)}
+	case n == 0:
+		return u
+	}
+	return Uint128{u.Hi >> n, u.Lo>>n | u.Hi<<(64-n)}
+}
+
+// BitLen returns the number of bits required to represent u
+func (u Uint128) BitLen() int {
+	if u.Hi != 0 {
+		return 64 + bits.Len64(u.Hi)
+	}
+	return bits.Len64(u.Lo)
+}
+
+// TrailingZeros returns the number of trailing zero bits in u
+// The result is 128 for u == 0
+func (u Uint128) TrailingZeros() int {
+	if u.Lo != 0 {
+		return bits.TrailingZeros64(u.Lo)
+	}
+	return 64 + bits.TrailingZeros64(u.Hi)
+}
+
+// BigInt returns the big.Int representation of u
+func (u Uint128) BigInt() *big.Int {
+	var buf [16]byte
+	u.putBytes(buf[:])
+	return new(big.Int).SetBytes(buf[:])
+}
+
+// String returns the decimal representation of u
+func (u Uint128) String() string {
+	if u.Hi == 0 {
+		return big.NewInt(0).SetUint64(u.Lo).String()
+	}
+	return u.BigInt().String()
+}
+
+// putBytes writes u into the big-endian 16-byte slice b
+func (u Uint128) putBytes(b []byte) {
+	binary.BigEndian.PutUint64(b[:8], u.Hi)
+	binary.BigEndian.PutUint64(b[8:], u.Lo)
+}
+
+// randomUint128 returns a uniformly distributed random number in [0, max]
+func randomUint128(max Uint128) Uint128 {
+	mask := uint128Max.Rsh(uint(128 - max.BitLen()))
+	for {
+		n := Uint128{rand.Uint64(), rand.Uint64()}.And(mask)
+		if n.Cmp(max) <= 0 {
+			return n
+		}
+	}
+}
+
+// bitAt returns the i'th most significant bit of u
+func bitAt(u Uint128, i int) int {
+	if i < 64 {
+		return int(u.Hi>>(63-uint(i))) & 1
+	}
+	return int(u.Lo>>(127-uint(i))) & 1
+}
+
+// prefixMask returns the Uint128 whose ones most significant bits are set
+func prefixMask(ones int) Uint128 {
+	return uint128Max.Lsh(uint(128 - ones))
+}
+
+// prefixMatches reports whether the ones most significant bits
+// of x and y are equal
+func prefixMatches(x, y Uint128, ones int) bool {
+	mask := prefixMask(ones)
+	return x.And(mask) == y.And(mask)
+}
+
+// commonPrefixLen returns the number of leading bits x and y have in common
+func commonPrefixLen(x, y Uint128) int {
+	if x.Hi != y.Hi {
+		return bits.LeadingZeros64(x.Hi ^ y.Hi)
+	}
+	return 64 + bits.LeadingZeros64(x.Lo^y.Lo)
+}
@@ -0,0 +1,75 @@
+package ipx
+
+import (
+	"math/big"
+	"testing"
+)
+
+var uint128AddTests = []struct {
+	x        Uint128
+	y        Uint128
+	sum      Uint128
+	overflow bool
+}{
+	{Uint128{0, 1}, Uint128{0, 2}, Uint128{0, 3}, false},
+	{Uint128{0, ^uint64(0)}, Uint128{0, 1}, Uint128{1, 0}, false},
+	{uint128Max, Uint128{0, 1}, Uint128{0, 0}, true},
+	{uint128Max, uint128Max, Uint128{^uint64(0), ^uint64(0) - 1}, true},
+}
+
+func TestUint128AddSub(t *testing.T) {
+	for _, tt := range uint128AddTests {
+		sum, overflow := tt.x.Add(tt.y)
+		if sum != tt.sum || overflow != tt.overflow {
+			t.Errorf("Uint128.Add(%v)(%v) = %v, %v, want %v, %v", tt.x, tt.y, sum, overflow, tt.sum, tt.overflow)
+		}
+		diff, underflow := tt.sum.Sub(tt.y)
+		if diff != tt.x || underflow != tt.overflow {
+			t.Errorf("Uint128.Sub(%v)(%v) = %v, %v, want %v, %v", tt.sum, tt.y, diff, underflow, tt.x, tt.overflow)
+		}
+	}
+}
+
+var uint128ShiftTests = []struct {
+	in  Uint128
+	n   uint
+	lsh Uint128
+	rsh Uint128
+}{
+	{Uint128{0, 1}, 0, Uint128{0, 1}, Uint128{0, 1}},
+	{Uint128{0, 1}, 64, Uint128{1, 0}, Uint128{0, 0}},
+	{Uint128{1, 1}, 1, Uint128{2, 2}, Uint128{0, 1 << 63}},
+	{Uint128{1, 0}, 127, Uint128{0, 0}, Uint128{0, 0}},
+	{uint128Max, 128, Uint128{0, 0}, Uint128{0, 0}},
+}
+
+func TestUint128Shift(t *testing.T) {
+	for _, tt := range uint128ShiftTests {
+		if out := tt.in.Lsh(tt.n); out != tt.lsh {
+			t.Errorf("Uint128.Lsh(%v)(%v) = %v, want %v", tt.in, tt.n, out, tt.lsh)
+		}
+		if out := tt.in.Rsh(tt.n); out != tt.rsh {
+			t.Errorf("Uint128.Rsh(%v)(%v) = %v, want %v", tt.in, tt.n, out, tt.rsh)
+		}
+	}
+}
+
+var uint128BigIntTests = []struct {
+	in  string
+	out Uint128
+}{
+	{"0", Uint128{0, 0}},
+	{"18446744073709551616", Uint128{1, 0}},
+	{"340282366920938463463374607431768211455", uint128Max},
+	{"340282366920938463463374607431768211456", Uint128{0, 0}},
+	{"-1", uint128Max},
+}
+
+func TestUint128FromBigInt(t *testing.T) {
+	for _, tt := range uint128BigIntTests {
+		n, _ := new(big.Int).SetString(tt.in, 10)
+		if out := Uint128FromBigInt(n); out != tt.out {
+			t.Errorf("Uint128FromBigInt(%v) = %v, want %v", tt.in, out, tt.out)
+		}
+	}
+}